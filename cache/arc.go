@@ -1,245 +1,330 @@
 package cache
 
-// An ARC is a fixed-size in-memory cache with least-recently-used eviction
-type ARC struct {
+import (
+	"container/list"
+	"fmt"
+)
+
+// An arcPolicy is a Policy that evicts bindings as ARC does (Megiddo and
+// Modha, FAST '03), with every size in bytes. Ghost entries keep the size of
+// the binding they remember, key and value, as in CAR, although the value
+// itself is dropped.
+type arcPolicy struct {
 	p        int // P is the dynamic preference towards t1 or t2
 	capacity int // To hold the capacity of the cache
 
-	t1 *LRU // To hold recent cache entries
-	t2 *LRU // To hold frequent cache entries, referenced at least twice
-	b1 *LRU // To hold ghost entries evicted from the t1 cache
-	b2 *LRU // To hold ghost entries evicted from the t2 cache
+	t1 *lruPolicy // To hold recent cache entries
+	t2 *lruPolicy // To hold frequent cache entries, referenced at least twice
+	b1 *ghostList // To hold ghost entries evicted from the t1 cache
+	b2 *ghostList // To hold ghost entries evicted from the t2 cache
 
-	currentlyUsedCapacity int   // Currently used capacity of the cache
-	stats                 Stats // Hits and misses for the cache
+	last string // Key most recently inserted or accessed, evicted last
 }
 
-// NewArc returns a pointer to a new ARC with a capacity to store limit bytes
-func NewArc(limit int) *ARC {
-	return &ARC{p: 0, capacity: limit, t1: NewLru(limit), t2: NewLru(limit), b1: NewLru(limit), b2: NewLru(limit), currentlyUsedCapacity: 0, stats: Stats{}}
+// newArcPolicy returns an arcPolicy for a cache of limit bytes, holding no keys.
+func newArcPolicy(limit int) *arcPolicy {
+	return &arcPolicy{p: 0, capacity: limit, t1: newLruPolicy(), t2: newLruPolicy(), b1: newGhostList(), b2: newGhostList()}
 }
 
-// MaxStorage returns the maximum number of bytes this  can store
-func (arc *ARC) MaxStorage() int {
-	return arc.capacity
-}
+// OnInsert adds key to t1, or to t2 if it is remembered in a ghost list, in
+// which case p first moves towards the list that would have kept it.
+func (policy *arcPolicy) OnInsert(key string, size int) {
+	policy.last = key
 
-// RemainingStorage returns the number of unused bytes available in this ARC
-func (arc *ARC) RemainingStorage() int {
-	return arc.capacity - arc.currentlyUsedCapacity
-}
+	// If key is part of ghost entries recently-evicted from recently-used list,
+	// adjust dynamic preference towards t1 v t2 in favour of t1, because client's
+	// usage shows preference for recently-used entries
+	if ghostSize, ok := policy.b1.remove(key); ok {
+		policy.p += ghostStep(ghostSize, policy.b2.size, policy.b1.size+ghostSize)
+		if policy.p > policy.capacity {
+			policy.p = policy.capacity
+		}
+		policy.t2.OnInsert(key, size)
+		policy.trim()
+		return
+	}
 
-// Peek returns the value associated with the given key, if it exists.
-// This operation does not counts as a "use" for that key-value pair
-// ok is true if a value was found and false otherwise.
-func (arc *ARC) Peek(key string) (value []byte, ok bool) {
-	currMapping, ok := arc.t1.Peek(key)
-	if ok {
-		return currMapping, ok
-	} else {
-		return arc.t2.Peek(key)
+	// If key is part of ghost entries recently-evicted from frequently-used list,
+	// adjust dynamic preference towards t1 v t2 in favour of t2, because client's
+	// usage shows preference for frequently-used entries
+	if ghostSize, ok := policy.b2.remove(key); ok {
+		policy.p -= ghostStep(ghostSize, policy.b1.size, policy.b2.size+ghostSize)
+		if policy.p < 0 {
+			policy.p = 0
+		}
+		policy.t2.OnInsert(key, size)
+		policy.trim()
+		return
 	}
+
+	policy.t1.OnInsert(key, size)
+	policy.trim()
 }
 
-// Get returns the value associated with the given key, if it exists.
-// This operation counts as a "use" for that key-value pair
-// ok is true if a value was found and false otherwise.
-func (arc *ARC) Get(key string) (value []byte, ok bool) {
-	if val, ok := arc.t1.Get(key); ok {
-		arc.t1.Remove(key)
-		arc.t2.Set(key, val)
-		arc.stats.Hits += 1
-		return val, ok
+// ghostStep returns how far p moves on a hit of a ghost entry of the given
+// size, in a ghost list of size hitList, when the other ghost list has size
+// otherList: the entry's size, scaled by otherList / hitList if the other list
+// is larger, as in CAR. Sizes are in bytes, so a hit moves p by at least one
+// entry, or by one byte if the entry is empty.
+func ghostStep(size int, otherList int, hitList int) int {
+	if size < 1 {
+		size = 1
+	}
+	if hitList <= 0 || otherList <= hitList {
+		return size
 	}
+	return size * otherList / hitList
+}
 
-	val, ok := arc.t2.Get(key)
-	if ok {
-		arc.stats.Hits += 1
+// OnAccess promotes key from t1 to t2, or makes it the most recently used
+// binding of t2.
+func (policy *arcPolicy) OnAccess(key string, size int) {
+	policy.last = key
+	if _, ok := policy.t1.nodes[key]; ok {
+		policy.t1.OnRemove(key, ReasonRemoved)
+		policy.t2.OnInsert(key, size)
 	} else {
-		arc.stats.Misses += 1
+		policy.t2.OnAccess(key, size)
 	}
+	policy.trim()
+}
 
-	if _, ok := arc.b1.Peek(key); ok {
-		arc.stats.B1Hits += 1
+// OnRemove forgets key, remembering it with its size in the ghost list of its
+// list if it was evicted.
+func (policy *arcPolicy) OnRemove(key string, reason RemovalReason) {
+	ghosts, elem := policy.b1, policy.t1.nodes[key]
+	if elem == nil {
+		ghosts, elem = policy.b2, policy.t2.nodes[key]
 	}
-
-	if _, ok := arc.b2.Peek(key); ok {
-		arc.stats.B2Hits += 1
+	if elem == nil {
+		return
+	}
+	policy.t1.OnRemove(key, reason)
+	policy.t2.OnRemove(key, reason)
+	if reason != ReasonEvicted {
+		return
 	}
 
-	return val, ok
+	ghosts.push(key, elem.Value.(lruEntry).size)
+	policy.trim()
 }
 
-// Remove removes and returns the value associated with the given key, if it exists.
-// ok is true if a value was found and false otherwise
-func (arc *ARC) Remove(key string) (value []byte, ok bool) {
-	if val, ok := arc.t1.Remove(key); ok {
-		arc.currentlyUsedCapacity -= len(key) + len(val)
-		return val, ok
-	}
+// Victim returns the key ARC replaces next.
+func (policy *arcPolicy) Victim() (key string, ok bool) {
+	return policy.replace(policy.last)
+}
 
-	if val, ok := arc.t2.Remove(key); ok {
-		arc.currentlyUsedCapacity -= len(key) + len(val)
-		return val, ok
+// replace returns the key ARC replaces to make room for a binding for skip:
+// the least recently used binding of t1 if t1 holds more than p bytes, and of
+// t2 otherwise. skip is only returned if it is the only key held.
+func (policy *arcPolicy) replace(skip string) (key string, ok bool) {
+	first, second := policy.t2, policy.t1
+	if policy.t1.Len() > 0 && policy.t1.size > policy.p {
+		first, second = policy.t1, policy.t2
 	}
 
-	if val, ok := arc.b1.Remove(key); ok {
-		return val, ok
+	// skip is only the victim of its list if it is alone there
+	if key, ok := first.Victim(); ok && key != skip {
+		return key, true
+	}
+	if key, ok := second.Victim(); ok {
+		return key, true
 	}
+	return first.Victim()
+}
 
-	if val, ok := arc.b2.Remove(key); ok {
-		return val, ok
+// trim forgets the oldest ghost entries until t1 and b1 together hold at most
+// the capacity, and all four lists at most twice the capacity.
+func (policy *arcPolicy) trim() {
+	for policy.b1.len() > 0 && policy.t1.size+policy.b1.size > policy.capacity {
+		policy.b1.popOldest()
 	}
+	for policy.b2.len() > 0 && policy.t1.size+policy.t2.size+policy.b1.size+policy.b2.size > 2*policy.capacity {
+		policy.b2.popOldest()
+	}
+}
 
-	return nil, false
+// An ARC is a fixed-size in-memory cache with adaptive replacement: a Store
+// whose bindings are evicted by an arcPolicy. A binding demoted from t1 or t2
+// into a ghost list is reported to the eviction callback as evicted, since
+// its value is dropped. Ghost entries carry no value and are never reported.
+type ARC struct {
+	*Store            // Bindings, sizes and stats
+	policy *arcPolicy // Lists t1, t2, b1 and b2, and p
+}
 
+// NewArc returns a pointer to a new ARC with a capacity to store limit bytes
+func NewArc(limit int) *ARC {
+	policy := newArcPolicy(limit)
+	return &ARC{Store: NewStore(limit, policy), policy: policy}
 }
 
-func (arc *ARC) updateCapacity() {
-	arc.currentlyUsedCapacity = arc.t1.currentlyUsedCapacity + arc.t2.currentlyUsedCapacity
+func init() {
+	Register("arc", func(limit int) Cache { return NewArc(limit) })
 }
 
-// Set associates the given value with the given key, possibly evicting values
-// to make room. Returns true if the binding was added successfully, else false.
-func (arc *ARC) Set(key string, value []byte) bool {
-	currObjectSize := len(key) + len(value)
-	if currObjectSize > arc.capacity {
-		return false
+// Get returns the value associated with the given key, if it exists.
+// This operation counts as a "use" for that key-value pair
+// ok is true if a value was found and false otherwise.
+// Expired bindings count as misses and are removed. A miss on a key
+// remembered in b1 or b2 counts as a B1Hit or B2Hit.
+func (arc *ARC) Get(key string) (value []byte, ok bool) {
+	value, ok = arc.Store.Get(key)
+	if ok {
+		return value, true
 	}
 
-	// If the key is in recently-used cache t1, then promote it to t2
-	if _, ok := arc.t1.Peek(key); ok {
-		arc.t1.Remove(key)
-		arc.t2.Set(key, value)
-		arc.updateCapacity()
-		return true
+	if arc.policy.b1.contains(key) {
+		arc.stats.B1Hits += 1
 	}
-
-	// If key is already in frequently-used cache t2, then update its corresponding value
-	if _, ok := arc.t2.Peek(key); ok {
-		arc.t2.Set(key, value)
-		arc.updateCapacity()
-		return true
+	if arc.policy.b2.contains(key) {
+		arc.stats.B2Hits += 1
 	}
+	return nil, false
+}
 
-	// If key is part of ghost entries recently-evicted from recently-used list,
-	// adjust dynamic preference towards t1 v t2 in favour of t1, because client's
-	// usage shows preference for recently-used entries
-	if _, ok := arc.b1.Peek(key); ok {
-		change := 1
-		if arc.b2.currentlyUsedCapacity > arc.b1.currentlyUsedCapacity {
-			change = (arc.b2.currentlyUsedCapacity) / (arc.b1.currentlyUsedCapacity)
-		}
-		updated_p := arc.p + change
-		if updated_p > (arc.capacity) {
-			arc.p = arc.capacity
-		} else {
-			arc.p = updated_p
-		}
-		for arc.capacity < arc.currentlyUsedCapacity+currObjectSize {
-			arc.Replace(key)
-		}
+// Remove removes and returns the value associated with the given key, if it
+// exists. A key remembered in a ghost list is forgotten, but is not a binding.
+// ok is true if a value was found and false otherwise
+func (arc *ARC) Remove(key string) (value []byte, ok bool) {
+	arc.policy.b1.remove(key)
+	arc.policy.b2.remove(key)
+	return arc.Store.Remove(key)
+}
 
-		// move key-value pair from b1 into t2
-		arc.b1.Remove(key)
-		arc.t2.Set(key, value)
-		arc.updateCapacity()
+// Implements the ARC replacement policy which decides whether to favor eviction
+// from t1 or t2, demoting the binding into b1 or b2 to make room for key.
+func (arc *ARC) Replace(key string) {
+	if victim, ok := arc.policy.replace(key); ok {
+		currMapping, _ := arc.unlink(victim, ReasonEvicted)
+		arc.notify(currMapping, ReasonEvicted)
 	}
+}
 
-	// If key is part of ghost entries recently-evicted from frequently-used list,
-	// adjust dynamic preference towards t1 v t2 in favour of t2, because client's
-	// usage shows preference for frequently-used entries
-	if _, ok := arc.b2.Peek(key); ok {
-		// fmt.Println("I AM SETTING: D")
-		change := 1
-		if arc.b2.currentlyUsedCapacity > arc.b1.currentlyUsedCapacity {
-			change = (arc.b2.currentlyUsedCapacity) / (arc.b1.currentlyUsedCapacity)
-		}
-		updated_p := arc.p - change
-		if updated_p < 0 {
-			arc.p = 0
-		} else {
-			arc.p = updated_p
-		}
-		for arc.capacity < arc.currentlyUsedCapacity+currObjectSize {
-			arc.Replace(key)
-		}
+// Empties the ARC cache instance.
+func (arc *ARC) Empty() {
+	arc.Store.Empty()
+	arc.policy.b1.reset()
+	arc.policy.b2.reset()
+}
 
-		// move key-value pair from b2 into t2
-		arc.b2.Remove(key)
-		arc.t2.Set(key, value)
-		arc.updateCapacity()
+// CheckInvariants returns an error describing the first way in which the ARC
+// breaks the bounds of the paper, with every size in bytes and c the capacity:
+// 0 <= p <= c, |T1|+|T2| <= c, |T1|+|B1| <= c and |T1|+|T2|+|B1|+|B2| <= 2c.
+// It also checks that no key is in more than one list, that T1 and T2 hold
+// exactly the bindings of the cache, and that every list's size is the sum of
+// the sizes of its entries, where a ghost entry in B1 or B2 counts the key and
+// value of the binding it remembers. It returns nil if the ARC is consistent.
+func (arc *ARC) CheckInvariants() error {
+	policy := arc.policy
+	t1, t2, b1, b2 := policy.t1.size, policy.t2.size, policy.b1.size, policy.b2.size
+
+	switch {
+	case policy.p < 0 || policy.p > policy.capacity:
+		return fmt.Errorf("cache: arc: p = %d is outside [0, %d]", policy.p, policy.capacity)
+	case t1+t2 > policy.capacity:
+		return fmt.Errorf("cache: arc: |T1|+|T2| = %d exceeds c = %d", t1+t2, policy.capacity)
+	case t1+b1 > policy.capacity:
+		return fmt.Errorf("cache: arc: |T1|+|B1| = %d exceeds c = %d", t1+b1, policy.capacity)
+	case t1+t2+b1+b2 > 2*policy.capacity:
+		return fmt.Errorf("cache: arc: |T1|+|T2|+|B1|+|B2| = %d exceeds 2c = %d", t1+t2+b1+b2, 2*policy.capacity)
+	case t1+t2 != arc.currentlyUsedCapacity:
+		return fmt.Errorf("cache: arc: |T1|+|T2| = %d but the bindings use %d bytes", t1+t2, arc.currentlyUsedCapacity)
 	}
 
-	// if not in any of the four
-	// if b1.Len() + t1.Len() = arc.capacity, then if b1 is not empty,
-	// delete from b1 & move key-value pair from t1 to b1
+	lists := []struct {
+		name    string
+		entries []ARCEntry
+		size    int
+	}{
+		{"T1", lruEntries(&policy.t1.order), t1},
+		{"T2", lruEntries(&policy.t2.order), t2},
+		{"B1", ghostEntries(&policy.b1.order), b1},
+		{"B2", ghostEntries(&policy.b2.order), b2},
+	}
+	seen := make(map[string]string)
+	for i, l := range lists {
+		size := 0
+		for _, entry := range l.entries {
+			if other, ok := seen[entry.Key]; ok {
+				return fmt.Errorf("cache: arc: key %q is in both %s and %s", entry.Key, other, l.name)
+			}
+			seen[entry.Key] = l.name
+			size += entry.Size
 
-	if arc.t1.currentlyUsedCapacity+arc.b1.currentlyUsedCapacity == arc.capacity {
-		if arc.t1.currentlyUsedCapacity < arc.capacity {
-			arc.b1.Evict()
-			for arc.capacity < arc.currentlyUsedCapacity+currObjectSize {
-				arc.Replace(key)
+			// Only T1 and T2 hold bindings
+			if i >= 2 {
+				continue
 			}
-		} else {
-			arc.t1.Evict()
-		}
-	}
-	if arc.t1.currentlyUsedCapacity+arc.b1.currentlyUsedCapacity < arc.capacity {
-		total_cap := arc.t1.currentlyUsedCapacity + arc.t2.currentlyUsedCapacity + arc.b1.currentlyUsedCapacity + arc.b2.currentlyUsedCapacity
-		if total_cap >= arc.capacity {
-			if total_cap == 2*arc.capacity {
-				arc.b2.Evict()
-				for arc.capacity < arc.currentlyUsedCapacity+currObjectSize {
-					arc.Replace(key)
-				}
+			currMapping, ok := arc.cachedValues[entry.Key]
+			if !ok {
+				return fmt.Errorf("cache: arc: key %q is in %s but has no binding", entry.Key, l.name)
+			}
+			if bindingSize := len(currMapping.key) + len(currMapping.value); bindingSize != entry.Size {
+				return fmt.Errorf("cache: arc: key %q has size %d in %s but its binding has %d bytes", entry.Key, entry.Size, l.name, bindingSize)
 			}
 		}
+		if size != l.size {
+			return fmt.Errorf("cache: arc: |%s| = %d but its entries total %d bytes", l.name, l.size, size)
+		}
+	}
+	if keys := policy.t1.Len() + policy.t2.Len(); keys != arc.Len() {
+		return fmt.Errorf("cache: arc: T1 and T2 hold %d keys but there are %d bindings", keys, arc.Len())
 	}
+	return nil
+}
 
-	arc.t1.Set(key, value)
-	arc.updateCapacity()
-	return true
+// An ARCEntry is a key in one of an ARC's lists, with its size in bytes.
+type ARCEntry struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
 }
 
-// Implements the ARC replacement policy which decides whether to favor eviction
-// from t1 or t2.
-func (arc *ARC) Replace(key string) {
-	_, key_in_b2 := arc.b1.Peek(key)
-	t1_length := arc.t1.currentlyUsedCapacity
-	if (t1_length > 0) && (t1_length > arc.p || (t1_length == arc.p && key_in_b2)) {
-		key, ok := arc.t1.Evict()
-		emptyVal := make([]byte, 0)
-		if ok {
-			arc.b1.Set(key, emptyVal)
-		}
-	} else {
-		key, ok := arc.t2.Evict()
-		emptyVal := make([]byte, 0)
-		if ok {
-			arc.b2.Set(key, emptyVal)
-		}
-	}
-	arc.updateCapacity()
+// An ARCState is a copy of the lists of an ARC and of p, for bug reports.
+// It can be marshaled to JSON. Every list runs from least to most recently
+// used, or remembered, and every size is in bytes.
+type ARCState struct {
+	Capacity int        `json:"capacity"` // To hold the capacity of the cache
+	P        int        `json:"p"`        // Target size of T1
+	T1       []ARCEntry `json:"t1"`       // Recent cache entries
+	T2       []ARCEntry `json:"t2"`       // Frequent cache entries
+	B1       []ARCEntry `json:"b1"`       // Ghost entries evicted from T1
+	B2       []ARCEntry `json:"b2"`       // Ghost entries evicted from T2
 }
 
-// Empties the ARC cache instance.
-func (arc *ARC) Empty() {
-	arc.t1.Empty()
-	arc.t2.Empty()
-	arc.b1.Empty()
-	arc.b2.Empty()
-	arc.updateCapacity()
+// DebugState returns a copy of the lists of the ARC and of p, which later
+// operations do not modify.
+func (arc *ARC) DebugState() ARCState {
+	return ARCState{
+		Capacity: arc.policy.capacity,
+		P:        arc.policy.p,
+		T1:       lruEntries(&arc.policy.t1.order),
+		T2:       lruEntries(&arc.policy.t2.order),
+		B1:       ghostEntries(&arc.policy.b1.order),
+		B2:       ghostEntries(&arc.policy.b2.order),
+	}
 }
 
-// Len returns the number of bindings in the ARC.
-func (arc *ARC) Len() int {
-	return arc.t1.Len() + arc.t2.Len()
+// lruEntries returns the entries of an lruPolicy's order, from least to most
+// recently used.
+func lruEntries(order *list.List) []ARCEntry {
+	entries := make([]ARCEntry, 0, order.Len())
+	for elem := order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(lruEntry)
+		entries = append(entries, ARCEntry{Key: entry.key, Size: entry.size})
+	}
+	return entries
 }
 
-// Stats returns statistics about how many search hits and misses have occurred.
-func (arc *ARC) Stats() *Stats {
-	return &arc.stats
+// ghostEntries returns the entries of a ghostList's order, from least to most
+// recently remembered.
+func ghostEntries(order *list.List) []ARCEntry {
+	entries := make([]ARCEntry, 0, order.Len())
+	for elem := order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(ghostEntry)
+		entries = append(entries, ARCEntry{Key: entry.key, Size: entry.size})
+	}
+	return entries
 }
 
 /*
@@ -1,10 +1,27 @@
 package cache
 
+import (
+	"sync/atomic"
+	"time"
+)
+
 type Stats struct {
 	Hits   int
 	B1Hits int
 	B2Hits int
 	Misses int
+
+	Sets         int // Bindings added or overwritten by Set
+	RejectedSets int // Calls to Set refused because the binding could never fit
+	Evictions    int // Bindings evicted to make room for others
+	Removals     int // Bindings removed by a call to Remove
+
+	HitBytes  int // Bytes of values returned by Get hits
+	MissBytes int // Bytes of values Set for keys that were not in the cache, rejected or not
+
+	LoadSuccesses int           // Loader calls that returned a value
+	LoadFailures  int           // Loader calls that returned an error
+	TotalLoadTime time.Duration // Time spent in loader calls, successful or not
 }
 
 func (stats *Stats) Equals(other *Stats) bool {
@@ -14,7 +31,51 @@ func (stats *Stats) Equals(other *Stats) bool {
 	if stats == nil || other == nil {
 		return false
 	}
-	return stats.Hits == other.Hits && stats.Misses == other.Misses
+	return *stats == *other
+}
+
+// Snapshot returns a copy of stats that later cache operations do not modify.
+func (stats *Stats) Snapshot() Stats {
+	return *stats
+}
+
+// Delta returns the change in every counter since prev, an earlier snapshot
+// of the same stats. Dividing the result by the time between the snapshots
+// gives rates.
+func (stats *Stats) Delta(prev Stats) Stats {
+	return Stats{
+		Hits:          stats.Hits - prev.Hits,
+		B1Hits:        stats.B1Hits - prev.B1Hits,
+		B2Hits:        stats.B2Hits - prev.B2Hits,
+		Misses:        stats.Misses - prev.Misses,
+		Sets:          stats.Sets - prev.Sets,
+		RejectedSets:  stats.RejectedSets - prev.RejectedSets,
+		Evictions:     stats.Evictions - prev.Evictions,
+		Removals:      stats.Removals - prev.Removals,
+		HitBytes:      stats.HitBytes - prev.HitBytes,
+		MissBytes:     stats.MissBytes - prev.MissBytes,
+		LoadSuccesses: stats.LoadSuccesses - prev.LoadSuccesses,
+		LoadFailures:  stats.LoadFailures - prev.LoadFailures,
+		TotalLoadTime: stats.TotalLoadTime - prev.TotalLoadTime,
+	}
+}
+
+// HitRatio returns the fraction of Gets that were hits, or 0 if there were none.
+func (stats *Stats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// ByteHitRatio returns the fraction of requested bytes that were served from
+// the cache, counting the bytes Set after a miss as the bytes that were
+// missed, or 0 if no bytes were requested.
+func (stats *Stats) ByteHitRatio() float64 {
+	if stats.HitBytes+stats.MissBytes == 0 {
+		return 0
+	}
+	return float64(stats.HitBytes) / float64(stats.HitBytes+stats.MissBytes)
 }
 
 type Cache interface {
@@ -51,3 +112,79 @@ type Cache interface {
 	// and misses this cache has resolved over its lifetime.
 	Stats() *Stats
 }
+
+// recordRemoval counts a binding leaving a cache for the given reason.
+func (stats *Stats) recordRemoval(reason RemovalReason) {
+	switch reason {
+	case ReasonEvicted:
+		stats.Evictions += 1
+	case ReasonRemoved:
+		stats.Removals += 1
+	}
+}
+
+// recordRejectedSet counts a call to Set refused because its binding, with the
+// given value, could never fit. Its bytes still count as missed, so that the
+// byte hit ratio does not leave out the objects too large to cache. The miss
+// itself was counted by the Get that preceded the Set.
+func (stats *Stats) recordRejectedSet(value []byte) {
+	stats.RejectedSets += 1
+	stats.MissBytes += len(value)
+}
+
+// add accumulates the counters of other into stats.
+func (stats *Stats) add(other *Stats) {
+	stats.Hits += other.Hits
+	stats.B1Hits += other.B1Hits
+	stats.B2Hits += other.B2Hits
+	stats.Misses += other.Misses
+	stats.Sets += other.Sets
+	stats.RejectedSets += other.RejectedSets
+	stats.Evictions += other.Evictions
+	stats.Removals += other.Removals
+	stats.HitBytes += other.HitBytes
+	stats.MissBytes += other.MissBytes
+	stats.LoadSuccesses += other.LoadSuccesses
+	stats.LoadFailures += other.LoadFailures
+	stats.TotalLoadTime += other.TotalLoadTime
+}
+
+// clampFraction returns fraction limited to [0, 1], the range of shares of a
+// capacity a policy may set aside. NaN is treated as 0.
+func clampFraction(fraction float64) float64 {
+	if !(fraction > 0) {
+		return 0
+	}
+	if fraction > 1 {
+		return 1
+	}
+	return fraction
+}
+
+// lookupCounts counts the hits and misses of a cache whose Get may run
+// concurrently with other Gets, as SIEVE's and S3FIFO's do. Its counters are
+// only accessed atomically.
+type lookupCounts struct {
+	hits     uint64
+	misses   uint64
+	hitBytes uint64
+}
+
+// recordHit counts a Get that found the given value.
+func (counts *lookupCounts) recordHit(value []byte) {
+	atomic.AddUint64(&counts.hits, 1)
+	atomic.AddUint64(&counts.hitBytes, uint64(len(value)))
+}
+
+// recordMiss counts a Get that found nothing.
+func (counts *lookupCounts) recordMiss() {
+	atomic.AddUint64(&counts.misses, 1)
+}
+
+// with returns a copy of stats with the counted lookups added to it.
+func (counts *lookupCounts) with(stats Stats) *Stats {
+	stats.Hits += int(atomic.LoadUint64(&counts.hits))
+	stats.Misses += int(atomic.LoadUint64(&counts.misses))
+	stats.HitBytes += int(atomic.LoadUint64(&counts.hitBytes))
+	return &stats
+}
@@ -0,0 +1,296 @@
+package cache
+
+// A CAR is a fixed-size in-memory cache using Clock with Adaptive Replacement
+// (Bansal and Modha, FAST '04). It adapts between recency and frequency the
+// way ARC does, with a target size p for t1 and ghost lists b1 and b2, but t1
+// and t2 are CLOCKs, so a hit only sets a reference bit instead of moving the
+// binding between lists.
+//
+// A binding enters t1. When room is needed, the hand of t1 or t2 is swept as
+// chosen by p: a referenced binding in t1 moves to t2, a referenced binding
+// in t2 gets a second chance, and an unreferenced binding is evicted into the
+// matching ghost list. Setting a key remembered in b1 grows p and setting one
+// remembered in b2 shrinks it; either way the binding goes straight into t2.
+// All sizes, including p, are in bytes.
+type CAR struct {
+	p        int // P is the target size of t1, adapted by ghost hits
+	capacity int // To hold the capacity of the cache
+
+	t1 *CLOCK     // To hold recent cache entries
+	t2 *CLOCK     // To hold frequent cache entries, referenced at least twice
+	b1 *ghostList // To hold ghost entries evicted from t1
+	b2 *ghostList // To hold ghost entries evicted from t2
+
+	stats   Stats            // Hits and misses for the cache
+	onEvict EvictionCallback // Called whenever a binding leaves the cache
+}
+
+// NewCar returns a pointer to a new CAR with a capacity to store limit bytes
+func NewCar(limit int) *CAR {
+	return &CAR{p: 0, capacity: limit, t1: NewClock(limit), t2: NewClock(limit), b1: newGhostList(), b2: newGhostList(), stats: Stats{}}
+}
+
+func init() {
+	Register("car", func(limit int) Cache { return NewCar(limit) })
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the CAR,
+// replacing any previously registered callback. A binding evicted into a
+// ghost list is reported as evicted, since its value is dropped.
+func (car *CAR) OnEvict(fn EvictionCallback) {
+	car.onEvict = fn
+}
+
+// notify records in the stats that a binding left the CAR, and reports it to
+// the eviction callback, if any.
+func (car *CAR) notify(entry *clockEntry, reason RemovalReason) {
+	car.stats.recordRemoval(reason)
+	if car.onEvict != nil {
+		car.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this CAR can store
+func (car *CAR) MaxStorage() int {
+	return car.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this CAR
+func (car *CAR) RemainingStorage() int {
+	return car.capacity - car.t1.currentlyUsedCapacity - car.t2.currentlyUsedCapacity
+}
+
+// lookup returns the entry for key and the CLOCK holding it, if it is resident.
+func (car *CAR) lookup(key string) (*clockEntry, *CLOCK) {
+	if elem, ok := car.t1.cachedValues[key]; ok {
+		return elem.Value.(*clockEntry), car.t1
+	}
+	if elem, ok := car.t2.cachedValues[key]; ok {
+		return elem.Value.(*clockEntry), car.t2
+	}
+	return nil, nil
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not set the reference bit or update the stats.
+// ok is true if a value was found and false otherwise.
+func (car *CAR) Peek(key string) (value []byte, ok bool) {
+	entry, _ := car.lookup(key)
+	if entry == nil {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and sets
+// its reference bit. A miss on a key remembered in b1 or b2 is counted in
+// B1Hits or B2Hits.
+// ok is true if a value was found and false otherwise.
+func (car *CAR) Get(key string) (value []byte, ok bool) {
+	entry, _ := car.lookup(key)
+	if entry == nil {
+		car.stats.Misses += 1
+		if car.b1.contains(key) {
+			car.stats.B1Hits += 1
+		}
+		if car.b2.contains(key) {
+			car.stats.B2Hits += 1
+		}
+		return nil, false
+	}
+
+	entry.referenced = true
+	car.stats.Hits += 1
+	car.stats.HitBytes += len(entry.value)
+	return entry.value, true
+}
+
+// Remove removes and returns the value associated with the given key, if it
+// exists. A key remembered in a ghost list is forgotten, but is not a binding.
+// ok is true if a value was found and false otherwise
+func (car *CAR) Remove(key string) (value []byte, ok bool) {
+	car.b1.remove(key)
+	car.b2.remove(key)
+
+	entry, clock := car.lookup(key)
+	if entry == nil {
+		return nil, false
+	}
+	clock.unlink(clock.cachedValues[key])
+	car.notify(entry, ReasonRemoved)
+	return entry.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. Overwriting a binding sets its reference bit and never evicts
+// it. Returns true if the binding was added successfully, else false.
+func (car *CAR) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > car.capacity {
+		car.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if entry, clock := car.lookup(key); entry != nil {
+		replaced := *entry
+		entry.referenced = true
+
+		growth := len(value) - len(entry.value)
+		for car.RemainingStorage() < growth {
+			car.replace(entry)
+		}
+
+		// replace may have moved the entry from t1 to t2
+		_, clock = car.lookup(key)
+		entry.value = value
+		clock.currentlyUsedCapacity += growth
+		car.stats.Sets += 1
+		car.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	for car.RemainingStorage() < currentObjectSize {
+		car.replace(nil)
+	}
+
+	switch {
+	case car.b1.contains(key):
+		// Recency is paying off: grow t1's target
+		size, _ := car.b1.remove(key)
+		car.p += car.adaptation(size, car.b2.size, car.b1.size+size)
+		if car.p > car.capacity {
+			car.p = car.capacity
+		}
+		car.t2.pushTail(&clockEntry{key: key, value: value})
+
+	case car.b2.contains(key):
+		// Frequency is paying off: shrink t1's target
+		size, _ := car.b2.remove(key)
+		car.p -= car.adaptation(size, car.b1.size, car.b2.size+size)
+		if car.p < 0 {
+			car.p = 0
+		}
+		car.t2.pushTail(&clockEntry{key: key, value: value})
+
+	default:
+		// Keep t1 and b1 within the capacity, and the whole directory
+		// within twice the capacity
+		for car.t1.currentlyUsedCapacity+car.b1.size+currentObjectSize > car.capacity && car.b1.len() > 0 {
+			car.b1.popOldest()
+		}
+		for car.directorySize()+currentObjectSize > 2*car.capacity && car.b2.len() > 0 {
+			car.b2.popOldest()
+		}
+		car.t1.pushTail(&clockEntry{key: key, value: value})
+	}
+
+	car.stats.Sets += 1
+	car.stats.MissBytes += len(value)
+	return true
+}
+
+// adaptation returns how far to move p on a ghost hit of the given size, in
+// the list whose size is hitList, when the other ghost list has size
+// otherList. As in ARC, the move is larger when the hit list is the smaller.
+func (car *CAR) adaptation(size int, otherList int, hitList int) int {
+	if size < 1 {
+		size = 1
+	}
+	if hitList <= 0 || otherList <= hitList {
+		return size
+	}
+	return size * otherList / hitList
+}
+
+// directorySize returns the size of every binding resident in or remembered
+// by the CAR.
+func (car *CAR) directorySize() int {
+	return car.t1.currentlyUsedCapacity + car.t2.currentlyUsedCapacity + car.b1.size + car.b2.size
+}
+
+// Empty removes every binding from the CAR and forgets its ghost lists.
+func (car *CAR) Empty() {
+	if car.onEvict != nil {
+		for _, clock := range []*CLOCK{car.t1, car.t2} {
+			for _, elem := range clock.cachedValues {
+				car.notify(elem.Value.(*clockEntry), ReasonEmptied)
+			}
+		}
+	}
+	car.t1.Empty()
+	car.t2.Empty()
+	car.b1.reset()
+	car.b2.reset()
+	car.p = 0
+}
+
+// Evict sweeps the CAR's hands until one binding has been evicted into a
+// ghost list, and returns its key.
+// ok is false if the CAR was already empty.
+func (car *CAR) Evict() (key string, ok bool) {
+	if car.Len() == 0 {
+		return "", false
+	}
+	return car.replace(nil).key, true
+}
+
+// replace sweeps the hand of t1 if t1 is larger than its target p, or of t2
+// otherwise, until it evicts an unreferenced binding other than skip into the
+// matching ghost list, and returns that binding. There must be such a binding.
+func (car *CAR) replace(skip *clockEntry) *clockEntry {
+	for {
+		if car.sweepT1(skip) {
+			elem := car.t1.hand
+			entry := elem.Value.(*clockEntry)
+			car.t1.unlink(elem)
+			if entry.referenced || entry == skip {
+				entry.referenced = false
+				car.t2.pushTail(entry)
+				continue
+			}
+			car.b1.push(entry.key, len(entry.key)+len(entry.value))
+			car.notify(entry, ReasonEvicted)
+			return entry
+		}
+
+		elem := car.t2.hand
+		entry := elem.Value.(*clockEntry)
+		if entry.referenced || entry == skip {
+			entry.referenced = false
+			car.t2.hand = clockNext(&car.t2.cachedList, elem)
+			continue
+		}
+		car.t2.unlink(elem)
+		car.b2.push(entry.key, len(entry.key)+len(entry.value))
+		car.notify(entry, ReasonEvicted)
+		return entry
+	}
+}
+
+// sweepT1 reports whether replace should sweep t1 rather than t2.
+func (car *CAR) sweepT1(skip *clockEntry) bool {
+	if car.t1.Len() == 0 {
+		return false
+	}
+	target := car.p
+	if target < 1 {
+		target = 1
+	}
+	if car.t1.currentlyUsedCapacity >= target {
+		return true
+	}
+
+	// t2 may hold nothing that can be evicted
+	return car.t2.Len() == 0 || (car.t2.Len() == 1 && car.t2.hand.Value.(*clockEntry) == skip)
+}
+
+// Len returns the number of bindings in the CAR.
+func (car *CAR) Len() int {
+	return car.t1.Len() + car.t2.Len()
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (car *CAR) Stats() *Stats {
+	return &car.stats
+}
@@ -0,0 +1,154 @@
+/******************************************************************************
+ * car_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for car.go. The Cache contract CAR shares with every
+ *    policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"math"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/trace"
+	"cos316.princeton.edu/assignment3/workload"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a referenced binding in t1 moves to t2 when the hand reaches it,
+// and that the first unreferenced one is evicted into b1
+func TestReplaceCar(t *testing.T) {
+	car := NewCar(30)
+	car.Set("____0", []byte("____0"))
+	car.Set("____1", []byte("____1"))
+	car.Set("____2", []byte("____2"))
+	car.Get("____0")
+
+	car.Set("____3", []byte("____3"))
+	if _, ok := car.t2.Peek("____0"); !ok {
+		t.Errorf("Referenced binding did not move to t2. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if !car.b1.contains("____1") || car.Len() != 3 {
+		t.Errorf("Unreferenced binding not evicted into b1. Got %v, Expected %v", car.b1.contains("____1"), true)
+		t.FailNow()
+	}
+	if car.t1.Len() != 2 || car.t2.Len() != 1 || car.RemainingStorage() != 0 {
+		t.Errorf("Wrong list sizes. Got t1 %v and t2 %v, Expected %v and %v", car.t1.Len(), car.t2.Len(), 2, 1)
+		t.FailNow()
+	}
+}
+
+// Check that ghost hits are counted like ARC's and move p in opposite
+// directions, with the binding coming back in t2
+func TestGhostHitsCar(t *testing.T) {
+	car := NewCar(30)
+	car.Set("____0", []byte("____0"))
+	car.Set("____1", []byte("____1"))
+	car.Set("____2", []byte("____2"))
+	car.Get("____0")
+	car.Set("____3", []byte("____3"))
+
+	// ____1 is in b1
+	car.Get("____1")
+	if car.stats.B1Hits != 1 || car.stats.B2Hits != 0 {
+		t.Errorf("Ghost hit counted wrong. Got %v B1Hits and %v B2Hits, Expected %v and %v", car.stats.B1Hits, car.stats.B2Hits, 1, 0)
+		t.FailNow()
+	}
+	car.Set("____1", []byte("____1"))
+	if car.p != 10 {
+		t.Errorf("b1 hit did not grow p. Got %v, Expected %v", car.p, 10)
+		t.FailNow()
+	}
+	if _, ok := car.t2.Peek("____1"); !ok || car.b1.contains("____1") {
+		t.Errorf("b1 hit did not move the binding into t2. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+
+	// With t1 under its target, the hand of t2 evicts ____0 into b2
+	car.p = 30
+	car.Set("____4", []byte("____4"))
+	if !car.b2.contains("____0") {
+		t.Errorf("t2 binding not evicted into b2. Got %v, Expected %v", car.b2.contains("____0"), true)
+		t.FailNow()
+	}
+	car.Get("____0")
+	if car.stats.B2Hits != 1 {
+		t.Errorf("b2 hit counted wrong. Got %v, Expected %v", car.stats.B2Hits, 1)
+		t.FailNow()
+	}
+	car.Set("____0", []byte("____0"))
+	if car.p >= 30 {
+		t.Errorf("b2 hit did not shrink p. Got %v, Expected less than %v", car.p, 30)
+		t.FailNow()
+	}
+	if _, ok := car.t2.Peek("____0"); !ok {
+		t.Errorf("b2 hit did not move the binding into t2. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+
+	// Removing a ghost forgets it without reporting a binding
+	car.Set("____5", []byte("____5"))
+	for _, key := range []string{"____1", "____2", "____3", "____4"} {
+		if car.b1.contains(key) || car.b2.contains(key) {
+			if _, ok := car.Remove(key); ok || car.b1.contains(key) || car.b2.contains(key) {
+				t.Errorf("Remove of ghost %s wrong. Got %v, Expected %v", key, ok, false)
+				t.FailNow()
+			}
+			return
+		}
+	}
+	t.Errorf("No ghost entry left to remove")
+	t.FailNow()
+}
+
+// Check the directory bounds on every step of a skewed workload: t1 and b1
+// together fit in the capacity, and everything together in twice that
+func TestDirectoryCar(t *testing.T) {
+	capacity := 200
+	car := NewCar(capacity)
+	events := workload.New(workload.Config{
+		Seed:         1,
+		Keys:         workload.NewZipf(1, 100, 0.9),
+		Sizes:        workload.NewUniformSize(1, 1, 20),
+		ReadFraction: 0.7,
+	}).Events(20000)
+
+	for i, event := range events {
+		replayEvents(car, []trace.Event{event})
+		if car.t1.currentlyUsedCapacity+car.b1.size > capacity {
+			t.Errorf("t1 and b1 too large at step %d. Got %v, Expected at most %v", i, car.t1.currentlyUsedCapacity+car.b1.size, capacity)
+			t.FailNow()
+		}
+		if car.directorySize() > 2*capacity || car.RemainingStorage() < 0 {
+			t.Errorf("Directory too large at step %d. Got %v, Expected at most %v", i, car.directorySize(), 2*capacity)
+			t.FailNow()
+		}
+		if car.p < 0 || car.p > capacity {
+			t.Errorf("p out of range at step %d. Got %v", i, car.p)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that CAR's hit ratio stays close to ARC's on the same traces
+func TestComparableToArcCar(t *testing.T) {
+	for name, newConfig := range benchmarkWorkloads() {
+		events := workload.New(newConfig()).Events(50000)
+		car, arc := NewCar(benchmarkCapacity), NewArc(benchmarkCapacity)
+		replayEvents(car, events)
+		replayEvents(arc, events)
+
+		carRatio, arcRatio := car.Stats().HitRatio(), arc.Stats().HitRatio()
+		if math.Abs(carRatio-arcRatio) > 0.05 {
+			t.Errorf("%s: CAR hit ratio far from ARC's. Got %v, Expected within 0.05 of %v", name, carRatio, arcRatio)
+			t.FailNow()
+		}
+	}
+}
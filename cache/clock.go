@@ -0,0 +1,20 @@
+package cache
+
+import (
+	"time"
+)
+
+// A Clock tells a cache what time it is, so that expiration can be tested
+// without sleeping
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// systemClock is the default Clock, backed by the wall clock.
+type systemClock struct{}
+
+// Now returns time.Now().
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
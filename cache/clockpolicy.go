@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// A clockEntry is a binding in a CLOCK along with its reference bit
+type clockEntry struct {
+	key        string
+	value      []byte
+	referenced bool // Set on every use, cleared as the hand passes
+}
+
+// A CLOCK is a fixed-size in-memory cache with second-chance eviction. It
+// approximates LRU, but a hit only sets a reference bit rather than moving
+// the binding, which makes hits much cheaper. Bindings sit on a circular list
+// swept by a hand: when room is needed, the hand clears the bit of each
+// referenced binding it passes and evicts the first unreferenced one.
+//
+// The type is called CLOCK rather than Clock, which is the time source used
+// by the TTL support.
+type CLOCK struct {
+	cachedValues          map[string]*list.Element // Map containing key-element pairings
+	cachedList            list.List                // Circular list of bindings, wrapping at the back
+	hand                  *list.Element            // The next binding to examine for eviction
+	capacity              int                      // To hold the capacity of the cache
+	currentlyUsedCapacity int                      // Currently used capacity of the cache
+	stats                 Stats                    // Hits and misses for the cache
+	onEvict               EvictionCallback         // Called whenever a binding leaves the cache
+}
+
+// NewClock returns a pointer to a new CLOCK with a capacity to store limit bytes
+func NewClock(limit int) *CLOCK {
+	return &CLOCK{cachedValues: make(map[string]*list.Element), capacity: limit, currentlyUsedCapacity: 0, stats: Stats{}}
+}
+
+func init() {
+	Register("clock", func(limit int) Cache { return NewClock(limit) })
+}
+
+// clockNext returns the element after elem in l, treating l as circular.
+func clockNext(l *list.List, elem *list.Element) *list.Element {
+	if next := elem.Next(); next != nil {
+		return next
+	}
+	return l.Front()
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the CLOCK,
+// replacing any previously registered callback.
+func (clock *CLOCK) OnEvict(fn EvictionCallback) {
+	clock.onEvict = fn
+}
+
+// notify records in the stats that a binding left the CLOCK, and reports it
+// to the eviction callback, if any.
+func (clock *CLOCK) notify(entry *clockEntry, reason RemovalReason) {
+	clock.stats.recordRemoval(reason)
+	if clock.onEvict != nil {
+		clock.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this CLOCK can store
+func (clock *CLOCK) MaxStorage() int {
+	return clock.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this CLOCK
+func (clock *CLOCK) RemainingStorage() int {
+	return clock.capacity - clock.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not set the reference bit or update the stats.
+// ok is true if a value was found and false otherwise.
+func (clock *CLOCK) Peek(key string) (value []byte, ok bool) {
+	elem, ok := clock.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*clockEntry).value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and sets
+// its reference bit.
+// ok is true if a value was found and false otherwise.
+func (clock *CLOCK) Get(key string) (value []byte, ok bool) {
+	elem, ok := clock.cachedValues[key]
+	if !ok {
+		clock.stats.Misses += 1
+		return nil, false
+	}
+
+	entry := elem.Value.(*clockEntry)
+	entry.referenced = true
+	clock.stats.Hits += 1
+	clock.stats.HitBytes += len(entry.value)
+	return entry.value, true
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (clock *CLOCK) Remove(key string) (value []byte, ok bool) {
+	elem, ok := clock.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := clock.unlink(elem)
+	clock.notify(entry, ReasonRemoved)
+	return entry.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. A new binding is placed just behind the hand, unreferenced;
+// overwriting a binding sets its reference bit and never evicts it.
+// Returns true if the binding was added successfully, else false.
+func (clock *CLOCK) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > clock.capacity {
+		clock.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if elem, ok := clock.cachedValues[key]; ok {
+		entry := elem.Value.(*clockEntry)
+		replaced := *entry
+
+		growth := len(value) - len(entry.value)
+		for clock.RemainingStorage() < growth {
+			clock.evict(elem)
+		}
+
+		entry.value = value
+		entry.referenced = true
+		clock.currentlyUsedCapacity += growth
+		clock.stats.Sets += 1
+		clock.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	for clock.RemainingStorage() < currentObjectSize {
+		if _, ok := clock.Evict(); !ok {
+			return false
+		}
+	}
+
+	clock.pushTail(&clockEntry{key: key, value: value})
+	clock.stats.Sets += 1
+	clock.stats.MissBytes += len(value)
+	return true
+}
+
+// Empty removes every binding from the CLOCK.
+func (clock *CLOCK) Empty() {
+	if clock.onEvict != nil {
+		for _, elem := range clock.cachedValues {
+			clock.notify(elem.Value.(*clockEntry), ReasonEmptied)
+		}
+	}
+	clock.cachedValues = make(map[string]*list.Element)
+	clock.cachedList.Init()
+	clock.hand = nil
+	clock.currentlyUsedCapacity = 0
+}
+
+// Evict sweeps the hand until it finds an unreferenced binding, then removes
+// that binding and returns its key.
+// ok is false if the CLOCK was already empty.
+func (clock *CLOCK) Evict() (key string, ok bool) {
+	if clock.hand == nil {
+		return "", false
+	}
+	return clock.evict(nil).key, true
+}
+
+// evict sweeps the hand past referenced bindings and skip, evicts the first
+// other binding and returns it. There must be such a binding.
+func (clock *CLOCK) evict(skip *list.Element) *clockEntry {
+	for {
+		elem := clock.hand
+		entry := elem.Value.(*clockEntry)
+		if elem == skip || entry.referenced {
+			entry.referenced = false
+			clock.hand = clockNext(&clock.cachedList, elem)
+			continue
+		}
+
+		clock.unlink(elem)
+		clock.notify(entry, ReasonEvicted)
+		return entry
+	}
+}
+
+// pushTail adds entry just behind the hand, where it will be examined last,
+// without making room for it.
+func (clock *CLOCK) pushTail(entry *clockEntry) {
+	if clock.hand == nil {
+		clock.hand = clock.cachedList.PushBack(entry)
+		clock.cachedValues[entry.key] = clock.hand
+	} else {
+		clock.cachedValues[entry.key] = clock.cachedList.InsertBefore(entry, clock.hand)
+	}
+	clock.currentlyUsedCapacity += len(entry.key) + len(entry.value)
+}
+
+// unlink removes elem from the CLOCK, moving the hand past it if needed,
+// without notifying the eviction callback.
+func (clock *CLOCK) unlink(elem *list.Element) *clockEntry {
+	if clock.hand == elem {
+		clock.hand = clockNext(&clock.cachedList, elem)
+		if clock.hand == elem {
+			clock.hand = nil
+		}
+	}
+
+	entry := clock.cachedList.Remove(elem).(*clockEntry)
+	delete(clock.cachedValues, entry.key)
+	clock.currentlyUsedCapacity -= len(entry.key) + len(entry.value)
+	return entry
+}
+
+// Len returns the number of bindings in the CLOCK.
+func (clock *CLOCK) Len() int {
+	return len(clock.cachedValues)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (clock *CLOCK) Stats() *Stats {
+	return &clock.stats
+}
@@ -0,0 +1,76 @@
+/******************************************************************************
+ * clockpolicy_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for clockpolicy.go. The Cache contract CLOCK shares
+ *    with every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"testing"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check the eviction order of a hand-worked example. With the hand on ____0,
+// a hit on ____0 gives it a second chance, so ____1 goes first. ____3 is then
+// inserted just behind the hand, which has moved on to ____2.
+func TestEvictionOrderClock(t *testing.T) {
+	clock := NewClock(30)
+	removals := recordRemovals(clock)
+
+	clock.Set("____0", []byte("____0"))
+	clock.Set("____1", []byte("____1"))
+	clock.Set("____2", []byte("____2"))
+	clock.Get("____0")
+
+	// Hand on ____0: clear its bit, evict ____1, stop on ____2
+	clock.Set("____3", []byte("____3"))
+	// Hand on ____2: evict it, wrap round to ____0
+	clock.Set("____4", []byte("____4"))
+	// Hand on ____0, whose bit is now clear: evict it
+	clock.Set("____5", []byte("____5"))
+
+	checkRemovals(t, clock, *removals, []removal{
+		{"____1", "____1", ReasonEvicted},
+		{"____2", "____2", ReasonEvicted},
+		{"____0", "____0", ReasonEvicted},
+	})
+
+	// The survivors are swept in insertion order behind the hand
+	expected := []string{"____3", "____4", "____5"}
+	for _, key := range expected {
+		if evicted, _ := clock.Evict(); evicted != key {
+			t.Errorf("Evicted wrong binding. Got %v, Expected %v", evicted, key)
+			t.FailNow()
+		}
+	}
+	if _, ok := clock.Evict(); ok || clock.RemainingStorage() != 30 {
+		t.Errorf("Evict on empty CLOCK wrong. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+}
+
+// Check that growing a binding evicts others but never itself, even when the
+// hand is on it
+func TestOverwriteClock(t *testing.T) {
+	clock := NewClock(30)
+	clock.Set("____0", []byte("____0"))
+	clock.Set("____1", []byte("____1"))
+	clock.Set("____2", []byte("____2"))
+
+	clock.Set("____0", []byte("__________0"))
+	if value, ok := clock.Peek("____0"); !ok || !bytesEqual(value, []byte("__________0")) {
+		t.Errorf("Overwrite lost the binding. Got %v, Expected %v", value, []byte("__________0"))
+		t.FailNow()
+	}
+	if _, ok := clock.Peek("____1"); ok || clock.Len() != 2 || clock.RemainingStorage() != 4 {
+		t.Errorf("Growing overwrite evicted wrong bindings. Got %v bindings and %v bytes left, Expected %v and %v", clock.Len(), clock.RemainingStorage(), 2, 4)
+		t.FailNow()
+	}
+}
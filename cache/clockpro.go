@@ -0,0 +1,415 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// A pageStatus classifies a page of a CLOCKPro
+type pageStatus int
+
+const (
+	pageHot  pageStatus = iota // Resident and frequently used
+	pageCold                   // Resident, but not yet shown to be frequently used
+	pageTest                   // No longer resident; only its key and size are kept
+)
+
+// A clockProPage is a binding in a CLOCKPro, or the memory of one
+type clockProPage struct {
+	key        string
+	value      []byte
+	size       int // len(key) + len(value), kept after the value is dropped
+	status     pageStatus
+	inTest     bool // For cold pages: whether the page is in its test period
+	referenced bool // Set on every use, cleared as a hand passes
+}
+
+// A CLOCKPro is a fixed-size in-memory cache using CLOCK-Pro replacement
+// (Jiang, Chen and Zhang, USENIX ATC '05). Like CLOCK, a hit only sets a
+// reference bit. Pages are classified as hot or cold by their reuse
+// distance: a new page is cold and in a test period, and a cold page that is
+// used again during its test period becomes hot. Evicted cold pages are kept
+// as non-resident test pages for the rest of their test period, so that a
+// page that comes back soon after eviction returns hot.
+//
+// All pages share one circular list, swept by three hands. HANDcold evicts
+// cold pages and promotes referenced ones, HANDhot demotes unreferenced hot
+// pages to cold and ends test periods, and HANDtest ends test periods and
+// forgets non-resident pages. The share of the capacity given to cold pages
+// adapts: it grows when a non-resident test page is reused and shrinks when
+// a test period ends without reuse.
+type CLOCKPro struct {
+	cachedValues map[string]*list.Element // Map containing key-page pairings, resident or not
+	cachedList   list.List                // Circular list of pages, wrapping at the back
+	handHot      *list.Element            // Oldest page; new pages are inserted just behind it
+	handCold     *list.Element            // The next page to consider for eviction
+	handTest     *list.Element            // The next page whose test period to end
+	capacity     int                      // To hold the capacity of the cache
+	coldTarget   int                      // Bytes of the capacity currently allotted to cold pages
+	hotBytes     int                      // Bytes used by hot pages
+	coldBytes    int                      // Bytes used by resident cold pages
+	testBytes    int                      // Bytes that non-resident test pages would use
+	hotCount     int                      // Number of hot pages
+	coldCount    int                      // Number of resident cold pages
+	testCount    int                      // Number of non-resident test pages
+	stats        Stats                    // Hits and misses for the cache
+	onEvict      EvictionCallback         // Called whenever a binding leaves the cache
+}
+
+// NewClockPro returns a pointer to a new CLOCKPro with a capacity to store
+// limit bytes. Half of the capacity is initially allotted to cold pages.
+func NewClockPro(limit int) *CLOCKPro {
+	return &CLOCKPro{cachedValues: make(map[string]*list.Element), capacity: limit, coldTarget: limit / 2, stats: Stats{}}
+}
+
+func init() {
+	Register("clockpro", func(limit int) Cache { return NewClockPro(limit) })
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the CLOCKPro,
+// replacing any previously registered callback. Non-resident test pages carry
+// no value, so forgetting one is not reported.
+func (cp *CLOCKPro) OnEvict(fn EvictionCallback) {
+	cp.onEvict = fn
+}
+
+// notify records in the stats that a binding left the CLOCKPro, and reports
+// it to the eviction callback, if any.
+func (cp *CLOCKPro) notify(page *clockProPage, reason RemovalReason) {
+	cp.stats.recordRemoval(reason)
+	if cp.onEvict != nil {
+		cp.onEvict(page.key, page.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this CLOCKPro can store
+func (cp *CLOCKPro) MaxStorage() int {
+	return cp.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this CLOCKPro
+func (cp *CLOCKPro) RemainingStorage() int {
+	return cp.capacity - cp.hotBytes - cp.coldBytes
+}
+
+// resident returns the page for key if it holds a binding.
+func (cp *CLOCKPro) resident(key string) (*clockProPage, bool) {
+	elem, ok := cp.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+	page := elem.Value.(*clockProPage)
+	return page, page.status != pageTest
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not set the reference bit or update the stats.
+// ok is true if a value was found and false otherwise.
+func (cp *CLOCKPro) Peek(key string) (value []byte, ok bool) {
+	page, ok := cp.resident(key)
+	if !ok {
+		return nil, false
+	}
+	return page.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and sets
+// its reference bit.
+// ok is true if a value was found and false otherwise.
+func (cp *CLOCKPro) Get(key string) (value []byte, ok bool) {
+	page, ok := cp.resident(key)
+	if !ok {
+		cp.stats.Misses += 1
+		return nil, false
+	}
+
+	page.referenced = true
+	cp.stats.Hits += 1
+	cp.stats.HitBytes += len(page.value)
+	return page.value, true
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (cp *CLOCKPro) Remove(key string) (value []byte, ok bool) {
+	elem, found := cp.cachedValues[key]
+	if !found {
+		return nil, false
+	}
+
+	page := cp.unlink(elem)
+	if page.status == pageTest {
+		return nil, false
+	}
+	cp.notify(page, ReasonRemoved)
+	return page.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. A new key becomes a cold page in its test period; a key that
+// is still remembered as a non-resident test page comes back hot. Overwriting
+// a binding sets its reference bit and never evicts it.
+// Returns true if the binding was added successfully, else false.
+func (cp *CLOCKPro) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > cp.capacity {
+		cp.stats.recordRejectedSet(value)
+		return false
+	}
+
+	elem, found := cp.cachedValues[key]
+	if found && elem.Value.(*clockProPage).status != pageTest {
+		page := elem.Value.(*clockProPage)
+		replaced := *page
+
+		cp.resize(page, currentObjectSize)
+		page.value = value
+		page.referenced = true
+		cp.makeRoom(0, page)
+
+		cp.stats.Sets += 1
+		cp.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	page := &clockProPage{key: key, value: value, size: currentObjectSize, status: pageCold, inTest: true}
+	if found {
+		// Reused during its test period, so it has a short reuse
+		// distance: give cold pages more room and bring it back hot
+		cp.coldTarget += elem.Value.(*clockProPage).size
+		if cp.coldTarget > cp.capacity {
+			cp.coldTarget = cp.capacity
+		}
+		cp.unlink(elem)
+		page.status = pageHot
+		page.inTest = false
+	}
+
+	cp.makeRoom(currentObjectSize, nil)
+	cp.insert(page)
+	cp.balanceHot()
+	cp.stats.Sets += 1
+	cp.stats.MissBytes += len(value)
+	return true
+}
+
+// Empty removes every binding from the CLOCKPro, and forgets every
+// non-resident page.
+func (cp *CLOCKPro) Empty() {
+	if cp.onEvict != nil {
+		for _, elem := range cp.cachedValues {
+			if page := elem.Value.(*clockProPage); page.status != pageTest {
+				cp.notify(page, ReasonEmptied)
+			}
+		}
+	}
+	cp.cachedValues = make(map[string]*list.Element)
+	cp.cachedList.Init()
+	cp.handHot, cp.handCold, cp.handTest = nil, nil, nil
+	cp.hotBytes, cp.coldBytes, cp.testBytes = 0, 0, 0
+	cp.hotCount, cp.coldCount, cp.testCount = 0, 0, 0
+	cp.coldTarget = cp.capacity / 2
+}
+
+// Evict runs HANDcold until it evicts a cold page and returns the page's key.
+// ok is false if the CLOCKPro was already empty.
+func (cp *CLOCKPro) Evict() (key string, ok bool) {
+	if cp.hotCount+cp.coldCount == 0 {
+		return "", false
+	}
+	for {
+		if page := cp.runHandCold(nil); page != nil {
+			return page.key, true
+		}
+	}
+}
+
+// Len returns the number of bindings in the CLOCKPro.
+func (cp *CLOCKPro) Len() int {
+	return cp.hotCount + cp.coldCount
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (cp *CLOCKPro) Stats() *Stats {
+	return &cp.stats
+}
+
+// makeRoom evicts cold pages other than skip until size more bytes fit.
+func (cp *CLOCKPro) makeRoom(size int, skip *clockProPage) {
+	for cp.RemainingStorage() < size {
+		cp.runHandCold(skip)
+	}
+}
+
+// balanceHot runs HANDhot until hot pages fit in the share of the capacity
+// not allotted to cold pages.
+func (cp *CLOCKPro) balanceHot() {
+	for cp.hotBytes > cp.capacity-cp.coldTarget {
+		cp.runHandHot()
+	}
+}
+
+// runHandCold moves HANDcold to the next resident cold page other than skip
+// and deals with it, then moves the hand past it. A referenced page in its
+// test period is promoted to hot; a referenced page out of its test period
+// starts a new one. An unreferenced page is evicted, and stays on as a
+// non-resident test page if it is in its test period. Returns the evicted
+// page, if any.
+func (cp *CLOCKPro) runHandCold(skip *clockProPage) *clockProPage {
+	// Without a cold page to evict, demote hot ones until there is one
+	for cp.coldCount == 0 || (cp.coldCount == 1 && skip != nil && skip.status == pageCold) {
+		cp.runHandHot()
+	}
+
+	page := cp.handCold.Value.(*clockProPage)
+	for page.status != pageCold || page == skip {
+		cp.handCold = clockNext(&cp.cachedList, cp.handCold)
+		page = cp.handCold.Value.(*clockProPage)
+	}
+	elem := cp.handCold
+	cp.handCold = clockNext(&cp.cachedList, elem)
+
+	if page.referenced {
+		page.referenced = false
+		if page.inTest {
+			cp.setStatus(page, pageHot)
+			page.inTest = false
+			cp.balanceHot()
+		} else {
+			page.inTest = true
+		}
+		return nil
+	}
+
+	if !page.inTest {
+		cp.unlink(elem)
+		cp.notify(page, ReasonEvicted)
+		return page
+	}
+
+	evicted := *page
+	cp.setStatus(page, pageTest)
+	page.value = nil
+	cp.notify(&evicted, ReasonEvicted)
+	for cp.testBytes > cp.capacity {
+		cp.runHandTest()
+	}
+	return &evicted
+}
+
+// runHandHot deals with the page under HANDhot and moves the hand past it.
+// A referenced hot page has its bit cleared and an unreferenced one is
+// demoted to cold. HANDhot also ends the test periods of the pages it
+// passes, as HANDtest does, since they are now older than every hot page.
+func (cp *CLOCKPro) runHandHot() {
+	if cp.handHot == cp.handTest {
+		cp.runHandTest()
+	}
+
+	elem := cp.handHot
+	page := elem.Value.(*clockProPage)
+	cp.handHot = clockNext(&cp.cachedList, elem)
+
+	switch page.status {
+	case pageHot:
+		if page.referenced {
+			page.referenced = false
+		} else {
+			cp.setStatus(page, pageCold)
+		}
+	case pageCold:
+		cp.endTest(elem)
+	case pageTest:
+		cp.endTest(elem)
+	}
+}
+
+// runHandTest ends the test period of the page under HANDtest, if it is in
+// one, and moves the hand past it.
+func (cp *CLOCKPro) runHandTest() {
+	elem := cp.handTest
+	cp.handTest = clockNext(&cp.cachedList, elem)
+	cp.endTest(elem)
+}
+
+// endTest ends the test period of the page in elem, if it is in one. The
+// page was not reused in time, so cold pages get less room. A non-resident
+// test page is forgotten.
+func (cp *CLOCKPro) endTest(elem *list.Element) {
+	page := elem.Value.(*clockProPage)
+	switch {
+	case page.status == pageTest:
+		cp.unlink(elem)
+	case page.status == pageCold && page.inTest:
+		page.inTest = false
+	default:
+		return
+	}
+
+	cp.coldTarget -= page.size
+	if cp.coldTarget < 0 {
+		cp.coldTarget = 0
+	}
+}
+
+// insert adds page at the head of the clock, just behind HANDhot.
+func (cp *CLOCKPro) insert(page *clockProPage) {
+	var elem *list.Element
+	if cp.handHot == nil {
+		elem = cp.cachedList.PushBack(page)
+		cp.handHot, cp.handCold, cp.handTest = elem, elem, elem
+	} else {
+		elem = cp.cachedList.InsertBefore(page, cp.handHot)
+	}
+	cp.cachedValues[page.key] = elem
+	cp.account(page, 1)
+}
+
+// unlink removes the page in elem from the clock, moving any hand on it to
+// the next page, without notifying the eviction callback.
+func (cp *CLOCKPro) unlink(elem *list.Element) *clockProPage {
+	next := clockNext(&cp.cachedList, elem)
+	if next == elem {
+		next = nil
+	}
+	for _, hand := range []**list.Element{&cp.handHot, &cp.handCold, &cp.handTest} {
+		if *hand == elem {
+			*hand = next
+		}
+	}
+
+	page := cp.cachedList.Remove(elem).(*clockProPage)
+	delete(cp.cachedValues, page.key)
+	cp.account(page, -1)
+	return page
+}
+
+// setStatus moves page to the given status, updating the counts.
+func (cp *CLOCKPro) setStatus(page *clockProPage, status pageStatus) {
+	cp.account(page, -1)
+	page.status = status
+	cp.account(page, 1)
+}
+
+// resize changes the recorded size of a resident page, updating the counts.
+func (cp *CLOCKPro) resize(page *clockProPage, size int) {
+	cp.account(page, -1)
+	page.size = size
+	cp.account(page, 1)
+}
+
+// account adds (sign 1) or removes (sign -1) page from the counts for its
+// status.
+func (cp *CLOCKPro) account(page *clockProPage, sign int) {
+	switch page.status {
+	case pageHot:
+		cp.hotBytes += sign * page.size
+		cp.hotCount += sign
+	case pageCold:
+		cp.coldBytes += sign * page.size
+		cp.coldCount += sign
+	case pageTest:
+		cp.testBytes += sign * page.size
+		cp.testCount += sign
+	}
+}
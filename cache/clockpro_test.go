@@ -0,0 +1,199 @@
+/******************************************************************************
+ * clockpro_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for clockpro.go. The Cache contract CLOCKPro shares
+ *    with every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// checkStatus fails t unless key is in cp with the given status.
+func checkStatus(t *testing.T, cp *CLOCKPro, key string, status pageStatus) {
+	elem, ok := cp.cachedValues[key]
+	if !ok {
+		t.Errorf("%s forgotten. Got %v, Expected status %v", key, ok, status)
+		t.FailNow()
+	}
+	if page := elem.Value.(*clockProPage); page.status != status {
+		t.Errorf("%s has wrong status. Got %v, Expected %v", key, page.status, status)
+		t.FailNow()
+	}
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check a hand-worked example. Four 10-byte pages fill a CLOCKPro of 40 bytes
+// with 20 allotted to cold pages. ____0 and ____1 are hit during their test
+// periods, so HANDcold promotes them to hot and evicts ____2, keeping it as a
+// non-resident test page. When ____2 comes back it is hot, the cold allotment
+// grows to 30 bytes, and HANDcold evicts ____3 to make room. The hot pages now
+// exceed their 10 bytes, so HANDhot demotes ____0 and ____1 to cold.
+func TestEvictionOrderClockPro(t *testing.T) {
+	cp := NewClockPro(40)
+	removals := recordRemovals(cp)
+
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("____%d", i)
+		cp.Set(key, []byte(key))
+		checkStatus(t, cp, key, pageCold)
+	}
+	cp.Get("____0")
+	cp.Get("____1")
+
+	cp.Set("____4", []byte("____4"))
+	checkRemovals(t, cp, *removals, []removal{
+		{"____2", "____2", ReasonEvicted},
+	})
+	checkStatus(t, cp, "____0", pageHot)
+	checkStatus(t, cp, "____1", pageHot)
+	checkStatus(t, cp, "____2", pageTest)
+	if _, ok := cp.Get("____2"); ok || cp.Len() != 4 {
+		t.Errorf("Non-resident page still readable. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+
+	cp.Set("____2", []byte("____2"))
+	checkRemovals(t, cp, *removals, []removal{
+		{"____2", "____2", ReasonEvicted},
+		{"____3", "____3", ReasonEvicted},
+	})
+	checkStatus(t, cp, "____2", pageHot)
+	checkStatus(t, cp, "____3", pageTest)
+	checkStatus(t, cp, "____0", pageCold)
+	checkStatus(t, cp, "____1", pageCold)
+	if cp.coldTarget != 30 {
+		t.Errorf("Cold allotment did not grow. Got %v, Expected %v", cp.coldTarget, 30)
+		t.FailNow()
+	}
+
+	// HANDcold continues from ____4, a cold page still in its test period
+	cp.Set("____5", []byte("____5"))
+	checkRemovals(t, cp, *removals, []removal{
+		{"____2", "____2", ReasonEvicted},
+		{"____3", "____3", ReasonEvicted},
+		{"____4", "____4", ReasonEvicted},
+	})
+	checkStatus(t, cp, "____4", pageTest)
+	if cp.Len() != 4 || cp.RemainingStorage() != 0 {
+		t.Errorf("Wrong occupancy. Got %v bindings and %v bytes left, Expected %v and %v", cp.Len(), cp.RemainingStorage(), 4, 0)
+		t.FailNow()
+	}
+}
+
+// Check that non-resident pages are bounded and forgotten as test periods end
+func TestTestPagesClockPro(t *testing.T) {
+	cp := NewClockPro(40)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("___%02d", i)
+		cp.Set(key, []byte(key))
+		if cp.testBytes > cp.capacity {
+			t.Errorf("Non-resident pages exceed capacity. Got %v, Expected at most %v", cp.testBytes, cp.capacity)
+			t.FailNow()
+		}
+	}
+	if len(cp.cachedValues) != cp.Len()+cp.testCount || cp.Len() != 4 {
+		t.Errorf("Page counts inconsistent. Got %v pages, %v resident and %v test", len(cp.cachedValues), cp.Len(), cp.testCount)
+		t.FailNow()
+	}
+
+	// A one-off scan never gets to keep the cold allotment at its maximum
+	if cp.coldTarget >= cp.capacity {
+		t.Errorf("Cold allotment did not shrink. Got %v, Expected less than %v", cp.coldTarget, cp.capacity)
+		t.FailNow()
+	}
+
+	// Removing a non-resident page forgets it without reporting a binding
+	key := ""
+	for k, elem := range cp.cachedValues {
+		if elem.Value.(*clockProPage).status == pageTest {
+			key = k
+		}
+	}
+	if _, ok := cp.Remove(key); ok {
+		t.Errorf("Removed a non-resident page as a binding. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if _, ok := cp.cachedValues[key]; ok {
+		t.Errorf("Remove did not forget non-resident page %s", key)
+		t.FailNow()
+	}
+}
+
+// Check that pages promoted to hot survive a long scan of one-off keys
+func TestScanResistanceClockPro(t *testing.T) {
+	cp := NewClockPro(100)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 4; i++ {
+			key := fmt.Sprintf("hot_%d", i)
+			if _, ok := cp.Get(key); !ok {
+				cp.Set(key, []byte(key))
+			}
+		}
+	}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("scan%02d", i)
+		cp.Set(key, []byte(key))
+		if i%5 == 0 {
+			cp.Get(fmt.Sprintf("hot_%d", i%4))
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("hot_%d", i)
+		if _, ok := cp.Peek(key); !ok {
+			t.Errorf("Scan evicted hot page %s. Got %v, Expected %v", key, ok, true)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that growing a binding evicts others but never itself
+func TestOverwriteClockPro(t *testing.T) {
+	cp := NewClockPro(30)
+	cp.Set("____0", []byte("____0"))
+	cp.Set("____1", []byte("____1"))
+	cp.Set("____2", []byte("____2"))
+
+	cp.Set("____0", []byte("__________0"))
+	if value, ok := cp.Peek("____0"); !ok || !bytesEqual(value, []byte("__________0")) {
+		t.Errorf("Overwrite lost the binding. Got %v, Expected %v", value, []byte("__________0"))
+		t.FailNow()
+	}
+	if cp.Len() != 2 || cp.RemainingStorage() != 4 {
+		t.Errorf("Growing overwrite evicted wrong bindings. Got %v bindings and %v bytes left, Expected %v and %v", cp.Len(), cp.RemainingStorage(), 2, 4)
+		t.FailNow()
+	}
+}
+
+// Check that Empty() removes every page and leaves a usable CLOCKPro
+func TestEmptyClockPro(t *testing.T) {
+	cp := NewClockPro(20)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("k%d", i)
+		cp.Set(key, []byte(key))
+	}
+	cp.Empty()
+
+	if cp.Len() != 0 || cp.RemainingStorage() != 20 || len(cp.cachedValues) != 0 {
+		t.Errorf("Empty left pages behind. Got %v pages and %v bytes left, Expected %v and %v", len(cp.cachedValues), cp.RemainingStorage(), 0, 20)
+		t.FailNow()
+	}
+	cp.Set("c", []byte("3"))
+	if value, ok := cp.Get("c"); !ok || !bytesEqual(value, []byte("3")) {
+		t.Errorf("CLOCKPro unusable after Empty. Got %v, Expected %v", value, []byte("3"))
+		t.FailNow()
+	}
+}
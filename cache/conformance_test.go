@@ -0,0 +1,36 @@
+/******************************************************************************
+ * conformance_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    Runs the cachetest conformance suite against every registered policy.
+ ******************************************************************************/
+
+package cache_test
+
+import (
+	"testing"
+
+	"cos316.princeton.edu/assignment3/cache"
+	"cos316.princeton.edu/assignment3/cachetest"
+)
+
+// Check that every registered policy honours the Cache contract
+func TestConformance(t *testing.T) {
+	for _, name := range cache.Names() {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			if _, err := cache.New(name, 0); err != nil {
+				t.Fatalf("Failed to build %s. Got %v, Expected %v", name, err, nil)
+			}
+			cachetest.RunConformance(t, func(limit int) cache.Cache {
+				// New cannot fail for a name it has built before
+				c, err := cache.New(name, limit)
+				if err != nil {
+					panic(err)
+				}
+				return c
+			})
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// A FIFO is a fixed-size in-memory cache with first-in first-out eviction
+type FIFO struct {
+	cachedValues          map[string]mapping // Map containing key-value pairings
+	cachedList            list.List          // Linked list to hold insertion order
+	capacity              int                // To hold the capacity of the cache
+	currentlyUsedCapacity int                // Currently used capacity of the cache
+	stats                 Stats              // Hits and misses for the cache
+	onEvict               EvictionCallback   // Called whenever a binding leaves the cache
+}
+
+// NewFifo returns a pointer to a new FIFO with a capacity to store limit bytes
+func NewFifo(limit int) *FIFO {
+	return &FIFO{cachedValues: make(map[string]mapping), capacity: limit, currentlyUsedCapacity: 0, stats: Stats{}}
+}
+
+func init() {
+	Register("fifo", func(limit int) Cache { return NewFifo(limit) })
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the FIFO,
+// replacing any previously registered callback.
+func (fifo *FIFO) OnEvict(fn EvictionCallback) {
+	fifo.onEvict = fn
+}
+
+// notify records in the stats that a binding left the FIFO, and reports it to
+// the eviction callback, if any.
+func (fifo *FIFO) notify(currMapping mapping, reason RemovalReason) {
+	fifo.stats.recordRemoval(reason)
+	if fifo.onEvict != nil {
+		fifo.onEvict(currMapping.key, currMapping.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this FIFO can store
+func (fifo *FIFO) MaxStorage() int {
+	return fifo.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this FIFO
+func (fifo *FIFO) RemainingStorage() int {
+	return fifo.capacity - fifo.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not update the stats of the FIFO.
+// ok is true if a value was found and false otherwise.
+func (fifo *FIFO) Peek(key string) (value []byte, ok bool) {
+	currMapping, ok := fifo.cachedValues[key]
+	return currMapping.value, ok
+}
+
+// Get returns the value associated with the given key, if it exists.
+// Unlike an LRU, a use does not change the eviction order of a FIFO.
+// ok is true if a value was found and false otherwise.
+func (fifo *FIFO) Get(key string) (value []byte, ok bool) {
+	currMapping, ok := fifo.cachedValues[key]
+
+	if ok {
+		fifo.stats.Hits += 1
+		fifo.stats.HitBytes += len(currMapping.value)
+	} else {
+		fifo.stats.Misses += 1
+	}
+
+	return currMapping.value, ok
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (fifo *FIFO) Remove(key string) (value []byte, ok bool) {
+	currMapping, ok := fifo.removeMapping(key)
+
+	if !ok {
+		return nil, false
+	}
+
+	fifo.notify(currMapping, ReasonRemoved)
+	return currMapping.value, ok
+}
+
+// removeMapping removes and returns the binding for key, if it exists, without
+// notifying the eviction callback.
+func (fifo *FIFO) removeMapping(key string) (mapping, bool) {
+	currMapping, ok := fifo.cachedValues[key]
+
+	if !ok {
+		return mapping{}, false
+	}
+
+	delete(fifo.cachedValues, key)
+	fifo.cachedList.Remove(currMapping.Node)
+	fifo.currentlyUsedCapacity -= len(currMapping.key) + len(currMapping.value)
+
+	return currMapping, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. Overwriting an existing key keeps its place in the queue and
+// never evicts it. Returns true if the binding was added successfully, else false.
+func (fifo *FIFO) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > fifo.capacity {
+		fifo.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if currMapping, ok := fifo.cachedValues[key]; ok {
+		// Evict the oldest other bindings to make room, even if the binding
+		// itself is the oldest, so that it never evicts itself
+		growth := len(value) - len(currMapping.value)
+		for fifo.RemainingStorage() < growth {
+			elem := fifo.cachedList.Back()
+			if elem == currMapping.Node {
+				elem = elem.Prev()
+			}
+			fifo.evictElement(elem)
+		}
+
+		replaced := currMapping
+		currMapping.value = value
+		currMapping.Node.Value = currMapping
+		fifo.cachedValues[key] = currMapping
+		fifo.currentlyUsedCapacity += growth
+		fifo.stats.Sets += 1
+		fifo.notify(replaced, ReasonReplaced)
+		return true
+	}
+
+	for fifo.RemainingStorage() < currentObjectSize {
+		if _, ok := fifo.Evict(); !ok {
+			return false
+		}
+	}
+
+	currMapping := mapping{key: key, value: value, Node: nil}
+	currMapping.Node = fifo.cachedList.PushFront(currMapping)
+	currMapping.Node.Value = currMapping
+	fifo.cachedValues[key] = currMapping
+
+	// Increase currentlyUsedCapacity to reflect currentObjectSize
+	fifo.currentlyUsedCapacity += currentObjectSize
+	fifo.stats.Sets += 1
+	fifo.stats.MissBytes += len(value)
+	return true
+}
+
+// Empty removes every binding from the FIFO.
+func (fifo *FIFO) Empty() {
+	if fifo.onEvict != nil {
+		for _, currMapping := range fifo.cachedValues {
+			fifo.notify(currMapping, ReasonEmptied)
+		}
+	}
+	fifo.cachedValues = make(map[string]mapping)
+	fifo.cachedList.Init()
+	fifo.currentlyUsedCapacity = 0
+}
+
+// Evict removes the oldest binding in the FIFO and returns its key.
+// ok is false if the FIFO was already empty.
+func (fifo *FIFO) Evict() (key string, ok bool) {
+	elem := fifo.cachedList.Back()
+	if elem == nil {
+		return "", false
+	}
+	return fifo.evictElement(elem).key, true
+}
+
+// evictElement removes the binding held by elem, reports it as evicted and
+// returns it.
+func (fifo *FIFO) evictElement(elem *list.Element) mapping {
+	currMapping := elem.Value.(mapping)
+
+	delete(fifo.cachedValues, currMapping.key)
+	fifo.cachedList.Remove(elem)
+	fifo.currentlyUsedCapacity -= len(currMapping.key) + len(currMapping.value)
+
+	fifo.notify(currMapping, ReasonEvicted)
+	return currMapping
+}
+
+// Len returns the number of bindings in the FIFO.
+func (fifo *FIFO) Len() int {
+	return len(fifo.cachedValues)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (fifo *FIFO) Stats() *Stats {
+	return &fifo.stats
+}
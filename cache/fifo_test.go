@@ -0,0 +1,100 @@
+/******************************************************************************
+ * fifo_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for fifo.go. The Cache contract FIFO shares with
+ *    every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"testing"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that bindings are evicted in insertion order, regardless of use
+func TestEvictionOrderFifo(t *testing.T) {
+	capacity := 30
+	fifo := NewFifo(capacity)
+	checkCapacity(t, fifo, capacity)
+
+	fifo.Set("____0", []byte("____0"))
+	fifo.Set("____1", []byte("____1"))
+	fifo.Set("____2", []byte("____2"))
+
+	// A use must not save the oldest binding from eviction
+	fifo.Get("____0")
+	fifo.Set("____3", []byte("____3"))
+
+	if _, ok := fifo.Peek("____0"); ok {
+		t.Errorf("Oldest binding was not evicted. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	for _, key := range []string{"____1", "____2", "____3"} {
+		if _, ok := fifo.Peek(key); !ok {
+			t.Errorf("Binding %s was evicted out of order. Got %v, Expected %v", key, ok, true)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that overwriting a binding does not move it to the back of the queue
+func TestOverwriteKeepsOrderFifo(t *testing.T) {
+	capacity := 30
+	fifo := NewFifo(capacity)
+	checkCapacity(t, fifo, capacity)
+
+	fifo.Set("____0", []byte("____0"))
+	fifo.Set("____1", []byte("____1"))
+	fifo.Set("____2", []byte("____2"))
+	fifo.Set("____0", []byte("___00"))
+	fifo.Set("____3", []byte("____3"))
+
+	if _, ok := fifo.Peek("____0"); ok {
+		t.Errorf("Overwritten binding was not evicted first. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if fifo.RemainingStorage() != 0 {
+		t.Errorf("RemainingStorage wrong after eviction. Got %v, Expected %v", fifo.RemainingStorage(), 0)
+		t.FailNow()
+	}
+}
+
+// Check that growing a binding evicts the oldest bindings to make room
+func TestOverwriteGrowFifo(t *testing.T) {
+	capacity := 30
+	fifo := NewFifo(capacity)
+	checkCapacity(t, fifo, capacity)
+
+	fifo.Set("____0", []byte("____0"))
+	fifo.Set("____1", []byte("____1"))
+	fifo.Set("____2", []byte("____2"))
+
+	ok := fifo.Set("____2", []byte("____2____2"))
+	if !ok {
+		t.Errorf("Failed to overwrite binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if fifo.Len() != 2 {
+		t.Errorf("Len wrong after overwrite. Got %v, Expected %v", fifo.Len(), 2)
+		t.FailNow()
+	}
+	if _, ok := fifo.Peek("____0"); ok {
+		t.Errorf("Oldest binding was not evicted. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	value, _ := fifo.Get("____2")
+	if !bytesEqual(value, []byte("____2____2")) {
+		t.Errorf("Fetched wrong value. Got %v, Expected %v", value, []byte("____2____2"))
+		t.FailNow()
+	}
+	if fifo.RemainingStorage() != 5 {
+		t.Errorf("RemainingStorage wrong after overwrite. Got %v, Expected %v", fifo.RemainingStorage(), 5)
+		t.FailNow()
+	}
+}
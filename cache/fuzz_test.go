@@ -0,0 +1,375 @@
+/******************************************************************************
+ * fuzz_test.go
+ * Author:
+ * Usage:    `go test -fuzz FuzzPolicies`  or  `go test -fuzz FuzzLru`
+ * Description:
+ *    Fuzz targets that replay random operations against every policy and
+ *    check it against a map model, and LRU against a naive reference.
+ *    Without -fuzz, `go test` replays the seed corpus.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// fuzzKeys are the keys fuzzed operations draw from, including the empty key
+var fuzzKeys = []string{"", "a", "b", "c", "dd", "eee", "ffff", "ggggg"}
+
+// Kinds of fuzzed operation
+const (
+	fuzzSet = iota
+	fuzzGet
+	fuzzPeek
+	fuzzRemove
+	fuzzEmpty
+)
+
+// A fuzzOp is one operation decoded from fuzz input
+type fuzzOp struct {
+	kind  int
+	key   string
+	value []byte
+}
+
+func (op fuzzOp) String() string {
+	switch op.kind {
+	case fuzzSet:
+		return fmt.Sprintf("Set(%q, %d bytes)", op.key, len(op.value))
+	case fuzzGet:
+		return fmt.Sprintf("Get(%q)", op.key)
+	case fuzzPeek:
+		return fmt.Sprintf("Peek(%q)", op.key)
+	case fuzzRemove:
+		return fmt.Sprintf("Remove(%q)", op.key)
+	default:
+		return "Empty()"
+	}
+}
+
+// decodeOps splits data into operations of three bytes each: the kind, the
+// key and the size of the value. Sets are the most common kind, and Empty the
+// rarest, so that caches fill up. Every value is filled with the index of its
+// operation, so that the values of different Sets differ.
+func decodeOps(data []byte) []fuzzOp {
+	ops := make([]fuzzOp, 0, len(data)/3)
+	for i := 0; i+2 < len(data); i += 3 {
+		op := fuzzOp{key: fuzzKeys[int(data[i+1])%len(fuzzKeys)]}
+		switch kind := data[i] % 16; {
+		case kind < 7:
+			op.kind = fuzzSet
+			op.value = bytes.Repeat([]byte{byte(len(ops))}, int(data[i+2]%16))
+		case kind < 11:
+			op.kind = fuzzGet
+		case kind < 13:
+			op.kind = fuzzPeek
+		case kind < 15:
+			op.kind = fuzzRemove
+		default:
+			op.kind = fuzzEmpty
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// encodeOp returns the fuzz input decodeOps turns into a Set of size value
+// bytes, or another kind of operation, on fuzzKeys[key].
+func encodeOp(kind int, key int, size int) []byte {
+	kinds := []byte{fuzzSet: 0, fuzzGet: 7, fuzzPeek: 11, fuzzRemove: 13, fuzzEmpty: 15}
+	return []byte{kinds[kind], byte(key), byte(size)}
+}
+
+// addSeeds adds the seed corpus shared by every fuzz target to f.
+func addSeeds(f *testing.F) {
+	var fill, churn []byte
+	for i := 0; i < 40; i++ {
+		fill = append(fill, encodeOp(fuzzSet, i, i)...)
+		churn = append(churn, encodeOp(i%5, i*3, i*7)...)
+	}
+	f.Add(uint8(32), fill)
+	f.Add(uint8(24), churn)
+	f.Add(uint8(0), churn)
+
+	// Overwrites that grow a binding until others must be evicted
+	f.Add(uint8(20), bytes.Join([][]byte{
+		encodeOp(fuzzSet, 1, 4), encodeOp(fuzzSet, 2, 4), encodeOp(fuzzSet, 3, 4),
+		encodeOp(fuzzSet, 1, 15), encodeOp(fuzzGet, 2, 0), encodeOp(fuzzSet, 1, 0),
+	}, nil))
+
+	// A binding for the empty key is evicted, leaving a ghost of size 0 for
+	// policies that remember evicted keys, and then set again
+	f.Add(uint8(4), bytes.Join([][]byte{
+		encodeOp(fuzzSet, 0, 3), encodeOp(fuzzSet, 1, 3), encodeOp(fuzzSet, 0, 3),
+		encodeOp(fuzzGet, 0, 0), encodeOp(fuzzSet, 1, 3), encodeOp(fuzzSet, 0, 3),
+	}, nil))
+}
+
+// A fuzzModel checks a cache against a map of the bindings it should hold,
+// kept in step through the cache's eviction callback.
+type fuzzModel struct {
+	t        *testing.T
+	cache    notifyingCache
+	name     string
+	bindings map[string][]byte // Bindings the cache should hold
+	removals []removal         // Removals reported during the current step
+}
+
+// newFuzzModel returns a fuzzModel of cache, which must be empty.
+func newFuzzModel(t *testing.T, cache notifyingCache) *fuzzModel {
+	m := &fuzzModel{t: t, cache: cache, name: cacheType(cache), bindings: make(map[string][]byte)}
+	cache.OnEvict(func(key string, value []byte, reason RemovalReason) {
+		m.removals = append(m.removals, removal{key: key, value: string(value), reason: reason})
+	})
+	return m
+}
+
+// fail reports a failure of the given step and stops the test.
+func (m *fuzzModel) fail(step int, op fuzzOp, format string, args ...interface{}) {
+	m.t.Errorf("%s step %d %v: %s", m.name, step, op, fmt.Sprintf(format, args...))
+	m.t.FailNow()
+}
+
+// apply applies op to the cache, checks its result and the removals it
+// reported against the model, updates the model and checks the invariants.
+// It returns the removals reported.
+func (m *fuzzModel) apply(step int, op fuzzOp) []removal {
+	m.removals = nil
+	expected, present := m.bindings[op.key]
+
+	switch op.kind {
+	case fuzzSet:
+		ok := m.cache.Set(op.key, op.value)
+		fits := len(op.key)+len(op.value) <= m.cache.MaxStorage()
+		if ok != fits {
+			m.fail(step, op, "Set returned wrong result. Got %v, Expected %v", ok, fits)
+		}
+		m.expectReplaced(step, op, fits && present, expected)
+		if ok {
+			m.bindings[op.key] = op.value
+		}
+		m.expectOnly(step, op, ReasonEvicted, ReasonReplaced)
+
+	case fuzzGet, fuzzPeek:
+		var value []byte
+		var ok bool
+		if op.kind == fuzzGet {
+			value, ok = m.cache.Get(op.key)
+		} else {
+			value, ok = m.cache.Peek(op.key)
+		}
+		if ok != present || !bytes.Equal(value, expected) {
+			m.fail(step, op, "Returned wrong binding. Got %v, %v, Expected %v, %v", value, ok, expected, present)
+		}
+		m.expectOnly(step, op)
+
+	case fuzzRemove:
+		value, ok := m.cache.Remove(op.key)
+		if ok != present || !bytes.Equal(value, expected) {
+			m.fail(step, op, "Removed wrong binding. Got %v, %v, Expected %v, %v", value, ok, expected, present)
+		}
+		m.expectOnly(step, op, ReasonRemoved)
+
+	case fuzzEmpty:
+		m.cache.Empty()
+		m.expectOnly(step, op, ReasonEmptied)
+		if len(m.bindings) != 0 {
+			m.fail(step, op, "Empty did not report every binding. Got %v left, Expected %v", len(m.bindings), 0)
+		}
+	}
+
+	m.checkInvariants(step, op)
+	return m.removals
+}
+
+// expectReplaced checks that the step reported the binding for op.key being
+// replaced, with its old value, if and only if replaced is true.
+func (m *fuzzModel) expectReplaced(step int, op fuzzOp, replaced bool, old []byte) {
+	count := 0
+	for _, r := range m.removals {
+		if r.reason != ReasonReplaced {
+			continue
+		}
+		if r.key != op.key || r.value != string(old) {
+			m.fail(step, op, "Reported wrong replaced binding. Got %v, Expected %v", r, removal{key: op.key, value: string(old), reason: ReasonReplaced})
+		}
+		count += 1
+	}
+	if replaced && count != 1 || !replaced && count != 0 {
+		m.fail(step, op, "Reported wrong number of replacements. Got %v, Expected replaced %v", count, replaced)
+	}
+}
+
+// expectOnly checks that every removal the step reported has one of the
+// given reasons and, except for replacements, was a binding of the model, and
+// removes those bindings from the model.
+func (m *fuzzModel) expectOnly(step int, op fuzzOp, reasons ...RemovalReason) {
+	for _, r := range m.removals {
+		allowed := false
+		for _, reason := range reasons {
+			allowed = allowed || r.reason == reason
+		}
+		if !allowed {
+			m.fail(step, op, "Reported an unexpected removal. Got %v, Expected reasons %v", r, reasons)
+		}
+		if r.reason == ReasonReplaced {
+			continue
+		}
+
+		value, ok := m.bindings[r.key]
+		if !ok || r.value != string(value) {
+			m.fail(step, op, "Reported the removal of a binding it did not hold. Got %v, Expected %q=%v, %v", r, r.key, value, ok)
+		}
+		delete(m.bindings, r.key)
+	}
+}
+
+// checkInvariants checks that the cache holds exactly the bindings of the
+// model and accounts for their bytes, and that it keeps its own invariants if
+// it can check them.
+func (m *fuzzModel) checkInvariants(step int, op fuzzOp) {
+	used := 0
+	for key, value := range m.bindings {
+		used += len(key) + len(value)
+	}
+
+	remaining := m.cache.RemainingStorage()
+	if remaining < 0 || remaining != m.cache.MaxStorage()-used {
+		m.fail(step, op, "RemainingStorage wrong. Got %v, Expected %v", remaining, m.cache.MaxStorage()-used)
+	}
+	if m.cache.Len() != len(m.bindings) {
+		m.fail(step, op, "Len wrong. Got %v, Expected %v", m.cache.Len(), len(m.bindings))
+	}
+	for _, key := range fuzzKeys {
+		expected, present := m.bindings[key]
+		if value, ok := m.cache.Peek(key); ok != present || !bytes.Equal(value, expected) {
+			m.fail(step, op, "Holds wrong binding for %q. Got %v, %v, Expected %v, %v", key, value, ok, expected, present)
+		}
+	}
+
+	// Policies that can check their own invariants, such as ARC, do so
+	if checker, ok := m.cache.(interface{ CheckInvariants() error }); ok {
+		if err := checker.CheckInvariants(); err != nil {
+			m.fail(step, op, "Broke an invariant. Got %v, Expected %v", err, nil)
+		}
+	}
+}
+
+// A referenceLru is a naive LRU: a slice of bindings, least recently used
+// first, searched from end to end on every operation.
+type referenceLru struct {
+	capacity int
+	bindings []mapping
+}
+
+// find returns the index of the binding for key, or -1.
+func (ref *referenceLru) find(key string) int {
+	for i, binding := range ref.bindings {
+		if binding.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// used returns the number of bytes held.
+func (ref *referenceLru) used() int {
+	used := 0
+	for _, binding := range ref.bindings {
+		used += len(binding.key) + len(binding.value)
+	}
+	return used
+}
+
+// apply applies op and returns the keys it evicted, in order.
+func (ref *referenceLru) apply(op fuzzOp) (evicted []string) {
+	i := ref.find(op.key)
+	switch op.kind {
+	case fuzzSet:
+		size := len(op.key) + len(op.value)
+		if size > ref.capacity {
+			return nil
+		}
+		if i >= 0 {
+			ref.bindings = append(ref.bindings[:i], ref.bindings[i+1:]...)
+		}
+		for ref.used()+size > ref.capacity {
+			evicted = append(evicted, ref.bindings[0].key)
+			ref.bindings = ref.bindings[1:]
+		}
+		ref.bindings = append(ref.bindings, mapping{key: op.key, value: op.value})
+	case fuzzGet:
+		if i >= 0 {
+			binding := ref.bindings[i]
+			ref.bindings = append(append(ref.bindings[:i:i], ref.bindings[i+1:]...), binding)
+		}
+	case fuzzRemove:
+		if i >= 0 {
+			ref.bindings = append(ref.bindings[:i], ref.bindings[i+1:]...)
+		}
+	case fuzzEmpty:
+		ref.bindings = nil
+	}
+	return evicted
+}
+
+// keys returns the keys from least to most recently used.
+func (ref *referenceLru) keys() []string {
+	keys := make([]string, 0, len(ref.bindings))
+	for _, binding := range ref.bindings {
+		keys = append(keys, binding.key)
+	}
+	return keys
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check every registered policy against a map model
+func FuzzPolicies(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, capacity uint8, data []byte) {
+		ops := decodeOps(data)
+		for _, name := range Names() {
+			cache := mustNew(t, name, int(capacity%64))
+			m := newFuzzModel(t, cache.(notifyingCache))
+			for step, op := range ops {
+				m.apply(step, op)
+			}
+		}
+	})
+}
+
+// Check that LRU evicts exactly as a naive reference LRU does
+func FuzzLru(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, capacity uint8, data []byte) {
+		lru := NewLru(int(capacity % 64))
+		ref := &referenceLru{capacity: lru.MaxStorage()}
+		m := newFuzzModel(t, lru)
+
+		for step, op := range decodeOps(data) {
+			var evicted []string
+			for _, r := range m.apply(step, op) {
+				if r.reason == ReasonEvicted {
+					evicted = append(evicted, r.key)
+				}
+			}
+
+			if expected := ref.apply(op); fmt.Sprint(evicted) != fmt.Sprint(expected) {
+				m.fail(step, op, "Evicted wrong bindings. Got %q, Expected %q", evicted, expected)
+			}
+			if expected := ref.keys(); fmt.Sprintf("%q", lru.policy.keys()) != fmt.Sprintf("%q", expected) {
+				m.fail(step, op, "Wrong recency order. Got %q, Expected %q", lru.policy.keys(), expected)
+			}
+		}
+	})
+}
@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"container/heap"
+	"math"
+)
+
+// A GDSFMode selects the cost a GDSF gives bindings set without one, and so
+// which hit ratio it maximizes.
+type GDSFMode int
+
+const (
+	// ObjectHitRatio gives every binding a cost of one, so that a GDSF
+	// prefers to keep many small bindings.
+	ObjectHitRatio GDSFMode = iota
+
+	// ByteHitRatio gives every binding a cost equal to its size, so that a
+	// GDSF ranks bindings by frequency alone and keeps large popular ones.
+	ByteHitRatio
+)
+
+// A gdsfEntry is a binding in a GDSF along with its priority
+type gdsfEntry struct {
+	key      string
+	value    []byte
+	cost     float64 // Cost of fetching the binding again
+	freq     int     // Number of uses since the binding was set
+	priority float64 // Inflation when last used, plus freq * cost / size
+	used     uint64  // Sequence number of the last use, to break ties
+	index    int     // Position of the entry in the heap
+}
+
+// A gdsfHeap is a min-heap of entries by priority, least recently used first
+// among equal priorities
+type gdsfHeap []*gdsfEntry
+
+func (h gdsfHeap) Len() int { return len(h) }
+
+func (h gdsfHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].used < h[j].used
+}
+
+func (h gdsfHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *gdsfHeap) Push(x interface{}) {
+	entry := x.(*gdsfEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *gdsfHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// A GDSF is a fixed-size in-memory cache using GreedyDual-Size-Frequency
+// eviction (Cherkasova, HP Labs '98). Each binding has a priority of
+//
+//	L + frequency * cost / size
+//
+// and the binding with the lowest priority is evicted first. The inflation
+// value L is the priority of the last binding evicted, so bindings that have
+// not been used for a while age relative to newly used ones. Unlike recency
+// alone, this weighs how much space evicting a binding frees against how
+// often it is used and how expensive it is to fetch again.
+//
+// The cost of a binding can be given with SetWithCost; otherwise it follows
+// the mode of the GDSF. Get and Set take O(log n) time.
+type GDSF struct {
+	cachedValues          map[string]*gdsfEntry // Map containing key-entry pairings
+	priorities            gdsfHeap              // Entries by priority, next victim first
+	inflation             float64               // Priority of the last binding evicted
+	uses                  uint64                // Uses so far, to order entries by recency
+	mode                  GDSFMode              // Cost given to bindings set without one
+	capacity              int                   // To hold the capacity of the cache
+	currentlyUsedCapacity int                   // Currently used capacity of the cache
+	stats                 Stats                 // Hits and misses for the cache
+	onEvict               EvictionCallback      // Called whenever a binding leaves the cache
+}
+
+// NewGdsf returns a pointer to a new GDSF with a capacity to store limit bytes,
+// maximizing the object hit ratio.
+func NewGdsf(limit int) *GDSF {
+	return &GDSF{cachedValues: make(map[string]*gdsfEntry), mode: ObjectHitRatio, capacity: limit, currentlyUsedCapacity: 0, stats: Stats{}}
+}
+
+func init() {
+	Register("gdsf", func(limit int) Cache { return NewGdsf(limit) })
+}
+
+// SetMode sets the cost given to bindings set without one from now on.
+// Bindings already in the GDSF keep their cost.
+func (gdsf *GDSF) SetMode(mode GDSFMode) {
+	gdsf.mode = mode
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the GDSF,
+// replacing any previously registered callback.
+func (gdsf *GDSF) OnEvict(fn EvictionCallback) {
+	gdsf.onEvict = fn
+}
+
+// notify records in the stats that a binding left the GDSF, and reports it to
+// the eviction callback, if any.
+func (gdsf *GDSF) notify(entry *gdsfEntry, reason RemovalReason) {
+	gdsf.stats.recordRemoval(reason)
+	if gdsf.onEvict != nil {
+		gdsf.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this GDSF can store
+func (gdsf *GDSF) MaxStorage() int {
+	return gdsf.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this GDSF
+func (gdsf *GDSF) RemainingStorage() int {
+	return gdsf.capacity - gdsf.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not count as a use or update the stats.
+// ok is true if a value was found and false otherwise.
+func (gdsf *GDSF) Peek(key string) (value []byte, ok bool) {
+	entry, ok := gdsf.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and
+// counts a use of it, raising its priority.
+// ok is true if a value was found and false otherwise.
+func (gdsf *GDSF) Get(key string) (value []byte, ok bool) {
+	entry, ok := gdsf.cachedValues[key]
+	if !ok {
+		gdsf.stats.Misses += 1
+		return nil, false
+	}
+
+	entry.freq += 1
+	gdsf.prioritize(entry)
+	heap.Fix(&gdsf.priorities, entry.index)
+	gdsf.stats.Hits += 1
+	gdsf.stats.HitBytes += len(entry.value)
+	return entry.value, true
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (gdsf *GDSF) Remove(key string) (value []byte, ok bool) {
+	entry, ok := gdsf.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+
+	gdsf.unlink(entry)
+	gdsf.notify(entry, ReasonRemoved)
+	return entry.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room, with a cost that follows the mode of the GDSF.
+// Returns true if the binding was added successfully, else false.
+func (gdsf *GDSF) Set(key string, value []byte) bool {
+	cost := 1.0
+	if gdsf.mode == ByteHitRatio {
+		cost = float64(len(key) + len(value))
+	}
+	return gdsf.SetWithCost(key, value, cost)
+}
+
+// SetWithCost associates the given value with the given key, possibly
+// evicting values to make room. cost is how expensive the binding is to fetch
+// again, in any unit as long as it is the same for every binding; bindings
+// with a higher cost are kept longer. Overwriting a binding counts as a use
+// of it and never evicts it. A cost that is negative, infinite or NaN is
+// refused, leaving the GDSF unchanged.
+// Returns true if the binding was added successfully, else false.
+func (gdsf *GDSF) SetWithCost(key string, value []byte, cost float64) bool {
+	if cost < 0 || math.IsInf(cost, 0) || math.IsNaN(cost) {
+		return false
+	}
+
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > gdsf.capacity {
+		gdsf.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if entry, ok := gdsf.cachedValues[key]; ok {
+		replaced := *entry
+
+		// Take the entry out while making room, so that it cannot be
+		// chosen as a victim
+		gdsf.unlink(entry)
+		for gdsf.RemainingStorage() < currentObjectSize {
+			gdsf.Evict()
+		}
+
+		entry.value = value
+		entry.cost = cost
+		entry.freq += 1
+		gdsf.insert(entry)
+		gdsf.stats.Sets += 1
+		gdsf.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	for gdsf.RemainingStorage() < currentObjectSize {
+		if _, ok := gdsf.Evict(); !ok {
+			return false
+		}
+	}
+
+	gdsf.insert(&gdsfEntry{key: key, value: value, cost: cost, freq: 1})
+	gdsf.stats.Sets += 1
+	gdsf.stats.MissBytes += len(value)
+	return true
+}
+
+// prioritize sets the priority of entry from the current inflation, and marks
+// it as the most recently used.
+func (gdsf *GDSF) prioritize(entry *gdsfEntry) {
+	size := len(entry.key) + len(entry.value)
+	if size < 1 {
+		size = 1
+	}
+	entry.priority = gdsf.inflation + float64(entry.freq)*entry.cost/float64(size)
+	gdsf.uses += 1
+	entry.used = gdsf.uses
+}
+
+// insert adds entry to the GDSF with a fresh priority. The entry must fit.
+func (gdsf *GDSF) insert(entry *gdsfEntry) {
+	gdsf.prioritize(entry)
+	heap.Push(&gdsf.priorities, entry)
+	gdsf.cachedValues[entry.key] = entry
+	gdsf.currentlyUsedCapacity += len(entry.key) + len(entry.value)
+}
+
+// unlink removes entry from the GDSF without notifying the eviction callback.
+func (gdsf *GDSF) unlink(entry *gdsfEntry) {
+	heap.Remove(&gdsf.priorities, entry.index)
+	delete(gdsf.cachedValues, entry.key)
+	gdsf.currentlyUsedCapacity -= len(entry.key) + len(entry.value)
+}
+
+// Empty removes every binding from the GDSF and resets its inflation.
+func (gdsf *GDSF) Empty() {
+	if gdsf.onEvict != nil {
+		for _, entry := range gdsf.cachedValues {
+			gdsf.notify(entry, ReasonEmptied)
+		}
+	}
+	gdsf.cachedValues = make(map[string]*gdsfEntry)
+	gdsf.priorities = nil
+	gdsf.inflation = 0
+	gdsf.currentlyUsedCapacity = 0
+}
+
+// Evict removes the binding with the lowest priority, raising the inflation
+// to its priority, and returns its key.
+// ok is false if the GDSF was already empty.
+func (gdsf *GDSF) Evict() (key string, ok bool) {
+	if len(gdsf.priorities) == 0 {
+		return "", false
+	}
+
+	entry := gdsf.priorities[0]
+	gdsf.unlink(entry)
+	gdsf.inflation = entry.priority
+	gdsf.notify(entry, ReasonEvicted)
+	return entry.key, true
+}
+
+// Len returns the number of bindings in the GDSF.
+func (gdsf *GDSF) Len() int {
+	return len(gdsf.cachedValues)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (gdsf *GDSF) Stats() *Stats {
+	return &gdsf.stats
+}
@@ -0,0 +1,167 @@
+/******************************************************************************
+ * gdsf_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for gdsf.go. The Cache contract GDSF shares with
+ *    every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/workload"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that, maximizing the object hit ratio, a large binding is evicted
+// before small ones used as often
+func TestSizeGdsf(t *testing.T) {
+	gdsf := NewGdsf(100)
+	gdsf.Set("large", []byte("_____________________________________________"))
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("____%d", i)
+		gdsf.Set(key, []byte(key))
+	}
+
+	removals := recordRemovals(gdsf)
+	gdsf.Set("____5", []byte("____5"))
+	checkRemovals(t, gdsf, *removals, []removal{
+		{"large", "_____________________________________________", ReasonEvicted},
+	})
+	if expected := 1.0 / 50; gdsf.inflation != expected {
+		t.Errorf("Inflation not raised to the evicted priority. Got %v, Expected %v", gdsf.inflation, expected)
+		t.FailNow()
+	}
+}
+
+// Check that, maximizing the byte hit ratio, bindings are ranked by frequency
+// alone, so a large binding used twice outlives small ones used once
+func TestByteHitRatioGdsf(t *testing.T) {
+	gdsf := NewGdsf(100)
+	gdsf.SetMode(ByteHitRatio)
+	gdsf.Set("large", []byte("_____________________________________________"))
+	gdsf.Get("large")
+	for i := 0; i < 6; i++ {
+		key := fmt.Sprintf("____%d", i)
+		gdsf.Set(key, []byte(key))
+	}
+
+	if _, ok := gdsf.Peek("large"); !ok {
+		t.Errorf("Large binding used twice evicted. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if _, ok := gdsf.Peek("____0"); ok {
+		t.Errorf("Wrong binding evicted. Got %v, Expected %v", "large", "____0")
+		t.FailNow()
+	}
+}
+
+// Check that a binding with a higher cost outlives cheaper ones of the same
+// size, and that overwriting with Set gives it the default cost again
+func TestCostGdsf(t *testing.T) {
+	gdsf := NewGdsf(30)
+	gdsf.SetWithCost("____0", []byte("____0"), 10)
+	gdsf.Set("____1", []byte("____1"))
+	gdsf.Set("____2", []byte("____2"))
+	gdsf.Set("____3", []byte("____3"))
+	if _, ok := gdsf.Peek("____0"); !ok {
+		t.Errorf("Expensive binding evicted. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if _, ok := gdsf.Peek("____1"); ok {
+		t.Errorf("Wrong binding evicted. Got %v, Expected %v", "____0", "____1")
+		t.FailNow()
+	}
+
+	gdsf.Set("____0", []byte("____0"))
+	if entry := gdsf.cachedValues["____0"]; entry.cost != 1 || entry.freq != 2 {
+		t.Errorf("Overwrite kept the cost or lost the use. Got %v, %v, Expected %v, %v", entry.cost, entry.freq, 1, 2)
+		t.FailNow()
+	}
+}
+
+// Check that SetWithCost refuses costs that would corrupt priorities, and
+// leaves an existing binding untouched
+func TestInvalidCostGdsf(t *testing.T) {
+	gdsf := NewGdsf(30)
+	gdsf.SetWithCost("____0", []byte("____0"), 2)
+
+	for _, cost := range []float64{-1, math.Inf(1), math.Inf(-1), math.NaN()} {
+		if ok := gdsf.SetWithCost("____1", []byte("____1"), cost); ok {
+			t.Errorf("Accepted cost %v. Got %v, Expected %v", cost, ok, false)
+			t.FailNow()
+		}
+		if ok := gdsf.SetWithCost("____0", []byte("__0"), cost); ok {
+			t.Errorf("Accepted cost %v on overwrite. Got %v, Expected %v", cost, ok, false)
+			t.FailNow()
+		}
+	}
+
+	if _, ok := gdsf.Peek("____1"); ok || gdsf.Len() != 1 || gdsf.Stats().Sets != 1 {
+		t.Errorf("Refused bindings changed the GDSF. Got %v bindings and %v sets, Expected %v and %v", gdsf.Len(), gdsf.Stats().Sets, 1, 1)
+		t.FailNow()
+	}
+	if entry := gdsf.cachedValues["____0"]; entry.cost != 2 || !bytesEqual(entry.value, []byte("____0")) {
+		t.Errorf("Refused overwrite changed the binding. Got %v, %v, Expected %v, %v", entry.cost, entry.value, 2, []byte("____0"))
+		t.FailNow()
+	}
+	if ok := gdsf.SetWithCost("____1", []byte("____1"), 0); !ok {
+		t.Errorf("Refused a zero cost. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+}
+
+// Check that inflation lets new bindings displace ones that were used often
+// long ago
+func TestInflationGdsf(t *testing.T) {
+	gdsf := NewGdsf(30)
+	gdsf.Set("____0", []byte("____0"))
+	for i := 0; i < 5; i++ {
+		gdsf.Get("____0")
+	}
+
+	for i := 1; i < 100; i++ {
+		key := fmt.Sprintf("__%03d", i)
+		gdsf.Set(key, []byte(key))
+		gdsf.Get(key)
+		if _, ok := gdsf.Peek("____0"); !ok {
+			return
+		}
+	}
+	t.Errorf("Binding used often long ago never evicted. Got %v, Expected %v", true, false)
+	t.FailNow()
+}
+
+// Check that the two modes each beat the other, and LRU, on the hit ratio
+// they maximize, with sizes spread over two orders of magnitude
+func TestModesGdsf(t *testing.T) {
+	events := workload.New(workload.Config{
+		Seed:         316,
+		Keys:         workload.NewScrambledZipf(316, 5000, 0.9),
+		Sizes:        workload.NewParetoSize(316, 10, 4000, 1.2),
+		ReadFraction: 1,
+	}).Events(50000)
+
+	objectMode, byteMode, lru := NewGdsf(20000), NewGdsf(20000), NewLru(20000)
+	byteMode.SetMode(ByteHitRatio)
+	for _, cache := range []Cache{objectMode, byteMode, lru} {
+		replayEvents(cache, events)
+	}
+
+	if objectMode.Stats().HitRatio() <= lru.Stats().HitRatio() || objectMode.Stats().HitRatio() <= byteMode.Stats().HitRatio() {
+		t.Errorf("Object mode hit ratio not the best. Got %v, Expected more than %v and %v", objectMode.Stats().HitRatio(), lru.Stats().HitRatio(), byteMode.Stats().HitRatio())
+		t.FailNow()
+	}
+	if byteMode.Stats().ByteHitRatio() <= objectMode.Stats().ByteHitRatio() {
+		t.Errorf("Byte mode byte hit ratio below object mode's. Got %v, Expected more than %v", byteMode.Stats().ByteHitRatio(), objectMode.Stats().ByteHitRatio())
+		t.FailNow()
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// A ghostEntry is the memory of a binding that has left a cache
+type ghostEntry struct {
+	key  string
+	size int // len(key) + len(value) of the binding when it left
+}
+
+// A ghostList remembers the keys and sizes of bindings that have left a
+// cache, from most to least recently remembered. It holds no values.
+type ghostList struct {
+	entries map[string]*list.Element // Map containing key-element pairings
+	order   list.List                // Entries, most recently remembered first
+	size    int                      // Total size of the bindings remembered
+}
+
+// newGhostList returns an empty ghostList.
+func newGhostList() *ghostList {
+	return &ghostList{entries: make(map[string]*list.Element)}
+}
+
+// push remembers a binding of the given key and size as the most recent one.
+// A key already remembered is moved to the front with its new size.
+func (g *ghostList) push(key string, size int) {
+	g.remove(key)
+	g.entries[key] = g.order.PushFront(ghostEntry{key: key, size: size})
+	g.size += size
+}
+
+// remove forgets key and returns the size it was remembered with.
+func (g *ghostList) remove(key string) (size int, ok bool) {
+	elem, ok := g.entries[key]
+	if !ok {
+		return 0, false
+	}
+	entry := g.order.Remove(elem).(ghostEntry)
+	delete(g.entries, key)
+	g.size -= entry.size
+	return entry.size, true
+}
+
+// contains reports whether key is remembered.
+func (g *ghostList) contains(key string) bool {
+	_, ok := g.entries[key]
+	return ok
+}
+
+// popOldest forgets the least recently remembered binding and returns it.
+// ok is false if the list was empty.
+func (g *ghostList) popOldest() (entry ghostEntry, ok bool) {
+	elem := g.order.Back()
+	if elem == nil {
+		return ghostEntry{}, false
+	}
+	entry = elem.Value.(ghostEntry)
+	g.remove(entry.key)
+	return entry, true
+}
+
+// len returns the number of bindings remembered.
+func (g *ghostList) len() int {
+	return len(g.entries)
+}
+
+// keys returns the remembered keys from least to most recently remembered.
+func (g *ghostList) keys() []string {
+	keys := make([]string, 0, g.len())
+	for elem := g.order.Back(); elem != nil; elem = elem.Prev() {
+		keys = append(keys, elem.Value.(ghostEntry).key)
+	}
+	return keys
+}
+
+// reset forgets every binding.
+func (g *ghostList) reset() {
+	g.entries = make(map[string]*list.Element)
+	g.order.Init()
+	g.size = 0
+}
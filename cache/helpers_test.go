@@ -1,24 +1,31 @@
 package cache
 
 import (
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 /******************************************************************************/
 /*                                 Helpers                                    */
 /******************************************************************************/
 
-// CacheType returns a string representing the type (i.e. eviction scheme) of
-// this cache.
+// CacheType returns the name this cache's type (i.e. eviction scheme) is
+// registered under.
 func cacheType(cache Cache) string {
-	switch cache.(type) {
-	case *LRU:
-		return "LRU"
-	// case *ARC:
-	// 	return "ARC"
-	default:
-		return "cache"
+	if c, ok := cache.(*SyncCache); ok {
+		return "Synchronized(" + cacheType(c.cache) + ")"
 	}
+	names := Names()
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, name := range names {
+		if registered := registry[name](0); reflect.TypeOf(registered) == reflect.TypeOf(cache) {
+			return name
+		}
+	}
+	return "cache"
 }
 
 // Returns true iff a and b represent equal slices of bytes.
@@ -47,3 +54,51 @@ func checkCapacity(t *testing.T, cache Cache, capacity int) {
 		t.Errorf("Expected %s to have %d MaxStorage, but it had %d", cacheType(cache), capacity, max)
 	}
 }
+
+// mustNew returns a new cache of the policy registered under name, with a
+// capacity of limit bytes, failing tb if New fails.
+func mustNew(tb testing.TB, name string, limit int) Cache {
+	tb.Helper()
+	cache, err := New(name, limit)
+	if err != nil {
+		tb.Fatalf("Failed to build %s. Got %v, Expected %v", name, err, nil)
+	}
+	return cache
+}
+
+// newPolicies returns a new cache of every registered eviction policy, each
+// with a capacity of limit bytes.
+func newPolicies(t *testing.T, limit int) []notifyingCache {
+	names := Names()
+	caches := make([]notifyingCache, 0, len(names))
+	for _, name := range names {
+		caches = append(caches, mustNew(t, name, limit).(notifyingCache))
+	}
+	return caches
+}
+
+// fakeClock is a Clock that only moves forward when Advance is called, so that
+// expiration can be tested deterministically.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// newFakeClock returns a fakeClock stopped at an arbitrary fixed time.
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the time the clock is stopped at.
+func (clock *fakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+// Advance moves the clock forward by d.
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+}
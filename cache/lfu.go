@@ -0,0 +1,303 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// An lfuEntry is a binding in an LFU along with its access count
+type lfuEntry struct {
+	key    string
+	value  []byte
+	freq   int           // Number of accesses, possibly aged
+	bucket *list.Element // Element of LFU.buckets holding the entry's bucket
+	node   *list.Element // Element of the bucket's entries holding the entry
+}
+
+// An lfuBucket holds every entry with the same access count, from most to
+// least recently used
+type lfuBucket struct {
+	freq    int
+	entries list.List
+}
+
+// An LFU is a fixed-size in-memory cache with least-frequently-used eviction.
+// Entries with the same access count are evicted least recently used first.
+// Get and Set run in O(1) time, using a list of buckets of entries with equal
+// counts, in increasing order of count.
+type LFU struct {
+	cachedValues          map[string]*lfuEntry // Map containing key-entry pairings
+	buckets               list.List            // Frequency buckets in increasing order of count
+	capacity              int                  // To hold the capacity of the cache
+	currentlyUsedCapacity int                  // Currently used capacity of the cache
+	stats                 Stats                // Hits and misses for the cache
+	onEvict               EvictionCallback     // Called whenever a binding leaves the cache
+	agingInterval         int                  // Accesses between agings, or 0 to never age
+	accesses              int                  // Accesses since the last aging
+}
+
+// NewLfu returns a pointer to a new LFU with a capacity to store limit bytes
+func NewLfu(limit int) *LFU {
+	return &LFU{cachedValues: make(map[string]*lfuEntry), capacity: limit, currentlyUsedCapacity: 0, stats: Stats{}}
+}
+
+func init() {
+	Register("lfu", func(limit int) Cache { return NewLfu(limit) })
+}
+
+// SetAgingInterval makes the LFU halve every access count after each interval
+// calls to Get and Set, so that formerly popular keys eventually lose out to
+// currently popular ones. Aging takes time linear in the number of bindings.
+// An interval of 0, the default, disables aging.
+func (lfu *LFU) SetAgingInterval(interval int) {
+	lfu.agingInterval = interval
+	lfu.accesses = 0
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the LFU,
+// replacing any previously registered callback.
+func (lfu *LFU) OnEvict(fn EvictionCallback) {
+	lfu.onEvict = fn
+}
+
+// notify records in the stats that a binding left the LFU, and reports it to
+// the eviction callback, if any.
+func (lfu *LFU) notify(entry *lfuEntry, reason RemovalReason) {
+	lfu.stats.recordRemoval(reason)
+	if lfu.onEvict != nil {
+		lfu.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this LFU can store
+func (lfu *LFU) MaxStorage() int {
+	return lfu.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this LFU
+func (lfu *LFU) RemainingStorage() int {
+	return lfu.capacity - lfu.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not update the access count or the stats of the LFU.
+// ok is true if a value was found and false otherwise.
+func (lfu *LFU) Peek(key string) (value []byte, ok bool) {
+	entry, ok := lfu.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and
+// counts an access to it.
+// ok is true if a value was found and false otherwise.
+func (lfu *LFU) Get(key string) (value []byte, ok bool) {
+	defer lfu.access()
+
+	entry, ok := lfu.cachedValues[key]
+	if !ok {
+		lfu.stats.Misses += 1
+		return nil, false
+	}
+
+	lfu.increment(entry)
+	lfu.stats.Hits += 1
+	lfu.stats.HitBytes += len(entry.value)
+	return entry.value, true
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (lfu *LFU) Remove(key string) (value []byte, ok bool) {
+	entry, ok := lfu.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+
+	lfu.unlink(entry)
+	lfu.notify(entry, ReasonRemoved)
+	return entry.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. A new binding starts with a count of one; overwriting an
+// existing binding counts as an access to it and never evicts it.
+// Returns true if the binding was added successfully, else false.
+func (lfu *LFU) Set(key string, value []byte) bool {
+	defer lfu.access()
+
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > lfu.capacity {
+		lfu.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if entry, ok := lfu.cachedValues[key]; ok {
+		replaced := *entry
+		lfu.increment(entry)
+
+		// Make room without evicting the binding being overwritten
+		growth := len(value) - len(entry.value)
+		for lfu.RemainingStorage() < growth {
+			victim := lfu.victim(entry)
+			lfu.unlink(victim)
+			lfu.notify(victim, ReasonEvicted)
+		}
+
+		entry.value = value
+		lfu.currentlyUsedCapacity += growth
+		lfu.stats.Sets += 1
+		lfu.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	for lfu.RemainingStorage() < currentObjectSize {
+		if _, ok := lfu.Evict(); !ok {
+			return false
+		}
+	}
+
+	lfu.insert(&lfuEntry{key: key, value: value, freq: 1})
+	lfu.stats.Sets += 1
+	lfu.stats.MissBytes += len(value)
+	return true
+}
+
+// Empty removes every binding from the LFU.
+func (lfu *LFU) Empty() {
+	if lfu.onEvict != nil {
+		for _, entry := range lfu.cachedValues {
+			lfu.notify(entry, ReasonEmptied)
+		}
+	}
+	lfu.cachedValues = make(map[string]*lfuEntry)
+	lfu.buckets.Init()
+	lfu.currentlyUsedCapacity = 0
+}
+
+// Evict removes the least recently used of the least frequently used
+// bindings in the LFU and returns its key.
+// ok is false if the LFU was already empty.
+func (lfu *LFU) Evict() (key string, ok bool) {
+	entry := lfu.victim(nil)
+	if entry == nil {
+		return "", false
+	}
+
+	lfu.unlink(entry)
+	lfu.notify(entry, ReasonEvicted)
+	return entry.key, true
+}
+
+// victim returns the entry to evict next other than skip, or nil if there is
+// none.
+func (lfu *LFU) victim(skip *lfuEntry) *lfuEntry {
+	for elem := lfu.buckets.Front(); elem != nil; elem = elem.Next() {
+		for node := elem.Value.(*lfuBucket).entries.Back(); node != nil; node = node.Prev() {
+			if entry := node.Value.(*lfuEntry); entry != skip {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// Len returns the number of bindings in the LFU.
+func (lfu *LFU) Len() int {
+	return len(lfu.cachedValues)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (lfu *LFU) Stats() *Stats {
+	return &lfu.stats
+}
+
+// insert adds a new entry to the LFU as the most recently used entry with a
+// count of one. The entry must fit.
+func (lfu *LFU) insert(entry *lfuEntry) {
+	elem := lfu.buckets.Front()
+	if elem == nil || elem.Value.(*lfuBucket).freq != entry.freq {
+		elem = lfu.buckets.PushFront(&lfuBucket{freq: entry.freq})
+	}
+
+	entry.bucket = elem
+	entry.node = elem.Value.(*lfuBucket).entries.PushFront(entry)
+	lfu.cachedValues[entry.key] = entry
+	lfu.currentlyUsedCapacity += len(entry.key) + len(entry.value)
+}
+
+// unlink removes entry from the LFU without notifying the eviction callback.
+func (lfu *LFU) unlink(entry *lfuEntry) {
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.entries.Remove(entry.node)
+	if bucket.entries.Len() == 0 {
+		lfu.buckets.Remove(entry.bucket)
+	}
+
+	delete(lfu.cachedValues, entry.key)
+	lfu.currentlyUsedCapacity -= len(entry.key) + len(entry.value)
+}
+
+// increment moves entry into the bucket for the next higher count, as its
+// most recently used entry.
+func (lfu *LFU) increment(entry *lfuEntry) {
+	current := entry.bucket
+	bucket := current.Value.(*lfuBucket)
+	entry.freq += 1
+
+	next := current.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != entry.freq {
+		next = lfu.buckets.InsertAfter(&lfuBucket{freq: entry.freq}, current)
+	}
+
+	bucket.entries.Remove(entry.node)
+	if bucket.entries.Len() == 0 {
+		lfu.buckets.Remove(current)
+	}
+	entry.bucket = next
+	entry.node = next.Value.(*lfuBucket).entries.PushFront(entry)
+}
+
+// access counts a call to Get or Set, aging the LFU if its interval is up.
+func (lfu *LFU) access() {
+	if lfu.agingInterval <= 0 {
+		return
+	}
+	lfu.accesses += 1
+	if lfu.accesses >= lfu.agingInterval {
+		lfu.accesses = 0
+		lfu.age()
+	}
+}
+
+// age halves every access count, down to a minimum of one. Buckets whose
+// counts become equal are merged, with the entries of the formerly higher
+// count treated as more recently used.
+func (lfu *LFU) age() {
+	old := make([]*lfuBucket, 0, lfu.buckets.Len())
+	for elem := lfu.buckets.Front(); elem != nil; elem = elem.Next() {
+		old = append(old, elem.Value.(*lfuBucket))
+	}
+
+	lfu.buckets.Init()
+	for _, bucket := range old {
+		freq := bucket.freq / 2
+		if freq < 1 {
+			freq = 1
+		}
+
+		last := lfu.buckets.Back()
+		if last == nil || last.Value.(*lfuBucket).freq != freq {
+			last = lfu.buckets.PushBack(&lfuBucket{freq: freq})
+		}
+		target := last.Value.(*lfuBucket)
+		for node := bucket.entries.Back(); node != nil; node = node.Prev() {
+			entry := node.Value.(*lfuEntry)
+			entry.freq = freq
+			entry.bucket = last
+			entry.node = target.entries.PushFront(entry)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/******************************************************************************
+ * lfu_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for lfu.go. The Cache contract LFU shares with
+ *    every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that the least frequently used binding is evicted, and the least
+// recently used of those on a tie
+func TestEvictionOrderLfu(t *testing.T) {
+	lfu := NewLfu(30)
+	lfu.Set("____0", []byte("____0"))
+	lfu.Set("____1", []byte("____1"))
+	lfu.Set("____2", []byte("____2"))
+	lfu.Get("____0")
+	lfu.Get("____0")
+	lfu.Get("____2")
+	lfu.Get("____1")
+
+	// Counts are now ____0: 3, ____1: 2, ____2: 2, with ____2 used less recently
+	expected := []string{"____2", "____1", "____0"}
+	for _, key := range expected {
+		evicted, ok := lfu.Evict()
+		if !ok || evicted != key {
+			t.Errorf("Evicted wrong binding. Got %v, Expected %v", evicted, key)
+			t.FailNow()
+		}
+	}
+	if _, ok := lfu.Evict(); ok || lfu.Len() != 0 || lfu.RemainingStorage() != 30 {
+		t.Errorf("Evict on empty LFU wrong. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+}
+
+// Check that popular bindings survive a burst of one-off keys
+func TestBurstLfu(t *testing.T) {
+	lfu := NewLfu(50)
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("hot_%d", i)
+		lfu.Set(key, []byte(key))
+		lfu.Get(key)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("___%02d", i)
+		lfu.Set(key, []byte(key))
+	}
+
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("hot_%d", i)
+		if _, ok := lfu.Peek(key); !ok {
+			t.Errorf("Burst evicted popular binding %s. Got %v, Expected %v", key, ok, true)
+			t.FailNow()
+		}
+	}
+	if _, ok := lfu.Peek("___99"); !ok {
+		t.Errorf("Newest one-off binding missing. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+}
+
+// Check that overwriting a binding counts as an access and that growing it
+// evicts other bindings but never itself
+func TestOverwriteLfu(t *testing.T) {
+	lfu := NewLfu(30)
+	lfu.Set("____0", []byte("____0"))
+	lfu.Set("____1", []byte("____1"))
+	lfu.Set("____2", []byte("____2"))
+
+	// ____0 is the least recently used of the least frequently used, but the
+	// overwrite makes it the most frequently used
+	if ok := lfu.Set("____0", []byte("__________0")); !ok {
+		t.Errorf("Failed to overwrite binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if value, ok := lfu.Peek("____0"); !ok || !bytesEqual(value, []byte("__________0")) {
+		t.Errorf("Overwrite lost the binding. Got %v, Expected %v", value, []byte("__________0"))
+		t.FailNow()
+	}
+	if _, ok := lfu.Peek("____1"); ok {
+		t.Errorf("Growing overwrite did not evict ____1. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if lfu.RemainingStorage() != 4 {
+		t.Errorf("RemainingStorage wrong after overwrite. Got %v, Expected %v", lfu.RemainingStorage(), 4)
+		t.FailNow()
+	}
+
+	if evicted, _ := lfu.Evict(); evicted != "____2" {
+		t.Errorf("Overwrite did not count as an access. Got %v, Expected %v", evicted, "____2")
+		t.FailNow()
+	}
+
+	// A binding that fills the whole LFU can still be overwritten
+	lfu.Set("____0", []byte("_________________________"))
+	if lfu.Len() != 1 || lfu.RemainingStorage() != 0 {
+		t.Errorf("Overwrite filling the LFU wrong. Got %v bindings and %v bytes left, Expected %v and %v", lfu.Len(), lfu.RemainingStorage(), 1, 0)
+		t.FailNow()
+	}
+}
+
+// Check that aging lets a formerly popular binding be evicted
+func TestAgingLfu(t *testing.T) {
+	for _, aging := range []bool{false, true} {
+		lfu := NewLfu(30)
+		if aging {
+			lfu.SetAgingInterval(10)
+		}
+
+		// ____0 becomes popular, then falls out of use
+		lfu.Set("____0", []byte("____0"))
+		for i := 0; i < 20; i++ {
+			lfu.Get("____0")
+		}
+		lfu.Set("____1", []byte("____1"))
+		lfu.Set("____2", []byte("____2"))
+		for i := 0; i < 8; i++ {
+			lfu.Get("____1")
+			lfu.Get("____2")
+		}
+		lfu.Set("____3", []byte("____3"))
+		lfu.Get("____3")
+		lfu.Set("____4", []byte("____4"))
+
+		_, ok := lfu.Peek("____0")
+		if ok == aging {
+			t.Errorf("Aging %v: wrong binding evicted. Got ____0 present %v, Expected %v", aging, ok, !aging)
+			t.FailNow()
+		}
+		if lfu.Len() != 3 {
+			t.Errorf("Len wrong after aging. Got %v, Expected %v", lfu.Len(), 3)
+			t.FailNow()
+		}
+	}
+}
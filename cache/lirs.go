@@ -0,0 +1,408 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// DefaultHirFraction is the share of a LIRS's capacity set aside for resident
+// HIR bindings, as recommended by the LIRS paper.
+const DefaultHirFraction = 0.01
+
+// An lirsEntry is a binding in a LIRS, or the memory of one that has left
+type lirsEntry struct {
+	key      string
+	value    []byte
+	lir      bool          // Whether the binding has a low inter-reference recency
+	resident bool          // Whether the value is still held by the LIRS
+	size     int           // len(key) + len(value)
+	inStack  *list.Element // Element of LIRS.stack holding the entry, if any
+	inQueue  *list.Element // Element of LIRS.queue holding the entry, if any
+}
+
+// A LIRS is a fixed-size in-memory cache using Low Inter-reference Recency Set
+// replacement (Jiang and Zhang, SIGMETRICS '02). Instead of the recency of a
+// binding, it ranks bindings by the recency at which they were last reused.
+// Bindings reused at a short distance form the LIR set, which takes most of
+// the capacity and is never evicted directly; the other resident bindings are
+// HIR and sit in a FIFO queue from which victims are taken. The recency stack
+// also remembers recently evicted HIR bindings, so that a key reused soon
+// after its eviction joins the LIR set when it is set again. A loop slightly
+// larger than the cache therefore keeps most of its keys resident, where LRU
+// evicts each one just before it is needed.
+//
+// All sizes are in bytes. The non-resident bindings remembered by the stack
+// are limited to the capacity of the cache in total, oldest forgotten first.
+type LIRS struct {
+	cachedValues map[string]*lirsEntry // Map containing key-entry pairings, resident or not
+	stack        list.List             // Recency stack, most recent first, with an LIR entry at the back
+	queue        list.List             // Resident HIR entries, next victim first
+	nonResident  *ghostList            // Non-resident entries in the stack, in order of eviction
+
+	capacity              int // To hold the capacity of the cache
+	lirCapacity           int // Bytes the LIR set may hold
+	lirSize               int // Bytes held by the LIR set
+	currentlyUsedCapacity int // Currently used capacity of the cache
+	residents             int // Number of resident bindings
+
+	stats   Stats            // Hits and misses for the cache
+	onEvict EvictionCallback // Called whenever a binding leaves the cache
+}
+
+// NewLirs returns a pointer to a new LIRS with a capacity to store limit bytes,
+// setting aside DefaultHirFraction of it for resident HIR bindings.
+func NewLirs(limit int) *LIRS {
+	lirs := &LIRS{cachedValues: make(map[string]*lirsEntry), nonResident: newGhostList(), capacity: limit, stats: Stats{}}
+	lirs.SetHirFraction(DefaultHirFraction)
+	return lirs
+}
+
+func init() {
+	Register("lirs", func(limit int) Cache { return NewLirs(limit) })
+}
+
+// SetHirFraction sets the share of the capacity set aside for resident HIR
+// bindings. The LIR set may hold the rest; it gives up bindings to the HIR
+// queue if it already holds more. fraction is clamped to [0, 1].
+func (lirs *LIRS) SetHirFraction(fraction float64) {
+	lirs.lirCapacity = lirs.capacity - int(clampFraction(fraction)*float64(lirs.capacity))
+	lirs.shrinkLir(nil)
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the LIRS,
+// replacing any previously registered callback. A binding that stays in the
+// stack as non-resident is reported as evicted, since its value is dropped.
+func (lirs *LIRS) OnEvict(fn EvictionCallback) {
+	lirs.onEvict = fn
+}
+
+// notify records in the stats that a binding left the LIRS, and reports it to
+// the eviction callback, if any.
+func (lirs *LIRS) notify(entry *lirsEntry, reason RemovalReason) {
+	lirs.stats.recordRemoval(reason)
+	if lirs.onEvict != nil {
+		lirs.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this LIRS can store
+func (lirs *LIRS) MaxStorage() int {
+	return lirs.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this LIRS
+func (lirs *LIRS) RemainingStorage() int {
+	return lirs.capacity - lirs.currentlyUsedCapacity
+}
+
+// resident returns the entry for key if its binding is resident, or nil.
+func (lirs *LIRS) resident(key string) *lirsEntry {
+	entry, ok := lirs.cachedValues[key]
+	if !ok || !entry.resident {
+		return nil
+	}
+	return entry
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not count as a reference or update the stats.
+// ok is true if a value was found and false otherwise.
+func (lirs *LIRS) Peek(key string) (value []byte, ok bool) {
+	entry := lirs.resident(key)
+	if entry == nil {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and
+// counts a reference to it.
+// ok is true if a value was found and false otherwise.
+func (lirs *LIRS) Get(key string) (value []byte, ok bool) {
+	entry := lirs.resident(key)
+	if entry == nil {
+		lirs.stats.Misses += 1
+		return nil, false
+	}
+
+	lirs.reference(entry)
+	lirs.stats.Hits += 1
+	lirs.stats.HitBytes += len(entry.value)
+	return entry.value, true
+}
+
+// Remove removes and returns the value associated with the given key, if it
+// exists. A non-resident key is forgotten, but is not a binding.
+// ok is true if a value was found and false otherwise
+func (lirs *LIRS) Remove(key string) (value []byte, ok bool) {
+	entry, ok := lirs.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+
+	lirs.forget(entry)
+	if !entry.resident {
+		return nil, false
+	}
+	lirs.notify(entry, ReasonRemoved)
+	return entry.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. Overwriting a binding counts as a reference to it and never
+// evicts it. Returns true if the binding was added successfully, else false.
+func (lirs *LIRS) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > lirs.capacity {
+		lirs.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if entry := lirs.resident(key); entry != nil {
+		replaced := *entry
+		lirs.reference(entry)
+
+		growth := currentObjectSize - entry.size
+		entry.value = value
+		lirs.resize(entry, currentObjectSize)
+		for growth > 0 && lirs.RemainingStorage() < 0 {
+			lirs.evict(entry)
+		}
+		lirs.shrinkLir(entry)
+
+		lirs.stats.Sets += 1
+		lirs.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	for lirs.RemainingStorage() < currentObjectSize {
+		lirs.evict(nil)
+	}
+
+	entry, remembered := lirs.cachedValues[key]
+	switch {
+	case remembered:
+		// Reused while still in the stack: its inter-reference recency
+		// is lower than that of the oldest LIR binding
+		lirs.nonResident.remove(key)
+		lirs.stack.Remove(entry.inStack)
+		entry.value = value
+		entry.size = 0
+		entry.resident = true
+		lirs.resize(entry, currentObjectSize)
+		lirs.residents += 1
+		lirs.promote(entry)
+		lirs.shrinkLir(entry)
+
+	case lirs.lirSize+currentObjectSize <= lirs.lirCapacity:
+		// The LIR set is still filling up
+		entry = &lirsEntry{key: key, value: value, lir: true, resident: true}
+		lirs.insert(entry, currentObjectSize)
+		entry.inStack = lirs.stack.PushFront(entry)
+
+	default:
+		entry = &lirsEntry{key: key, value: value, resident: true}
+		lirs.insert(entry, currentObjectSize)
+		entry.inStack = lirs.stack.PushFront(entry)
+		entry.inQueue = lirs.queue.PushBack(entry)
+		lirs.prune()
+	}
+
+	lirs.stats.Sets += 1
+	lirs.stats.MissBytes += len(value)
+	return true
+}
+
+// reference moves a resident entry to the top of the stack. A HIR entry still
+// in the stack joins the LIR set; one that had left the stack stays HIR and
+// goes to the back of the queue.
+func (lirs *LIRS) reference(entry *lirsEntry) {
+	if entry.lir {
+		lirs.stack.MoveToFront(entry.inStack)
+		lirs.prune()
+		return
+	}
+
+	if entry.inStack != nil {
+		lirs.stack.Remove(entry.inStack)
+		lirs.queue.Remove(entry.inQueue)
+		entry.inQueue = nil
+		lirs.promote(entry)
+		lirs.shrinkLir(entry)
+		return
+	}
+
+	entry.inStack = lirs.stack.PushFront(entry)
+	lirs.queue.MoveToBack(entry.inQueue)
+	lirs.prune()
+}
+
+// promote makes a resident entry, which must be in neither the stack nor the
+// queue, an LIR entry at the top of the stack.
+func (lirs *LIRS) promote(entry *lirsEntry) {
+	entry.lir = true
+	entry.inStack = lirs.stack.PushFront(entry)
+	lirs.lirSize += entry.size
+}
+
+// shrinkLir demotes the oldest LIR entries to the back of the queue until the
+// LIR set fits in its capacity, keeping at least skip.
+func (lirs *LIRS) shrinkLir(skip *lirsEntry) {
+	for lirs.lirSize > lirs.lirCapacity {
+		bottom := lirs.stack.Back()
+		if bottom == nil || bottom.Value.(*lirsEntry) == skip {
+			return
+		}
+		lirs.demote(bottom.Value.(*lirsEntry))
+	}
+}
+
+// demote moves the LIR entry at the bottom of the stack to the back of the
+// queue as a resident HIR entry.
+func (lirs *LIRS) demote(entry *lirsEntry) {
+	lirs.stack.Remove(entry.inStack)
+	entry.inStack = nil
+	entry.lir = false
+	lirs.lirSize -= entry.size
+	entry.inQueue = lirs.queue.PushBack(entry)
+	lirs.prune()
+}
+
+// prune removes HIR entries from the bottom of the stack, so that its oldest
+// entry is LIR. Non-resident entries pruned from the stack are forgotten.
+func (lirs *LIRS) prune() {
+	for elem := lirs.stack.Back(); elem != nil; elem = lirs.stack.Back() {
+		entry := elem.Value.(*lirsEntry)
+		if entry.lir {
+			return
+		}
+		lirs.stack.Remove(elem)
+		entry.inStack = nil
+		if !entry.resident {
+			lirs.nonResident.remove(entry.key)
+			delete(lirs.cachedValues, entry.key)
+		}
+	}
+}
+
+// evict evicts the resident HIR entry at the front of the queue other than
+// skip, demoting the oldest LIR entry first if there is none, and returns it.
+// An entry still in the stack stays there as non-resident. There must be a
+// resident entry other than skip.
+func (lirs *LIRS) evict(skip *lirsEntry) *lirsEntry {
+	elem := lirs.queue.Front()
+	if elem != nil && elem.Value.(*lirsEntry) == skip {
+		elem = elem.Next()
+	}
+	if elem == nil {
+		lirs.demote(lirs.stack.Back().Value.(*lirsEntry))
+		elem = lirs.queue.Back()
+	}
+
+	entry := elem.Value.(*lirsEntry)
+	lirs.queue.Remove(elem)
+	entry.inQueue = nil
+	lirs.currentlyUsedCapacity -= entry.size
+	lirs.residents -= 1
+	entry.resident = false
+	if entry.inStack == nil {
+		delete(lirs.cachedValues, entry.key)
+	} else {
+		lirs.nonResident.push(entry.key, entry.size)
+		for lirs.nonResident.size > lirs.capacity {
+			lirs.forgetOldest()
+		}
+	}
+
+	lirs.notify(entry, ReasonEvicted)
+	entry.value = nil
+	return entry
+}
+
+// forgetOldest forgets the non-resident entry evicted the longest ago.
+func (lirs *LIRS) forgetOldest() {
+	ghost, _ := lirs.nonResident.popOldest()
+	entry := lirs.cachedValues[ghost.key]
+	lirs.stack.Remove(entry.inStack)
+	delete(lirs.cachedValues, ghost.key)
+}
+
+// insert adds a new resident entry of the given size to the map, without
+// placing it in the stack or the queue.
+func (lirs *LIRS) insert(entry *lirsEntry, size int) {
+	lirs.cachedValues[entry.key] = entry
+	lirs.resize(entry, size)
+	lirs.residents += 1
+}
+
+// resize sets the size of a resident entry, keeping the totals in step.
+func (lirs *LIRS) resize(entry *lirsEntry, size int) {
+	lirs.currentlyUsedCapacity += size - entry.size
+	if entry.lir {
+		lirs.lirSize += size - entry.size
+	}
+	entry.size = size
+}
+
+// forget removes entry from the LIRS without notifying the eviction callback.
+func (lirs *LIRS) forget(entry *lirsEntry) {
+	if entry.inQueue != nil {
+		lirs.queue.Remove(entry.inQueue)
+		entry.inQueue = nil
+	}
+	if entry.inStack != nil {
+		lirs.stack.Remove(entry.inStack)
+		entry.inStack = nil
+	}
+	delete(lirs.cachedValues, entry.key)
+
+	if !entry.resident {
+		lirs.nonResident.remove(entry.key)
+		return
+	}
+	lirs.currentlyUsedCapacity -= entry.size
+	lirs.residents -= 1
+	if entry.lir {
+		lirs.lirSize -= entry.size
+	}
+	lirs.prune()
+}
+
+// Empty removes every binding from the LIRS and forgets its non-resident
+// entries.
+func (lirs *LIRS) Empty() {
+	if lirs.onEvict != nil {
+		for _, entry := range lirs.cachedValues {
+			if entry.resident {
+				lirs.notify(entry, ReasonEmptied)
+			}
+		}
+	}
+	lirs.cachedValues = make(map[string]*lirsEntry)
+	lirs.stack.Init()
+	lirs.queue.Init()
+	lirs.nonResident.reset()
+	lirs.lirSize = 0
+	lirs.currentlyUsedCapacity = 0
+	lirs.residents = 0
+}
+
+// Evict removes the resident HIR binding at the front of the queue, or the
+// oldest LIR binding if there is none, and returns its key.
+// ok is false if the LIRS was already empty.
+func (lirs *LIRS) Evict() (key string, ok bool) {
+	if lirs.residents == 0 {
+		return "", false
+	}
+	return lirs.evict(nil).key, true
+}
+
+// Len returns the number of bindings in the LIRS.
+func (lirs *LIRS) Len() int {
+	return lirs.residents
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (lirs *LIRS) Stats() *Stats {
+	return &lirs.stats
+}
@@ -0,0 +1,188 @@
+/******************************************************************************
+ * lirs_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for lirs.go. The Cache contract LIRS shares with
+ *    every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// checkLir fails t unless the binding for key is resident in lirs and is in the
+// LIR set iff lir is true.
+func checkLir(t *testing.T, lirs *LIRS, key string, lir bool) {
+	entry := lirs.resident(key)
+	if entry == nil {
+		t.Errorf("Binding %s not resident. Got %v, Expected %v", key, false, true)
+		t.FailNow()
+	}
+	if entry.lir != lir {
+		t.Errorf("Binding %s has wrong status. Got LIR %v, Expected LIR %v", key, entry.lir, lir)
+		t.FailNow()
+	}
+}
+
+// loopHitRatio replays a loop over n keys of size bytes in total each, rounds
+// times, against cache, and returns the hit ratio of the rounds after the
+// first.
+func loopHitRatio(cache Cache, n int, rounds int) float64 {
+	hits := 0
+	for round := 0; round < rounds; round++ {
+		for i := 0; i < n; i++ {
+			key := fmt.Sprintf("%04d", i)
+			if _, ok := cache.Get(key); ok {
+				if round > 0 {
+					hits += 1
+				}
+			} else {
+				cache.Set(key, []byte("______"))
+			}
+		}
+	}
+	return float64(hits) / float64(n*(rounds-1))
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check the stack and queue after a hand-worked sequence: a binding set again
+// while non-resident joins the LIR set, pushing the oldest LIR binding into
+// the queue, and a queued binding that has left the stack is forgotten when
+// evicted
+func TestEvictionOrderLirs(t *testing.T) {
+	lirs := NewLirs(40)
+	lirs.SetHirFraction(0.25)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("____%d", i)
+		lirs.Set(key, []byte(key))
+	}
+	checkLir(t, lirs, "____0", true)
+	checkLir(t, lirs, "____2", true)
+	checkLir(t, lirs, "____4", false)
+	if _, ok := lirs.Peek("____3"); ok || lirs.cachedValues["____3"] == nil {
+		t.Errorf("Evicted HIR binding not kept as non-resident. Got %v, Expected %v", lirs.cachedValues["____3"], "____3")
+		t.FailNow()
+	}
+
+	lirs.Set("____3", []byte("____3"))
+	checkLir(t, lirs, "____3", true)
+	checkLir(t, lirs, "____0", false)
+	if _, ok := lirs.Peek("____4"); ok {
+		t.Errorf("Wrong binding evicted. Got %v, Expected %v", "____0", "____4")
+		t.FailNow()
+	}
+
+	lirs.Get("____1")
+	lirs.Set("____5", []byte("____5"))
+	if _, ok := lirs.cachedValues["____0"]; ok {
+		t.Errorf("Binding evicted outside the stack still remembered. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if _, ok := lirs.cachedValues["____4"]; !ok {
+		t.Errorf("Non-resident binding in the stack forgotten. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if lirs.Stats().Evictions != 3 || lirs.Len() != 4 {
+		t.Errorf("Wrong evictions or length. Got %v, %v, Expected %v, %v", lirs.Stats().Evictions, lirs.Len(), 3, 4)
+		t.FailNow()
+	}
+}
+
+// Check that a loop slightly larger than the cache keeps a high hit ratio,
+// where LRU misses on every access
+func TestLoopLirs(t *testing.T) {
+	capacity := 1000
+	lruRatio := loopHitRatio(NewLru(capacity), 110, 20)
+	lirsRatio := loopHitRatio(NewLirs(capacity), 110, 20)
+
+	if lruRatio != 0 {
+		t.Errorf("LRU hit on a loop larger than the cache. Got %v, Expected %v", lruRatio, 0)
+		t.FailNow()
+	}
+	if lirsRatio < 0.5 {
+		t.Errorf("LIRS lost the loop. Got %v, Expected at least %v", lirsRatio, 0.5)
+		t.FailNow()
+	}
+}
+
+// Check that the non-resident bindings remembered stay within the capacity
+// however many distinct keys are set
+func TestMetadataLirs(t *testing.T) {
+	capacity := 200
+	lirs := NewLirs(capacity)
+	lirs.SetHirFraction(0.1)
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("%04d", (i*7)%1000+(i/500))
+		lirs.Set(key, []byte("______"))
+		if i%5 == 0 {
+			lirs.Get(fmt.Sprintf("%04d", i%50))
+		}
+
+		if lirs.nonResident.size > capacity {
+			t.Errorf("Non-resident bindings exceed the capacity. Got %v, Expected at most %v", lirs.nonResident.size, capacity)
+			t.FailNow()
+		}
+		if len(lirs.cachedValues) != lirs.Len()+lirs.nonResident.len() {
+			t.Errorf("Map holds unaccounted entries. Got %v, Expected %v", len(lirs.cachedValues), lirs.Len()+lirs.nonResident.len())
+			t.FailNow()
+		}
+		if lirs.RemainingStorage() < 0 || lirs.lirSize > lirs.lirCapacity {
+			t.Errorf("Exceeded capacity. Got %v, %v, Expected %v, %v", lirs.RemainingStorage(), lirs.lirSize, 0, lirs.lirCapacity)
+			t.FailNow()
+		}
+		if bottom := lirs.stack.Back(); bottom != nil && !bottom.Value.(*lirsEntry).lir {
+			t.Errorf("Stack not pruned. Got %v, Expected %v", bottom.Value.(*lirsEntry).key, "an LIR binding")
+			t.FailNow()
+		}
+	}
+}
+
+// Check that HIR fractions outside [0, 1] are clamped to it
+func TestHirFractionClampedLirs(t *testing.T) {
+	lirs := NewLirs(40)
+	for _, c := range []struct {
+		fraction    float64
+		lirCapacity int
+	}{{-0.5, 40}, {1.5, 0}} {
+		lirs.SetHirFraction(c.fraction)
+		if lirs.lirCapacity != c.lirCapacity {
+			t.Errorf("HIR fraction %v not clamped. Got %v, Expected %v", c.fraction, lirs.lirCapacity, c.lirCapacity)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that a binding growing past the free space is not evicted to make room
+// for itself, whether it is LIR or HIR
+func TestOverwriteLirs(t *testing.T) {
+	lirs := NewLirs(40)
+	lirs.SetHirFraction(0.25)
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("____%d", i)
+		lirs.Set(key, []byte(key))
+	}
+
+	for _, key := range []string{"____3", "____0"} {
+		value := []byte("_______________")
+		lirs.Set(key, value)
+		if got, ok := lirs.Peek(key); !ok || !bytesEqual(got, value) {
+			t.Errorf("Overwrite of %s lost the binding. Got %v, Expected %v", key, got, value)
+			t.FailNow()
+		}
+		if lirs.RemainingStorage() < 0 || lirs.lirSize > lirs.lirCapacity {
+			t.Errorf("Overwrite exceeded capacity. Got %v, %v, Expected %v, %v", lirs.RemainingStorage(), lirs.lirSize, 0, lirs.lirCapacity)
+			t.FailNow()
+		}
+	}
+}
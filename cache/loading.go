@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLoaderPanicked is returned to callers that were waiting on a loader call
+// that panicked. The caller that made the call sees the panic itself, if it is
+// still waiting.
+var ErrLoaderPanicked = errors.New("cache: loader panicked")
+
+// A Loader fetches the value for a key that missed in the cache
+type Loader func(ctx context.Context, key string) ([]byte, error)
+
+// loadCall is a loader call in flight, shared by every caller that missed on
+// the same key while it runs.
+type loadCall struct {
+	done      chan struct{} // Closed once value and err are set
+	value     []byte
+	err       error
+	panicked  bool        // Whether the loader panicked
+	recovered interface{} // The value the loader panicked with
+}
+
+// A detachedContext carries the values of its parent but is never cancelled
+// and has no deadline, so that a loader call shared by many callers does not
+// fail because the caller that started it gave up.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}                   { return nil }
+func (detachedContext) Err() error                              { return nil }
+
+// negativeEntry is a cached loader error.
+type negativeEntry struct {
+	err     error
+	expires time.Time
+}
+
+// A LoadingCache is a read-through cache: on a miss it calls a Loader, stores
+// the result and returns it. Concurrent misses on the same key share a single
+// loader call.
+type LoadingCache struct {
+	Cache // The underlying cache, made safe for concurrent use
+
+	mu          sync.Mutex               // Guards every field below
+	calls       map[string]*loadCall     // Loader calls in flight, by key
+	negative    map[string]negativeEntry // Cached loader errors, by key
+	negativeTTL time.Duration            // How long loader errors are cached, 0 to never cache them
+	clock       Clock                    // Source of the current time for load latency and negative caching
+	loadStats   Stats                    // Loader successes, failures and latency
+}
+
+// NewLoadingCache returns a pointer to a new LoadingCache that stores loaded
+// values in c. c is wrapped with Synchronized and must not be used directly
+// afterwards.
+func NewLoadingCache(c Cache) *LoadingCache {
+	return &LoadingCache{
+		Cache:    Synchronized(c),
+		calls:    make(map[string]*loadCall),
+		negative: make(map[string]negativeEntry),
+		clock:    systemClock{},
+	}
+}
+
+// SetNegativeTTL makes the LoadingCache remember loader errors for ttl, during
+// which GetOrLoad returns the cached error instead of calling the loader
+// again. A ttl <= 0, the default, disables negative caching.
+func (lc *LoadingCache) SetNegativeTTL(ttl time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.negativeTTL = ttl
+}
+
+// SetClock replaces the clock used to time loader calls and expire cached
+// errors. It does not change the clock of the underlying cache.
+func (lc *LoadingCache) SetClock(clock Clock) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.clock = clock
+}
+
+// GetOrLoad returns the value associated with the given key, calling loader
+// to fetch and store it if it is not in the cache. If another caller is
+// already loading the same key, GetOrLoad waits for that call instead of
+// starting a new one. Loader errors are returned to every waiting caller and
+// are not cached unless a negative TTL has been set. The loader runs with a
+// context that carries the values of ctx but is not cancelled with it. If ctx
+// is done before the value is available, GetOrLoad returns ctx.Err(), and the
+// load goes on for the other callers and stores its value.
+func (lc *LoadingCache) GetOrLoad(ctx context.Context, key string, loader Loader) ([]byte, error) {
+	if value, ok := lc.Cache.Get(key); ok {
+		return value, nil
+	}
+
+	lc.mu.Lock()
+	if entry, ok := lc.negative[key]; ok {
+		if lc.clock.Now().Before(entry.expires) {
+			lc.mu.Unlock()
+			return nil, entry.err
+		}
+		delete(lc.negative, key)
+	}
+
+	if call, ok := lc.calls[key]; ok {
+		lc.mu.Unlock()
+		return wait(ctx, call, false)
+	}
+
+	call := &loadCall{done: make(chan struct{})}
+	lc.calls[key] = call
+	lc.mu.Unlock()
+
+	go lc.load(detachedContext{ctx}, key, loader, call)
+	return wait(ctx, call, true)
+}
+
+// wait returns the result of call once it is done, or ctx.Err() if ctx is
+// done first. If the loader panicked, the caller that started call panics
+// with the same value.
+func wait(ctx context.Context, call *loadCall, started bool) ([]byte, error) {
+	select {
+	case <-call.done:
+		if call.panicked && started {
+			panic(call.recovered)
+		}
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load runs loader for key on behalf of call, stores the result and wakes up
+// every caller waiting on call.
+func (lc *LoadingCache) load(ctx context.Context, key string, loader Loader, call *loadCall) {
+	lc.mu.Lock()
+	clock := lc.clock
+	lc.mu.Unlock()
+
+	start := clock.Now()
+	finished := false
+	defer func() {
+		elapsed := clock.Now().Sub(start)
+		if !finished {
+			call.panicked, call.recovered = true, recover()
+			call.err = ErrLoaderPanicked
+		}
+
+		lc.mu.Lock()
+		delete(lc.calls, key)
+		lc.loadStats.TotalLoadTime += elapsed
+		if call.err != nil {
+			lc.loadStats.LoadFailures += 1
+			if lc.negativeTTL > 0 {
+				lc.negative[key] = negativeEntry{err: call.err, expires: clock.Now().Add(lc.negativeTTL)}
+			}
+		} else {
+			lc.loadStats.LoadSuccesses += 1
+		}
+		lc.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	call.value, call.err = loader(ctx, key)
+	finished = true
+
+	if call.err == nil {
+		lc.Cache.Set(key, call.value)
+	}
+}
+
+// Remove removes and returns the value associated with the given key, if it
+// exists, and forgets any cached loader error for it.
+func (lc *LoadingCache) Remove(key string) (value []byte, ok bool) {
+	lc.mu.Lock()
+	delete(lc.negative, key)
+	lc.mu.Unlock()
+	return lc.Cache.Remove(key)
+}
+
+// Empty removes every binding and every cached loader error.
+func (lc *LoadingCache) Empty() {
+	lc.mu.Lock()
+	lc.negative = make(map[string]negativeEntry)
+	lc.mu.Unlock()
+	lc.Cache.Empty()
+}
+
+// Stats returns a copy of the underlying cache's statistics, together with
+// the number of loader successes and failures and the total time spent in
+// loader calls.
+func (lc *LoadingCache) Stats() *Stats {
+	stats := *lc.Cache.Stats()
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	stats.add(&lc.loadStats)
+	return &stats
+}
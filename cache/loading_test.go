@@ -0,0 +1,311 @@
+/******************************************************************************
+ * loading_test.go
+ * Author:
+ * Usage:    `go test -race`  or  `go test -race -v`
+ * Description:
+ *    A unit testing suite for loading.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                                Constants                                   */
+/******************************************************************************/
+
+var errBackend = errors.New("backend unavailable")
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a miss calls the loader and stores its value, and a hit does not
+func TestLoadOnMissLoading(t *testing.T) {
+	lc := NewLoadingCache(NewLru(1024))
+	calls := 0
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		calls++
+		return []byte("value-" + key), nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := lc.GetOrLoad(context.Background(), "key", loader)
+		if err != nil {
+			t.Errorf("GetOrLoad returned an error. Got %v, Expected %v", err, nil)
+			t.FailNow()
+		}
+		if !bytesEqual(value, []byte("value-key")) {
+			t.Errorf("GetOrLoad returned wrong value. Got %v, Expected %v", value, []byte("value-key"))
+			t.FailNow()
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Loader called wrong number of times. Got %v, Expected %v", calls, 1)
+		t.FailNow()
+	}
+	if value, ok := lc.Peek("key"); !ok || !bytesEqual(value, []byte("value-key")) {
+		t.Errorf("Loaded value was not stored. Got %v, Expected %v", value, []byte("value-key"))
+		t.FailNow()
+	}
+}
+
+// Check that concurrent misses on the same key share a single loader call
+func TestCoalescingLoading(t *testing.T) {
+	lc := NewLoadingCache(NewArc(1024))
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var started, wg sync.WaitGroup
+	for g := 0; g < syncGoroutines; g++ {
+		started.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			value, err := lc.GetOrLoad(context.Background(), "hot", loader)
+			if err != nil || !bytesEqual(value, []byte("value")) {
+				t.Errorf("GetOrLoad returned wrong result. Got %v, %v, Expected %v, %v", value, err, []byte("value"), nil)
+			}
+		}()
+	}
+	started.Wait()
+
+	// Give every goroutine a chance to miss before the load finishes
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Loader called wrong number of times. Got %v, Expected %v", calls, 1)
+		t.FailNow()
+	}
+	if lc.Stats().LoadSuccesses != 1 {
+		t.Errorf("LoadSuccesses wrong. Got %v, Expected %v", lc.Stats().LoadSuccesses, 1)
+		t.FailNow()
+	}
+}
+
+// Check that loader errors are returned and, by default, not cached
+func TestErrorLoading(t *testing.T) {
+	lc := NewLoadingCache(NewLru(1024))
+	calls := 0
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		calls++
+		return nil, errBackend
+	}
+
+	for i := 0; i < 2; i++ {
+		value, err := lc.GetOrLoad(context.Background(), "key", loader)
+		if err != errBackend || value != nil {
+			t.Errorf("GetOrLoad returned wrong result. Got %v, %v, Expected %v, %v", value, err, nil, errBackend)
+			t.FailNow()
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("Loader error was cached. Got %v calls, Expected %v", calls, 2)
+		t.FailNow()
+	}
+	if lc.Len() != 0 {
+		t.Errorf("Failed load stored a binding. Got %v, Expected %v", lc.Len(), 0)
+		t.FailNow()
+	}
+	if lc.Stats().LoadFailures != 2 {
+		t.Errorf("LoadFailures wrong. Got %v, Expected %v", lc.Stats().LoadFailures, 2)
+		t.FailNow()
+	}
+}
+
+// Check that loader errors are cached for the negative TTL when it is set
+func TestNegativeCachingLoading(t *testing.T) {
+	clock := newFakeClock()
+	lc := NewLoadingCache(NewLru(1024))
+	lc.SetClock(clock)
+	lc.SetNegativeTTL(time.Minute)
+
+	calls := 0
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		calls++
+		return nil, errBackend
+	}
+
+	lc.GetOrLoad(context.Background(), "key", loader)
+	_, err := lc.GetOrLoad(context.Background(), "key", loader)
+	if err != errBackend {
+		t.Errorf("Cached error not returned. Got %v, Expected %v", err, errBackend)
+		t.FailNow()
+	}
+	if calls != 1 {
+		t.Errorf("Loader called while error was cached. Got %v, Expected %v", calls, 1)
+		t.FailNow()
+	}
+
+	clock.Advance(time.Minute)
+	lc.GetOrLoad(context.Background(), "key", loader)
+	if calls != 2 {
+		t.Errorf("Loader not called after negative TTL. Got %v, Expected %v", calls, 2)
+		t.FailNow()
+	}
+
+	lc.Remove("key")
+	lc.GetOrLoad(context.Background(), "key", loader)
+	if calls != 3 {
+		t.Errorf("Remove did not forget cached error. Got %v, Expected %v", calls, 3)
+		t.FailNow()
+	}
+}
+
+// Check that loader latency is recorded in Stats
+func TestLatencyLoading(t *testing.T) {
+	clock := newFakeClock()
+	lc := NewLoadingCache(NewLru(1024))
+	lc.SetClock(clock)
+
+	lc.GetOrLoad(context.Background(), "fast", func(ctx context.Context, key string) ([]byte, error) {
+		clock.Advance(10 * time.Millisecond)
+		return []byte("value"), nil
+	})
+	lc.GetOrLoad(context.Background(), "slow", func(ctx context.Context, key string) ([]byte, error) {
+		clock.Advance(time.Second)
+		return nil, errBackend
+	})
+
+	stats := lc.Stats()
+	if stats.TotalLoadTime != time.Second+10*time.Millisecond {
+		t.Errorf("TotalLoadTime wrong. Got %v, Expected %v", stats.TotalLoadTime, time.Second+10*time.Millisecond)
+		t.FailNow()
+	}
+	if stats.LoadSuccesses != 1 || stats.LoadFailures != 1 {
+		t.Errorf("Load counters wrong. Got %v successes and %v failures, Expected %v and %v", stats.LoadSuccesses, stats.LoadFailures, 1, 1)
+		t.FailNow()
+	}
+	if stats.Misses != 2 {
+		t.Errorf("Misses wrong. Got %v, Expected %v", stats.Misses, 2)
+		t.FailNow()
+	}
+}
+
+// Check that a waiting caller gives up when its context is cancelled
+func TestContextLoading(t *testing.T) {
+	lc := NewLoadingCache(NewLru(1024))
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		close(loading)
+		<-release
+		return []byte("value"), nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lc.GetOrLoad(context.Background(), "key", loader)
+	}()
+	<-loading
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := lc.GetOrLoad(ctx, "key", loader); err != context.Canceled {
+		t.Errorf("Waiting caller ignored its context. Got %v, Expected %v", err, context.Canceled)
+		t.FailNow()
+	}
+
+	close(release)
+	<-done
+}
+
+// Check that the caller that started a load giving up does not fail the load
+// for the other callers waiting on it
+func TestDetachedContextLoading(t *testing.T) {
+	lc := NewLoadingCache(NewLru(1024))
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		close(loading)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return []byte("value"), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := lc.GetOrLoad(ctx, "key", loader)
+		errs <- err
+	}()
+	<-loading
+
+	values := make(chan []byte)
+	go func() {
+		value, _ := lc.GetOrLoad(context.Background(), "key", loader)
+		values <- value
+	}()
+
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("First caller ignored its context. Got %v, Expected %v", err, context.Canceled)
+		t.FailNow()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if value := <-values; !bytesEqual(value, []byte("value")) {
+		t.Errorf("Second caller did not get the value. Got %v, Expected %v", value, []byte("value"))
+		t.FailNow()
+	}
+	if value, ok := lc.Peek("key"); !ok || !bytesEqual(value, []byte("value")) {
+		t.Errorf("Loaded value was not stored. Got %v, %v, Expected %v, %v", value, ok, []byte("value"), true)
+		t.FailNow()
+	}
+}
+
+// Check that callers waiting on a panicking loader are released with an error
+func TestPanicLoading(t *testing.T) {
+	lc := NewLoadingCache(NewLru(1024))
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	var calls int32
+	loader := func(ctx context.Context, key string) ([]byte, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			return nil, errBackend
+		}
+		close(loading)
+		<-release
+		panic("loader bug")
+	}
+
+	go func() {
+		defer func() { recover() }()
+		lc.GetOrLoad(context.Background(), "key", loader)
+	}()
+	<-loading
+
+	errs := make(chan error)
+	go func() {
+		_, err := lc.GetOrLoad(context.Background(), "key", loader)
+		errs <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-errs; err != ErrLoaderPanicked {
+		t.Errorf("Waiting caller got wrong error. Got %v, Expected %v", err, ErrLoaderPanicked)
+		t.FailNow()
+	}
+}
@@ -2,188 +2,106 @@ package cache
 
 import (
 	"container/list"
+	"time"
 )
 
 type mapping struct {
-	key   string
-	value []byte
-	Node  *list.Element
+	key     string
+	value   []byte
+	Node    *list.Element
+	expires time.Time // Time at which the binding expires, zero if it never does
 }
 
-// An LRU is a fixed-size in-memory cache with least-recently-used eviction
-type LRU struct {
-	// whatever fields you want here
-	cachedValues          map[string]mapping // Map containing key-value pairings
-	cachedList            list.List          // Linked list to hold insertion order
-	capacity              int                // To hold the capacity of the cache
-	currentlyUsedCapacity int                // Currently used capacity of the cache
-	stats                 Stats              // Hits and misses for the cache
+// expired returns true if the binding has a time-to-live that has run out by now.
+func (m mapping) expired(now time.Time) bool {
+	return !m.expires.IsZero() && !now.Before(m.expires)
 }
 
-// NewLRU returns a pointer to a new LRU with a capacity to store limit bytes
-func NewLru(limit int) *LRU {
-	return &LRU{cachedValues: make(map[string]mapping), cachedList: *list.New(), capacity: limit, currentlyUsedCapacity: 0, stats: Stats{}}
+// An lruEntry is a key in an lruPolicy along with the size of its binding
+type lruEntry struct {
+	key  string
+	size int // len(key) + len(value) of the binding
 }
 
-// MaxStorage returns the maximum number of bytes this LRU can store
-func (lru *LRU) MaxStorage() int {
-	return lru.capacity
+// An lruPolicy is a Policy that evicts the least recently used binding. It
+// keeps the keys of a Store in a list, most recently used first.
+type lruPolicy struct {
+	nodes map[string]*list.Element // Map containing key-element pairings
+	order list.List                // Entries, most recently used at the front
+	size  int                      // Total size of the bindings
 }
 
-// RemainingStorage returns the number of unused bytes available in this LRU
-func (lru *LRU) RemainingStorage() int {
-	return lru.capacity - lru.currentlyUsedCapacity
+// newLruPolicy returns an lruPolicy holding no keys.
+func newLruPolicy() *lruPolicy {
+	return &lruPolicy{nodes: make(map[string]*list.Element)}
 }
 
-// Peek returns the value associated with the given key, if it exists.
-// This operation does not counts as a "use" for that key-value pair
-// ok is true if a value was found and false otherwise.
-func (lru *LRU) Peek(key string) (value []byte, ok bool) {
-	currMapping, ok := lru.cachedValues[key]
-	return currMapping.value, ok
+// OnInsert makes key the most recently used.
+func (policy *lruPolicy) OnInsert(key string, size int) {
+	policy.nodes[key] = policy.order.PushFront(lruEntry{key: key, size: size})
+	policy.size += size
 }
 
-// Get returns the value associated with the given key, if it exists.
-// This operation counts as a "use" for that key-value pair
-// ok is true if a value was found and false otherwise.
-func (lru *LRU) Get(key string) (value []byte, ok bool) {
-	currMapping, ok := lru.cachedValues[key]
-
-	if ok {
-		if len(lru.cachedValues) > 1 {
-			//if key exists move to front of linkedlist
-			lru.cachedList.MoveToFront(currMapping.Node)
-		}
-		lru.stats.Hits += 1
-	} else {
-		lru.stats.Misses += 1
+// OnAccess makes key the most recently used.
+func (policy *lruPolicy) OnAccess(key string, size int) {
+	elem, ok := policy.nodes[key]
+	if !ok {
+		return
 	}
-
-	// THIS WORKS
-
-	return currMapping.value, ok
+	entry := elem.Value.(lruEntry)
+	policy.size += size - entry.size
+	entry.size = size
+	elem.Value = entry
+	policy.order.MoveToFront(elem)
 }
 
-// Remove removes and returns the value associated with the given key, if it exists.
-// ok is true if a value was found and false otherwise
-func (lru *LRU) Remove(key string) (value []byte, ok bool) {
-	currMapping, ok := lru.cachedValues[key]
-
+// OnRemove forgets key.
+func (policy *lruPolicy) OnRemove(key string, reason RemovalReason) {
+	elem, ok := policy.nodes[key]
 	if !ok {
-		return nil, false
+		return
 	}
-
-	delete(lru.cachedValues, key)
-	lru.cachedList.Remove(currMapping.Node)
-	lru.currentlyUsedCapacity -= len(currMapping.key) + len(currMapping.value)
-
-	return currMapping.value, ok
+	policy.size -= policy.order.Remove(elem).(lruEntry).size
+	delete(policy.nodes, key)
 }
 
-// Set associates the given value with the given key, possibly evicting values
-// to make room. Returns true if the binding was added successfully, else false.
-func (lru *LRU) Set(key string, value []byte) bool {
-	// check if key exists - simply replace
-	currMapping, ok := lru.cachedValues[key]
-
-	currentObjectSize := len(key) + len(value)
-	// If objectSize is larger than the whole cache
-	if currentObjectSize > lru.capacity {
-		return false
-	}
-
-	if ok {
-		if len(currMapping.value) == len(value) {
-			currMapping.value = value
-			lru.cachedValues[key] = currMapping
-		} else if len(currMapping.value) > len(value) {
-			lru.currentlyUsedCapacity -= len(currMapping.value)
-			currMapping.value = value
-			lru.cachedValues[key] = currMapping
-			lru.currentlyUsedCapacity += len(value)
-		} else if len(currMapping.value) < len(value) {
-			lru.currentlyUsedCapacity -= len(currMapping.value)
-			if lru.capacity-lru.currentlyUsedCapacity < len(value) {
-				if _, successfulEvict := lru.Evict(); !successfulEvict {
-					return false
-				}
-			}
-			currMapping.value = value
-			lru.cachedValues[key] = currMapping
-			lru.currentlyUsedCapacity += len(value)
-		}
-		return true
-	}
-
-	if lru.capacity-lru.currentlyUsedCapacity < currentObjectSize {
-		if _, successfulEvict := lru.Evict(); !successfulEvict {
-			return false
-		}
-	}
-
-	currMapping = mapping{key: key, value: value, Node: nil}
-	if lru.cachedList.Len() == 0 {
-		lru.cachedList.Init()
+// Victim returns the least recently used key.
+func (policy *lruPolicy) Victim() (key string, ok bool) {
+	elem := policy.order.Back()
+	if elem == nil {
+		return "", false
 	}
-	mappingPtr := lru.cachedList.PushFront(currMapping)
-
-	currMapping.Node = mappingPtr
-	mappingPtr.Value = currMapping
-	lru.cachedValues[key] = currMapping
-
-	// Increase currentlyUsedCapacity to reflect currentObjectSize
-	lru.currentlyUsedCapacity += currentObjectSize
-	return true
+	return elem.Value.(lruEntry).key, true
 }
 
-func (lru *LRU) Empty() {
-	lru.cachedValues = make(map[string]mapping)
-	lru.cachedList = *list.New()
-	lru.currentlyUsedCapacity = 0
+// Len returns the number of keys in the lruPolicy.
+func (policy *lruPolicy) Len() int {
+	return len(policy.nodes)
 }
 
-func (lru *LRU) Evict() (key string, ok bool) {
-	if lru.Len() == 0 {
-		return "", false
+// keys returns the keys from least to most recently used.
+func (policy *lruPolicy) keys() []string {
+	keys := make([]string, 0, policy.Len())
+	for elem := policy.order.Back(); elem != nil; elem = elem.Prev() {
+		keys = append(keys, elem.Value.(lruEntry).key)
 	}
-	// Last element of list
-
-	elem := (lru.cachedList).Back()
-	currMapping := elem.Value.(mapping)
-
-	// If key is not found, THEN RETURN WHAT?
-	if _, ok := lru.cachedValues[currMapping.key]; !ok {
-		return "", false
-	}
-
-	// If key is found - Delete from map
-	delete(lru.cachedValues, currMapping.key)
-
-	// Delete from list
-	lru.cachedList.Remove(elem)
-
-	// Decrease currentlyUsedCapacity to reflect deletion
-	currentObjectSize := len(currMapping.key) + len(currMapping.value)
-	lru.currentlyUsedCapacity -= currentObjectSize
-
-	// Eviction successful
-	return currMapping.key, true
+	return keys
 }
 
-// Len returns the number of bindings in the LRU.
-func (lru *LRU) Len() int {
-	return len(lru.cachedValues)
+// An LRU is a fixed-size in-memory cache with least-recently-used eviction
+type LRU struct {
+	*Store            // Bindings, sizes and stats
+	policy *lruPolicy // Keys in recency order
 }
 
-// Stats returns statistics about how many search hits and misses have occurred.
-func (lru *LRU) Stats() *Stats {
-	return &lru.stats
+// NewLRU returns a pointer to a new LRU with a capacity to store limit bytes
+func NewLru(limit int) *LRU {
+	policy := newLruPolicy()
+	return &LRU{Store: NewStore(limit, policy), policy: policy}
 }
 
-func (lru *LRU) resizeLRU(length int) {
-	for lru.RemainingStorage() < length {
-		lru.Evict()
-	}
+func init() {
+	Register("lru", func(limit int) Cache { return NewLru(limit) })
 }
 
 /*
@@ -3,95 +3,110 @@ package cache
 // Will have the performance tests
 
 import (
-	//"bytes"
-	//"fmt"
-	//"testing"
-	"crypto/rand"
+	"sort"
 	"testing"
 
-	//"time"
-	"math"
-	"math/big"
-	"strconv"
+	"cos316.princeton.edu/assignment3/trace"
+	"cos316.princeton.edu/assignment3/workload"
 )
 
 // Evaluate: Latency, Hit ratio
-// Use on really large dataset(a lot of misses)
+// Each benchmark replays the same synthetic workloads, so the hit% reported
+// for each policy can be compared directly
+
+const (
+	benchmarkSeed      = 316
+	benchmarkCapacity  = 20480
+	benchmarkKeys      = 32768
+	benchmarkValueSize = 15
+)
 
-func getRand(tb testing.TB) int64 {
-	out, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
-	if err != nil {
-		tb.Fatal(err)
+// benchmarkWorkloads returns constructors for the workloads every policy is
+// benchmarked on, by name. Key sources are stateful, so each run builds its
+// workload afresh and every policy sees the same requests. About a thousand
+// bindings fit in benchmarkCapacity.
+func benchmarkWorkloads() map[string]func() workload.Config {
+	fits := uint64(benchmarkCapacity / (benchmarkValueSize + 5))
+	keys := map[string]func() workload.KeySource{
+		"Uniform":       func() workload.KeySource { return workload.NewUniform(benchmarkSeed, benchmarkKeys) },
+		"Zipf":          func() workload.KeySource { return workload.NewZipf(benchmarkSeed, benchmarkKeys, 0.99) },
+		"ScrambledZipf": func() workload.KeySource { return workload.NewScrambledZipf(benchmarkSeed, benchmarkKeys, 0.99) },
+		"Hotspot":       func() workload.KeySource { return workload.NewHotspot(benchmarkSeed, benchmarkKeys, 0.02, 0.9) },
+		"Loop":          func() workload.KeySource { return workload.NewLoop(fits + fits/4) },
+		"ScanMix": func() workload.KeySource {
+			return workload.NewScanMix(benchmarkSeed, workload.NewZipf(benchmarkSeed+1, fits, 0.99), 0.5)
+		},
 	}
-	return out.Int64()
-}
-
-// Small benchmark test to compare arc
-func BenchmarkArc(b *testing.B) {
-	capacities := []int{20480} //160, 320, 640, 1280, 2560, 5120, 10240, 20480}
 
-	for i := range capacities {
-		capacity := capacities[i]
-		arc := NewArc(capacity)
-
-		trace := make([]int64, b.N*2)
-		for i := 0; i < b.N*2; i++ {
-			trace[i] = getRand(b) % 32768
+	configs := make(map[string]func() workload.Config, len(keys))
+	for name, newSource := range keys {
+		newSource := newSource
+		configs[name] = func() workload.Config {
+			return workload.Config{
+				Seed:         benchmarkSeed,
+				Keys:         newSource(),
+				Sizes:        workload.FixedSize(benchmarkValueSize),
+				ReadFraction: 0.9,
+			}
 		}
+	}
+	return configs
+}
 
-		b.ResetTimer()
-
-		var hits, misses, total int
-		for i := 0; i < 2*b.N; i++ {
-			s := strconv.Itoa(int(trace[i]))
-			if i%2 == 0 {
-				arc.Set(s, []byte(s))
-			} else {
-				if _, ok := arc.Get(s); ok {
-					hits++
-				} else {
-					misses++
-				}
-				total = hits + misses
+// replayEvents applies events to cache as a look-aside client would: a get
+// is a Get followed by a Set if it missed, a set is a Set.
+func replayEvents(cache Cache, events []trace.Event) {
+	value := make([]byte, benchmarkValueSize)
+	for _, event := range events {
+		if event.Size > len(value) {
+			value = make([]byte, event.Size)
+		}
+		switch event.Op {
+		case trace.OpGet:
+			if _, ok := cache.Get(event.Key); !ok {
+				cache.Set(event.Key, value[:event.Size])
 			}
+		case trace.OpSet:
+			cache.Set(event.Key, value[:event.Size])
+		case trace.OpDelete:
+			cache.Remove(event.Key)
 		}
-		hit_ratio := float64(hits) / float64(misses)
-		b.Logf("ARC(%d): hits: %d | misses: %d | hit-ratio: %f", total, hits, misses, hit_ratio)
 	}
-
 }
 
-func BenchmarkLru(b *testing.B) {
-	capacities := []int{20480} //160, 320, 640, 1280, 2560, 5120, 10240, 20480}
-
-	for i := range capacities {
-		capacity := capacities[i]
-		lru := NewLru(capacity)
+// benchmarkPolicy runs every workload against a fresh cache of the policy
+// registered under policy, reporting the hit ratio alongside the time per
+// request.
+func benchmarkPolicy(b *testing.B, policy string) {
+	workloads := benchmarkWorkloads()
+	names := make([]string, 0, len(workloads))
+	for name := range workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-		trace := make([]int64, b.N*2)
-		for i := 0; i < b.N*2; i++ {
-			trace[i] = getRand(b) % 327680
-		}
+	for _, name := range names {
+		newConfig := workloads[name]
+		b.Run(name, func(b *testing.B) {
+			events := workload.New(newConfig()).Events(b.N)
+			cache := mustNew(b, policy, benchmarkCapacity)
 
-		b.ResetTimer()
+			b.ResetTimer()
+			replayEvents(cache, events)
+			b.StopTimer()
 
-		var hits, misses, total int
-		for i := 0; i < 2*b.N; i++ {
-			s := strconv.Itoa(int(trace[i]))
-			if i%2 == 0 {
-				lru.Set(s, []byte(s))
-			} else {
-				if _, ok := lru.Get(s); ok {
-					hits++
-				} else {
-					misses++
-				}
-			}
-			total = hits + misses
-		}
-		hit_ratio := float64(hits) / float64(misses)
-		b.Logf("LRU(%d): hits: %d | misses: %d | hit-ratio: %f", total, hits, misses, hit_ratio)
+			b.ReportMetric(100*cache.Stats().HitRatio(), "hit%")
+		})
 	}
 }
 
-// Using trace from webcachism
+// BenchmarkPolicies benchmarks every registered policy, as
+// BenchmarkPolicies/<policy>/<workload>
+func BenchmarkPolicies(b *testing.B) {
+	for _, name := range Names() {
+		name := name
+		b.Run(name, func(b *testing.B) {
+			benchmarkPolicy(b, name)
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// A snapshot is laid out as follows, with every integer after the header
+// varint-encoded:
+//
+//	magic    4 bytes, "CSNP"
+//	version  1 byte, snapshotVersion
+//	kind     1 byte, snapshotKindLRU or snapshotKindARC
+//	body     depends on kind, see LRU.SaveTo and ARC.SaveTo
+//	checksum 4 bytes, big-endian CRC-32 (IEEE) of everything before it
+//
+// Lists of bindings are a count followed by that many bindings, from least to
+// most recently used. A binding is its key, its value and its expiry time in
+// Unix nanoseconds, or 0 if it never expires. Keys and values are a length
+// followed by that many bytes; a nil value has length -1. Lists of ghost
+// entries are a count followed by that many keys, from least to most recently
+// remembered, each followed by the size of the binding it remembers.
+//
+// Version 2 added the sizes of ghost entries. Version 1 snapshots, whose
+// ghost entries are bindings with empty values, can still be loaded; their
+// ghost entries are given the size of their key, as version 1 ARCs did.
+const (
+	snapshotMagic     = "CSNP"
+	snapshotVersion   = 2
+	snapshotVersionV1 = 1
+
+	snapshotKindLRU = 1
+	snapshotKindARC = 2
+
+	snapshotHeaderSize   = len(snapshotMagic) + 2
+	snapshotChecksumSize = 4
+)
+
+var (
+	// ErrCorruptSnapshot is returned when a snapshot is truncated, fails its
+	// checksum or is otherwise malformed.
+	ErrCorruptSnapshot = errors.New("cache: corrupt snapshot")
+
+	// ErrSnapshotVersion is returned when a snapshot was written by an
+	// unsupported version of the format.
+	ErrSnapshotVersion = errors.New("cache: unsupported snapshot version")
+
+	// ErrSnapshotKind is returned when a snapshot of one policy is loaded into
+	// a cache of another.
+	ErrSnapshotKind = errors.New("cache: snapshot is for a different policy")
+
+	// ErrSnapshotTooLarge is returned when a snapshot holds more bytes than
+	// the cache it is loaded into can store.
+	ErrSnapshotTooLarge = errors.New("cache: snapshot does not fit in cache")
+
+	// ErrSnapshotCapacity is returned when an ARC snapshot is loaded into an
+	// ARC of a different capacity.
+	ErrSnapshotCapacity = errors.New("cache: snapshot was saved with a different capacity")
+)
+
+// A Persister is a cache whose contents can be saved and restored
+type Persister interface {
+	// SaveTo writes a snapshot of the cache's contents to w.
+	SaveTo(w io.Writer) error
+
+	// LoadFrom replaces the cache's contents with a snapshot read from r. On
+	// error the cache is left unchanged.
+	LoadFrom(r io.Reader) error
+}
+
+/******************************************************************************/
+/*                                  Writing                                   */
+/******************************************************************************/
+
+// snapshotWriter accumulates the body of a snapshot.
+type snapshotWriter struct {
+	buf     bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+// varint appends x to the snapshot.
+func (sw *snapshotWriter) varint(x int64) {
+	n := binary.PutVarint(sw.scratch[:], x)
+	sw.buf.Write(sw.scratch[:n])
+}
+
+// bytes appends a length-prefixed byte slice, preserving whether it is nil.
+func (sw *snapshotWriter) bytes(b []byte) {
+	if b == nil {
+		sw.varint(-1)
+		return
+	}
+	sw.varint(int64(len(b)))
+	sw.buf.Write(b)
+}
+
+// list appends the bindings of store for keys, ordered from least to most
+// recently used.
+func (sw *snapshotWriter) list(store *Store, keys []string) {
+	sw.varint(int64(len(keys)))
+	for _, key := range keys {
+		currMapping := store.cachedValues[key]
+		sw.bytes([]byte(currMapping.key))
+		sw.bytes(currMapping.value)
+		if currMapping.expires.IsZero() {
+			sw.varint(0)
+		} else {
+			sw.varint(currMapping.expires.UnixNano())
+		}
+	}
+}
+
+// writeSnapshot writes a complete snapshot of the given kind to w, with the
+// body produced by body.
+func writeSnapshot(w io.Writer, kind byte, body func(sw *snapshotWriter)) error {
+	sw := &snapshotWriter{}
+	sw.buf.WriteString(snapshotMagic)
+	sw.buf.WriteByte(snapshotVersion)
+	sw.buf.WriteByte(kind)
+	body(sw)
+
+	var checksum [snapshotChecksumSize]byte
+	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(sw.buf.Bytes()))
+	sw.buf.Write(checksum[:])
+
+	_, err := w.Write(sw.buf.Bytes())
+	return err
+}
+
+/******************************************************************************/
+/*                                  Reading                                   */
+/******************************************************************************/
+
+// snapshotReader decodes the body of a snapshot. The first decoding error is
+// kept in err and turns every later read into a no-op.
+type snapshotReader struct {
+	body    *bytes.Reader
+	version byte
+	err     error
+}
+
+// readSnapshot reads a complete snapshot from r, checks its header and
+// checksum, and returns a reader positioned at the start of its body.
+func readSnapshot(r io.Reader, kind byte) (*snapshotReader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < snapshotHeaderSize+snapshotChecksumSize || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, ErrCorruptSnapshot
+	}
+
+	contents := data[:len(data)-snapshotChecksumSize]
+	checksum := binary.BigEndian.Uint32(data[len(contents):])
+	if crc32.ChecksumIEEE(contents) != checksum {
+		return nil, ErrCorruptSnapshot
+	}
+
+	version := data[len(snapshotMagic)]
+	if version != snapshotVersion && version != snapshotVersionV1 {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+	if data[len(snapshotMagic)+1] != kind {
+		return nil, ErrSnapshotKind
+	}
+	return &snapshotReader{body: bytes.NewReader(contents[snapshotHeaderSize:]), version: version}, nil
+}
+
+// varint reads an integer from the snapshot.
+func (sr *snapshotReader) varint() int64 {
+	if sr.err != nil {
+		return 0
+	}
+	x, err := binary.ReadVarint(sr.body)
+	if err != nil {
+		sr.err = ErrCorruptSnapshot
+	}
+	return x
+}
+
+// bytes reads a length-prefixed byte slice from the snapshot.
+func (sr *snapshotReader) bytes() []byte {
+	n := sr.varint()
+	if sr.err != nil || n == -1 {
+		return nil
+	}
+	if n < -1 || n > int64(sr.body.Len()) {
+		sr.err = ErrCorruptSnapshot
+		return nil
+	}
+	b := make([]byte, n)
+	sr.body.Read(b)
+	return b
+}
+
+// list reads a list of bindings written by snapshotWriter.list.
+func (sr *snapshotReader) list() []mapping {
+	n := sr.varint()
+	if sr.err != nil {
+		return nil
+	}
+	if n < 0 || n > int64(sr.body.Len()) {
+		sr.err = ErrCorruptSnapshot
+		return nil
+	}
+
+	mappings := make([]mapping, 0, n)
+	seen := make(map[string]bool, n)
+	for i := int64(0); i < n && sr.err == nil; i++ {
+		currMapping := mapping{key: string(sr.bytes()), value: sr.bytes()}
+		if expires := sr.varint(); expires != 0 {
+			currMapping.expires = time.Unix(0, expires)
+		}
+		if seen[currMapping.key] {
+			sr.err = ErrCorruptSnapshot
+		}
+		seen[currMapping.key] = true
+		mappings = append(mappings, currMapping)
+	}
+	return mappings
+}
+
+// ghosts reads a list of ghost entries written by snapshotWriter.ghosts, or,
+// in a version 1 snapshot, a list of bindings whose sizes are taken as those
+// of the entries. An entry smaller than its key or larger than limit is
+// corrupt.
+func (sr *snapshotReader) ghosts(limit int64) []ghostEntry {
+	n := sr.varint()
+	if sr.err != nil {
+		return nil
+	}
+	if n < 0 || n > int64(sr.body.Len()) {
+		sr.err = ErrCorruptSnapshot
+		return nil
+	}
+
+	entries := make([]ghostEntry, 0, n)
+	seen := make(map[string]bool, n)
+	for i := int64(0); i < n && sr.err == nil; i++ {
+		key := string(sr.bytes())
+		var size int64
+		if sr.version == snapshotVersionV1 {
+			size = int64(len(key) + len(sr.bytes()))
+			sr.varint()
+		} else {
+			size = sr.varint()
+		}
+		if seen[key] || size < int64(len(key)) || size > limit {
+			sr.err = ErrCorruptSnapshot
+		}
+		seen[key] = true
+		entries = append(entries, ghostEntry{key: key, size: int(size)})
+	}
+	return entries
+}
+
+// finish returns the first decoding error, or ErrCorruptSnapshot if there is
+// data left over after the body.
+func (sr *snapshotReader) finish() error {
+	if sr.err == nil && sr.body.Len() != 0 {
+		sr.err = ErrCorruptSnapshot
+	}
+	return sr.err
+}
+
+// listSize returns the number of bytes the given bindings occupy.
+func listSize(mappings []mapping) int {
+	size := 0
+	for _, currMapping := range mappings {
+		size += len(currMapping.key) + len(currMapping.value)
+	}
+	return size
+}
+
+// ghostsSize returns the number of bytes of the bindings the given ghost
+// entries remember.
+func ghostsSize(entries []ghostEntry) int {
+	size := 0
+	for _, entry := range entries {
+		size += entry.size
+	}
+	return size
+}
+
+// distinctKeys reports whether no key appears in more than one of the given
+// lists of bindings and ghost entries.
+func distinctKeys(mappingLists [][]mapping, ghostLists [][]ghostEntry) bool {
+	seen := make(map[string]bool)
+	for _, mappings := range mappingLists {
+		for _, currMapping := range mappings {
+			if seen[currMapping.key] {
+				return false
+			}
+			seen[currMapping.key] = true
+		}
+	}
+	for _, entries := range ghostLists {
+		for _, entry := range entries {
+			if seen[entry.key] {
+				return false
+			}
+			seen[entry.key] = true
+		}
+	}
+	return true
+}
+
+// ghosts appends the keys of g with their sizes, ordered from least to most
+// recently remembered.
+func (sw *snapshotWriter) ghosts(g *ghostList) {
+	sw.varint(int64(g.len()))
+	for elem := g.order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(ghostEntry)
+		sw.bytes([]byte(entry.key))
+		sw.varint(int64(entry.size))
+	}
+}
+
+// restoreList adds the given bindings to store, ordered from least to most
+// recently used, keeping their order in policy rather than in the policy of
+// the store.
+func restoreList(store *Store, policy *lruPolicy, mappings []mapping) {
+	for _, currMapping := range mappings {
+		policy.OnInsert(currMapping.key, len(currMapping.key)+len(currMapping.value))
+		store.put(currMapping)
+	}
+}
+
+// restoreGhosts replaces the entries of g with the given ones, ordered from
+// least to most recently remembered.
+func restoreGhosts(g *ghostList, entries []ghostEntry) {
+	g.reset()
+	for _, entry := range entries {
+		g.push(entry.key, entry.size)
+	}
+}
+
+/******************************************************************************/
+/*                                  Policies                                  */
+/******************************************************************************/
+
+// SaveTo writes a snapshot of the LRU's bindings to w, keeping their recency
+// order and expiry times. The body of an LRU snapshot is the LRU's capacity
+// followed by its list of bindings. Stats are not saved.
+func (lru *LRU) SaveTo(w io.Writer) error {
+	return writeSnapshot(w, snapshotKindLRU, func(sw *snapshotWriter) {
+		sw.varint(int64(lru.capacity))
+		sw.list(lru.Store, lru.policy.keys())
+	})
+}
+
+// LoadFrom replaces the LRU's bindings with a snapshot written by SaveTo.
+// The snapshot may come from an LRU of a different capacity, as long as its
+// bindings fit. On error the LRU is left unchanged.
+func (lru *LRU) LoadFrom(r io.Reader) error {
+	sr, err := readSnapshot(r, snapshotKindLRU)
+	if err != nil {
+		return err
+	}
+	sr.varint()
+	mappings := sr.list()
+	if err := sr.finish(); err != nil {
+		return err
+	}
+
+	if listSize(mappings) > lru.capacity {
+		return ErrSnapshotTooLarge
+	}
+	lru.reset()
+	restoreList(lru.Store, lru.policy, mappings)
+	return nil
+}
+
+// SaveTo writes a snapshot of the ARC to w: its capacity, the adaptation
+// parameter p, and the lists t1, t2, b1 and b2 in that order, so that a
+// restored ARC makes exactly the same decisions as this one. Stats are not
+// saved.
+func (arc *ARC) SaveTo(w io.Writer) error {
+	return writeSnapshot(w, snapshotKindARC, func(sw *snapshotWriter) {
+		sw.varint(int64(arc.capacity))
+		sw.varint(int64(arc.policy.p))
+		sw.list(arc.Store, arc.policy.t1.keys())
+		sw.list(arc.Store, arc.policy.t2.keys())
+		sw.ghosts(arc.policy.b1)
+		sw.ghosts(arc.policy.b2)
+	})
+}
+
+// LoadFrom replaces the ARC's contents with a snapshot written by SaveTo.
+// Since p is measured in bytes, the snapshot must come from an ARC with the
+// same capacity. A snapshot with a key in more than one list, or with lists
+// larger than ARC allows, is reported as corrupt. On error the ARC is left
+// unchanged.
+func (arc *ARC) LoadFrom(r io.Reader) error {
+	sr, err := readSnapshot(r, snapshotKindARC)
+	if err != nil {
+		return err
+	}
+	capacity := sr.varint()
+	p := sr.varint()
+	t1, t2 := sr.list(), sr.list()
+	b1, b2 := sr.ghosts(capacity), sr.ghosts(capacity)
+	if err := sr.finish(); err != nil {
+		return err
+	}
+
+	if capacity != int64(arc.capacity) {
+		return ErrSnapshotCapacity
+	}
+	t1Size, t2Size, b1Size, b2Size := listSize(t1), listSize(t2), ghostsSize(b1), ghostsSize(b2)
+	if p < 0 || p > capacity || t1Size+t2Size > arc.capacity ||
+		t1Size+b1Size > arc.capacity || t1Size+t2Size+b1Size+b2Size > 2*arc.capacity ||
+		!distinctKeys([][]mapping{t1, t2}, [][]ghostEntry{b1, b2}) {
+		return ErrCorruptSnapshot
+	}
+
+	arc.reset()
+	arc.policy.p = int(p)
+	restoreList(arc.Store, arc.policy.t1, t1)
+	restoreList(arc.Store, arc.policy.t2, t2)
+	restoreGhosts(arc.policy.b1, b1)
+	restoreGhosts(arc.policy.b2, b2)
+	return nil
+}
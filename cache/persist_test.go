@@ -0,0 +1,386 @@
+/******************************************************************************
+ * persist_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for saving and restoring caches in persist.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// checkSameKeys fails t if the two lists of keys are not the same, in the
+// same order.
+func checkSameKeys(t *testing.T, name string, gotKeys []string, expectedKeys []string) {
+	if fmt.Sprint(gotKeys) != fmt.Sprint(expectedKeys) {
+		t.Errorf("%s restored in wrong order. Got %v, Expected %v", name, gotKeys, expectedKeys)
+		t.FailNow()
+	}
+}
+
+// checkSameBindings fails t if the two Stores do not hold the same bindings.
+func checkSameBindings(t *testing.T, name string, got *Store, expected *Store) {
+	if got.Len() != expected.Len() {
+		t.Errorf("%s restored wrong number of bindings. Got %v, Expected %v", name, got.Len(), expected.Len())
+		t.FailNow()
+	}
+	for key := range expected.cachedValues {
+		gotMapping, _ := got.peekMapping(key)
+		expectedMapping, _ := expected.peekMapping(key)
+		if !bytesEqual(gotMapping.value, expectedMapping.value) || (gotMapping.value == nil) != (expectedMapping.value == nil) {
+			t.Errorf("%s restored wrong value for %s. Got %v, Expected %v", name, key, gotMapping.value, expectedMapping.value)
+			t.FailNow()
+		}
+		if !gotMapping.expires.Equal(expectedMapping.expires) {
+			t.Errorf("%s restored wrong expiry for %s. Got %v, Expected %v", name, key, gotMapping.expires, expectedMapping.expires)
+			t.FailNow()
+		}
+	}
+	if got.currentlyUsedCapacity != expected.currentlyUsedCapacity {
+		t.Errorf("%s restored wrong used storage. Got %v, Expected %v", name, got.currentlyUsedCapacity, expected.currentlyUsedCapacity)
+		t.FailNow()
+	}
+}
+
+// Return an ARC snapshot of the given capacity and p whose lists t1, t2, b1
+// and b2 hold the given keys, each bound to, or remembering, an empty value
+func arcSnapshot(capacity int, p int, lists ...[]string) []byte {
+	var buf bytes.Buffer
+	writeSnapshot(&buf, snapshotKindARC, func(sw *snapshotWriter) {
+		sw.varint(int64(capacity))
+		sw.varint(int64(p))
+		for i, keys := range lists {
+			sw.varint(int64(len(keys)))
+			for _, key := range keys {
+				sw.bytes([]byte(key))
+				if i >= 2 {
+					sw.varint(int64(len(key)))
+					continue
+				}
+				sw.bytes([]byte{})
+				sw.varint(0)
+			}
+		}
+	})
+	return buf.Bytes()
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that an LRU round-trips with its recency order, values and TTLs
+func TestRoundTripPersistLru(t *testing.T) {
+	clock := newFakeClock()
+	lru := NewLru(1024)
+	lru.SetClock(clock)
+
+	lru.Set("a", []byte("1"))
+	lru.Set("b", nil)
+	lru.Set("c", []byte{})
+	lru.SetWithTTL("d", []byte("\x00\x01\xff"), time.Minute)
+	lru.Set("😂", []byte("✔"))
+	lru.Get("a")
+
+	var buf bytes.Buffer
+	if err := lru.SaveTo(&buf); err != nil {
+		t.Errorf("SaveTo failed. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+
+	restored := NewLru(512)
+	restored.SetClock(clock)
+	restored.Set("stale", []byte("binding"))
+	if err := restored.LoadFrom(&buf); err != nil {
+		t.Errorf("LoadFrom failed. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+	checkSameKeys(t, "LRU", restored.policy.keys(), lru.policy.keys())
+	checkSameBindings(t, "LRU", restored.Store, lru.Store)
+	if restored.RemainingStorage() != 512-(1024-lru.RemainingStorage()) {
+		t.Errorf("RemainingStorage wrong after restore. Got %v, Expected %v", restored.RemainingStorage(), 512-(1024-lru.RemainingStorage()))
+		t.FailNow()
+	}
+
+	// The restored LRU evicts in the saved order
+	restored.Evict()
+	if _, ok := restored.Peek("b"); ok {
+		t.Errorf("Restored LRU evicted out of order. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+
+	// TTLs keep running after a restore
+	clock.Advance(time.Minute)
+	if _, ok := restored.Get("d"); ok {
+		t.Errorf("Restored binding lost its TTL. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+}
+
+// Check that a restored ARC has the same lists and p, and keeps behaving
+// exactly like the ARC it was saved from
+func TestRoundTripPersistArc(t *testing.T) {
+	capacity := 40
+	arc := NewArc(capacity)
+	arc.Set("____0", []byte("____0"))
+	arc.Set("____1", []byte("____1"))
+	arc.Get("____0")
+	arc.Get("____1")
+	arc.Replace("____2")
+	arc.Set("____2", []byte("____2"))
+	arc.Set("____3", []byte("____3"))
+	arc.Replace("____4")
+	arc.Set("____4", []byte("____4"))
+	arc.policy.p = 7
+	if arc.policy.t1.Len() == 0 || arc.policy.t2.Len() == 0 || arc.policy.b1.len() == 0 || arc.policy.b2.len() == 0 {
+		t.Errorf("Setup did not populate every list. Got %v, %v, %v, %v", arc.policy.t1.Len(), arc.policy.t2.Len(), arc.policy.b1.len(), arc.policy.b2.len())
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	if err := arc.SaveTo(&buf); err != nil {
+		t.Errorf("SaveTo failed. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+	restored := NewArc(capacity)
+	if err := restored.LoadFrom(&buf); err != nil {
+		t.Errorf("LoadFrom failed. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+
+	check := func() {
+		if restored.policy.p != arc.policy.p {
+			t.Errorf("Restored ARC has wrong p. Got %v, Expected %v", restored.policy.p, arc.policy.p)
+			t.FailNow()
+		}
+		checkSameKeys(t, "t1", restored.policy.t1.keys(), arc.policy.t1.keys())
+		checkSameKeys(t, "t2", restored.policy.t2.keys(), arc.policy.t2.keys())
+		checkSameKeys(t, "b1", restored.policy.b1.keys(), arc.policy.b1.keys())
+		checkSameKeys(t, "b2", restored.policy.b2.keys(), arc.policy.b2.keys())
+		if restored.policy.b1.size != arc.policy.b1.size || restored.policy.b2.size != arc.policy.b2.size {
+			t.Errorf("Restored ARC has wrong ghost sizes. Got %v, %v, Expected %v, %v", restored.policy.b1.size, restored.policy.b2.size, arc.policy.b1.size, arc.policy.b2.size)
+			t.FailNow()
+		}
+		checkSameBindings(t, "ARC", restored.Store, arc.Store)
+		if restored.RemainingStorage() != arc.RemainingStorage() {
+			t.Errorf("Restored ARC has wrong RemainingStorage. Got %v, Expected %v", restored.RemainingStorage(), arc.RemainingStorage())
+			t.FailNow()
+		}
+	}
+	check()
+
+	for i := 0; i < 60; i++ {
+		key := fmt.Sprintf("____%d", (i*7)%9)
+		if i%2 == 0 {
+			arc.Get(key)
+			restored.Get(key)
+		} else {
+			arc.Set(key, []byte(key))
+			restored.Set(key, []byte(key))
+		}
+		check()
+	}
+}
+
+// Check that corrupted, truncated or mismatched snapshots are rejected and
+// leave the cache unchanged
+func TestRejectPersist(t *testing.T) {
+	lru := NewLru(1024)
+	lru.Set("key", []byte("value"))
+	var buf bytes.Buffer
+	lru.SaveTo(&buf)
+	snapshot := buf.Bytes()
+
+	flipped := append([]byte{}, snapshot...)
+	flipped[len(flipped)/2] ^= 0x01
+
+	cases := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"empty", []byte{}, ErrCorruptSnapshot},
+		{"bad magic", append([]byte("XXXX"), snapshot[4:]...), ErrCorruptSnapshot},
+		{"flipped bit", flipped, ErrCorruptSnapshot},
+		{"truncated", snapshot[:len(snapshot)-1], ErrCorruptSnapshot},
+	}
+
+	for _, c := range cases {
+		target := NewLru(1024)
+		target.Set("keep", []byte("me"))
+		err := target.LoadFrom(bytes.NewReader(c.data))
+		if !errors.Is(err, c.expected) {
+			t.Errorf("LoadFrom accepted %s snapshot. Got %v, Expected %v", c.name, err, c.expected)
+			t.FailNow()
+		}
+		if _, ok := target.Peek("keep"); !ok || target.Len() != 1 {
+			t.Errorf("Rejected %s snapshot modified the LRU", c.name)
+			t.FailNow()
+		}
+	}
+
+	if err := NewArc(1024).LoadFrom(bytes.NewReader(snapshot)); !errors.Is(err, ErrSnapshotKind) {
+		t.Errorf("ARC accepted an LRU snapshot. Got %v, Expected %v", err, ErrSnapshotKind)
+		t.FailNow()
+	}
+	if err := NewLru(4).LoadFrom(bytes.NewReader(snapshot)); !errors.Is(err, ErrSnapshotTooLarge) {
+		t.Errorf("Small LRU accepted a large snapshot. Got %v, Expected %v", err, ErrSnapshotTooLarge)
+		t.FailNow()
+	}
+
+	buf.Reset()
+	NewArc(1024).SaveTo(&buf)
+	if err := NewArc(512).LoadFrom(&buf); !errors.Is(err, ErrSnapshotCapacity) {
+		t.Errorf("ARC accepted a snapshot of another capacity. Got %v, Expected %v", err, ErrSnapshotCapacity)
+		t.FailNow()
+	}
+}
+
+// Check that an ARC snapshot is rejected if a key is in more than one list,
+// if a ghost entry is smaller than its key or if its lists break the bounds
+// of ARC, and accepted otherwise
+func TestRejectArcPersist(t *testing.T) {
+	var buf bytes.Buffer
+	writeSnapshot(&buf, snapshotKindARC, func(sw *snapshotWriter) {
+		sw.varint(10)
+		sw.varint(0)
+		sw.varint(0)
+		sw.varint(0)
+		sw.varint(1)
+		sw.bytes([]byte("key"))
+		sw.varint(2)
+		sw.varint(0)
+	})
+	corruptGhost := buf.Bytes()
+
+	cases := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"valid", arcSnapshot(10, 0, []string{"t1"}, []string{"t2"}, []string{"b1"}, []string{"b2"}), nil},
+		{"t1 and b2 sharing a key", arcSnapshot(10, 0, []string{"key"}, nil, nil, []string{"key"}), ErrCorruptSnapshot},
+		{"t2 and b1 sharing a key", arcSnapshot(10, 0, nil, []string{"key"}, []string{"key"}, nil), ErrCorruptSnapshot},
+		{"b1 and b2 sharing a key", arcSnapshot(10, 0, nil, nil, []string{"key"}, []string{"key"}), ErrCorruptSnapshot},
+		{"t1 and b1 over capacity", arcSnapshot(10, 0, []string{"aaaaa"}, nil, []string{"bbbbbb"}, nil), ErrCorruptSnapshot},
+		{"lists over twice the capacity", arcSnapshot(10, 0, nil, []string{"aaaaaaaaa"}, []string{"bbbbbbbbbb"}, []string{"cccccccccc"}), ErrCorruptSnapshot},
+		{"a ghost smaller than its key", corruptGhost, ErrCorruptSnapshot},
+	}
+
+	for _, c := range cases {
+		target := NewArc(10)
+		target.Set("keep", []byte("me"))
+		err := target.LoadFrom(bytes.NewReader(c.data))
+		if !errors.Is(err, c.expected) {
+			t.Errorf("Wrong result loading a snapshot with %s. Got %v, Expected %v", c.name, err, c.expected)
+			t.FailNow()
+		}
+		if _, ok := target.Peek("keep"); c.expected != nil && (!ok || target.Len() != 1) {
+			t.Errorf("Rejected snapshot with %s modified the ARC", c.name)
+			t.FailNow()
+		}
+		if err := target.CheckInvariants(); err != nil {
+			t.Errorf("Snapshot with %s broke the ARC. Got %v, Expected %v", c.name, err, nil)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that a snapshot from a newer version of the format is reported as
+// such, rather than as corrupt
+func TestVersionPersist(t *testing.T) {
+	var buf bytes.Buffer
+	NewLru(64).SaveTo(&buf)
+
+	contents := buf.Bytes()[:buf.Len()-snapshotChecksumSize]
+	contents[len(snapshotMagic)] = snapshotVersion + 1
+	snapshot := make([]byte, len(contents)+snapshotChecksumSize)
+	copy(snapshot, contents)
+	binary.BigEndian.PutUint32(snapshot[len(contents):], crc32.ChecksumIEEE(contents))
+
+	err := NewLru(64).LoadFrom(bytes.NewReader(snapshot))
+	if !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("LoadFrom accepted an unknown version. Got %v, Expected %v", err, ErrSnapshotVersion)
+		t.FailNow()
+	}
+}
+
+// Check that a version 1 ARC snapshot, whose ghost entries are bindings with
+// empty values, still loads, giving each ghost entry the size of its key
+func TestVersion1Persist(t *testing.T) {
+	var body snapshotWriter
+	body.buf.WriteString(snapshotMagic)
+	body.buf.WriteByte(snapshotVersionV1)
+	body.buf.WriteByte(snapshotKindARC)
+	body.varint(40)
+	body.varint(5)
+	for _, keys := range [][]string{{"____0"}, {}, {"b1key"}, {"b2key", "other"}} {
+		body.varint(int64(len(keys)))
+		for _, key := range keys {
+			body.bytes([]byte(key))
+			body.bytes([]byte{})
+			body.varint(0)
+		}
+	}
+	contents := body.buf.Bytes()
+	snapshot := make([]byte, len(contents)+snapshotChecksumSize)
+	copy(snapshot, contents)
+	binary.BigEndian.PutUint32(snapshot[len(contents):], crc32.ChecksumIEEE(contents))
+
+	arc := NewArc(40)
+	if err := arc.LoadFrom(bytes.NewReader(snapshot)); err != nil {
+		t.Errorf("LoadFrom refused a version 1 snapshot. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+	if _, ok := arc.Peek("____0"); !ok || arc.policy.p != 5 {
+		t.Errorf("Version 1 snapshot restored wrong. Got binding %v and p = %v, Expected %v and %v", ok, arc.policy.p, true, 5)
+		t.FailNow()
+	}
+	if arc.policy.b1.size != 5 || arc.policy.b2.size != 10 || !arc.policy.b2.contains("other") {
+		t.Errorf("Version 1 ghost entries restored wrong. Got b1 of %v bytes and b2 of %v, Expected %v and %v", arc.policy.b1.size, arc.policy.b2.size, 5, 10)
+		t.FailNow()
+	}
+	if err := arc.CheckInvariants(); err != nil {
+		t.Errorf("Version 1 snapshot broke the ARC. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+}
+
+// Check that a Synchronized cache forwards snapshots to the cache it wraps
+func TestSynchronizedPersist(t *testing.T) {
+	source := Synchronized(NewLru(64))
+	source.Set("key", []byte("value"))
+
+	var buf bytes.Buffer
+	if err := source.(Persister).SaveTo(&buf); err != nil {
+		t.Errorf("SaveTo failed. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+	target := Synchronized(NewLru(64))
+	if err := target.(Persister).LoadFrom(&buf); err != nil {
+		t.Errorf("LoadFrom failed. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+	if value, ok := target.Get("key"); !ok || !bytesEqual(value, []byte("value")) {
+		t.Errorf("Restored wrong value. Got %v, Expected %v", value, []byte("value"))
+		t.FailNow()
+	}
+
+	if err := Synchronized(NewFifo(64)).(Persister).SaveTo(&buf); err != ErrNotSupported {
+		t.Errorf("Saved a cache that cannot be saved. Got %v, Expected %v", err, ErrNotSupported)
+		t.FailNow()
+	}
+}
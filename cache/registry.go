@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownPolicy is returned by New when no policy is registered under
+	// the requested name.
+	ErrUnknownPolicy = errors.New("cache: unknown policy")
+
+	// ErrUnsupportedOption is returned by New when an option cannot be
+	// applied to the cache built by the requested policy.
+	ErrUnsupportedOption = errors.New("cache: option not supported by policy")
+)
+
+// A Factory returns a new, empty cache with a capacity to store limit bytes.
+type Factory func(limit int) Cache
+
+// An Option configures a cache built by New. It returns an error wrapping
+// ErrUnsupportedOption if the cache does not support it.
+type Option func(c Cache) error
+
+var (
+	registryMu sync.RWMutex           // Guards registry
+	registry   = map[string]Factory{} // Factories by policy name
+)
+
+// Register makes a policy available to New under the given name, which must
+// be lowercase. Every policy in this package registers itself, e.g. as "lru"
+// or "arc". Register panics if name is not lowercase, if factory is nil or if
+// name is already taken.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name != strings.ToLower(name) {
+		panic("cache: Register name is not lowercase: " + name)
+	}
+	if factory == nil {
+		panic("cache: Register factory is nil for " + name)
+	}
+	if _, taken := registry[name]; taken {
+		panic("cache: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Names returns the names of the registered policies, sorted.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a new cache with a capacity to store limit bytes, built by the
+// policy registered under name, ignoring case, and configured by opts, in
+// order.
+func New(name string, limit int, opts ...Option) (Cache, error) {
+	registryMu.RLock()
+	factory, ok := registry[strings.ToLower(name)]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownPolicy, name, strings.Join(Names(), ", "))
+	}
+
+	c := factory(limit)
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("%w (policy %q)", err, name)
+		}
+	}
+	return c, nil
+}
+
+// WithEvictionCallback registers fn to be called whenever a binding leaves
+// the cache. See EvictionNotifier.
+func WithEvictionCallback(fn EvictionCallback) Option {
+	return func(c Cache) error {
+		notifier, ok := c.(EvictionNotifier)
+		if !ok {
+			return fmt.Errorf("%w: eviction callback", ErrUnsupportedOption)
+		}
+		notifier.OnEvict(fn)
+		return nil
+	}
+}
+
+// WithClock makes the cache use clock to decide whether a binding has
+// expired. Only caches built on a Store, such as LRU and ARC, support it.
+func WithClock(clock Clock) Option {
+	return func(c Cache) error {
+		setter, ok := c.(interface{ SetClock(Clock) })
+		if !ok {
+			return fmt.Errorf("%w: clock", ErrUnsupportedOption)
+		}
+		setter.SetClock(clock)
+		return nil
+	}
+}
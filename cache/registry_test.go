@@ -0,0 +1,129 @@
+/******************************************************************************
+ * registry_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for the policy registry in registry.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that every policy is registered and that New builds an empty cache of
+// the requested capacity, whatever the case of the name
+func TestNewRegistry(t *testing.T) {
+	expected := []string{"2q", "arc", "car", "clock", "clockpro", "fifo", "gdsf", "lfu", "lirs", "lru", "s3fifo", "sieve", "slru", "tinylfu"}
+	names := Names()
+	if len(names) != len(expected) {
+		t.Errorf("Wrong policies registered. Got %v, Expected %v", names, expected)
+		t.FailNow()
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Wrong policies registered. Got %v, Expected %v", names, expected)
+			t.FailNow()
+		}
+	}
+
+	for _, name := range names {
+		cache, err := New(strings.ToUpper(name), 64)
+		if err != nil {
+			t.Errorf("Failed to build %s. Got %v, Expected %v", name, err, nil)
+			t.FailNow()
+		}
+		if cacheType(cache) != name {
+			t.Errorf("Built the wrong policy. Got %v, Expected %v", cacheType(cache), name)
+			t.FailNow()
+		}
+		checkCapacity(t, cache, 64)
+		if cache.Len() != 0 || cache.RemainingStorage() != 64 {
+			t.Errorf("%s was not empty. Got %v bindings, %v bytes free, Expected %v, %v", name, cache.Len(), cache.RemainingStorage(), 0, 64)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that New fails for a name no policy is registered under
+func TestUnknownRegistry(t *testing.T) {
+	cache, err := New("mru", 64)
+	if cache != nil || !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("Built an unregistered policy. Got %v, %v, Expected %v, %v", cache, err, nil, ErrUnknownPolicy)
+		t.FailNow()
+	}
+}
+
+// Check that Register makes a policy available to New and refuses to
+// register a name twice
+func TestRegisterRegistry(t *testing.T) {
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "test")
+		registryMu.Unlock()
+	}()
+
+	Register("test", func(limit int) Cache { return NewFifo(limit / 2) })
+	cache, err := New("test", 64)
+	if err != nil || cacheType(cache) != "fifo" || cache.MaxStorage() != 32 {
+		t.Errorf("Built the wrong cache. Got %v, %v with %v bytes, Expected %v, %v with %v bytes", cacheType(cache), err, cache.MaxStorage(), "fifo", nil, 32)
+		t.FailNow()
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Registered a name twice. Got %v, Expected %v", "no panic", "panic")
+		}
+	}()
+	Register("test", func(limit int) Cache { return NewLru(limit) })
+}
+
+// Check that Register refuses names that are not lowercase, which New could
+// never find
+func TestLowercaseRegistry(t *testing.T) {
+	defer func() {
+		registryMu.Lock()
+		_, registered := registry["Mixed"]
+		delete(registry, "Mixed")
+		registryMu.Unlock()
+		if recover() == nil || registered {
+			t.Errorf("Registered a mixed-case name. Got %v, Expected %v", "no panic", "panic")
+		}
+	}()
+	Register("Mixed", func(limit int) Cache { return NewLru(limit) })
+}
+
+// Check that New applies its options, and fails if the policy does not
+// support one
+func TestOptionsRegistry(t *testing.T) {
+	clock := newFakeClock()
+	var removed []string
+	cache, err := New("lru", 64, WithClock(clock), WithEvictionCallback(func(key string, value []byte, reason RemovalReason) {
+		removed = append(removed, key)
+	}))
+	if err != nil {
+		t.Errorf("Failed to build lru. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+
+	cache.(Expirer).SetWithTTL("key", []byte("value"), time.Minute)
+	clock.Advance(time.Minute)
+	if cache.(Expirer).RemoveExpired() != 1 || len(removed) != 1 {
+		t.Errorf("lru did not use the clock. Got %v removed, Expected %v", removed, []string{"key"})
+		t.FailNow()
+	}
+
+	cache, err = New("fifo", 64, WithClock(clock))
+	if cache != nil || !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("Applied an unsupported option. Got %v, %v, Expected %v, %v", cache, err, nil, ErrUnsupportedOption)
+		t.FailNow()
+	}
+}
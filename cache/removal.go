@@ -0,0 +1,48 @@
+package cache
+
+// A RemovalReason tells an EvictionCallback why a binding left the cache
+type RemovalReason int
+
+const (
+	// ReasonEvicted means the binding was evicted to make room for another.
+	ReasonEvicted RemovalReason = iota
+	// ReasonRemoved means the binding was removed by a call to Remove.
+	ReasonRemoved
+	// ReasonReplaced means Set overwrote the binding with a new value.
+	ReasonReplaced
+	// ReasonEmptied means the binding was dropped by a call to Empty.
+	ReasonEmptied
+	// ReasonExpired means the binding's time-to-live ran out.
+	ReasonExpired
+)
+
+// String returns a lower-case name for the reason, suitable for logging.
+func (reason RemovalReason) String() string {
+	switch reason {
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonRemoved:
+		return "removed"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonEmptied:
+		return "emptied"
+	case ReasonExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// An EvictionCallback is called with the key and value of every binding that
+// leaves a cache, and the reason it left. It runs synchronously inside the
+// cache operation that removed the binding, so it must not call back into the
+// same cache.
+type EvictionCallback func(key string, value []byte, reason RemovalReason)
+
+// An EvictionNotifier is a cache that reports bindings leaving it
+type EvictionNotifier interface {
+	// OnEvict registers fn to be called whenever a binding leaves the cache,
+	// replacing any previously registered callback. A nil fn removes it.
+	OnEvict(fn EvictionCallback)
+}
@@ -0,0 +1,192 @@
+/******************************************************************************
+ * removal_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for the eviction callbacks in removal.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// removal records a single call to an EvictionCallback.
+type removal struct {
+	key    string
+	value  string
+	reason RemovalReason
+}
+
+// notifyingCache is a Cache that reports bindings leaving it.
+type notifyingCache interface {
+	Cache
+	EvictionNotifier
+}
+
+// recordRemovals registers a callback on cache that appends every removal it
+// reports to the returned slice.
+func recordRemovals(cache notifyingCache) *[]removal {
+	removals := make([]removal, 0)
+	cache.OnEvict(func(key string, value []byte, reason RemovalReason) {
+		removals = append(removals, removal{key: key, value: string(value), reason: reason})
+	})
+	return &removals
+}
+
+// checkRemovals fails t if got does not match expected exactly, in order.
+func checkRemovals(t *testing.T, cache Cache, got []removal, expected []removal) {
+	if len(got) != len(expected) {
+		t.Errorf("%s reported wrong removals. Got %v, Expected %v", cacheType(cache), got, expected)
+		t.FailNow()
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("%s reported wrong removal %d. Got %v, Expected %v", cacheType(cache), i, got[i], expected[i])
+			t.FailNow()
+		}
+	}
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that Remove(), Set() overwrites and Empty() are reported by every policy
+func TestReasonsRemoval(t *testing.T) {
+	for _, cache := range newPolicies(t, 1024) {
+		removals := recordRemovals(cache)
+
+		cache.Set("a", []byte("1"))
+		cache.Set("b", []byte("2"))
+		cache.Set("a", []byte("3"))
+		cache.Remove("b")
+		cache.Remove("missing")
+		cache.Empty()
+
+		checkRemovals(t, cache, *removals, []removal{
+			{"a", "1", ReasonReplaced},
+			{"b", "2", ReasonRemoved},
+			{"a", "3", ReasonEmptied},
+		})
+	}
+}
+
+// Check that capacity evictions are reported with the evicted value
+func TestEvictionRemoval(t *testing.T) {
+	for _, cache := range newPolicies(t, 30) {
+		removals := recordRemovals(cache)
+
+		cache.Set("____0", []byte("____0"))
+		cache.Set("____1", []byte("____1"))
+		cache.Set("____2", []byte("____2"))
+		cache.Set("____3", []byte("____3"))
+
+		checkRemovals(t, cache, *removals, []removal{
+			{"____0", "____0", ReasonEvicted},
+		})
+	}
+}
+
+// Check that expired bindings are reported both on access and on sweeps
+func TestExpiryRemoval(t *testing.T) {
+	clock := newFakeClock()
+	for _, cache := range newExpiringCaches(1024, clock) {
+		removals := recordRemovals(cache.(notifyingCache))
+
+		cache.SetWithTTL("accessed", []byte("1"), time.Second)
+		cache.SetWithTTL("swept", []byte("2"), time.Second)
+		clock.Advance(time.Second)
+
+		cache.Get("accessed")
+		cache.RemoveExpired()
+
+		checkRemovals(t, cache, *removals, []removal{
+			{"accessed", "1", ReasonExpired},
+			{"swept", "2", ReasonExpired},
+		})
+	}
+}
+
+// Check that ARC reports demotions into its ghost lists but not promotions
+// from t1 to t2
+func TestGhostDemotionRemovalArc(t *testing.T) {
+	arc := NewArc(30)
+	removals := recordRemovals(arc)
+
+	arc.Set("____0", []byte("____0"))
+	arc.Set("____1", []byte("____1"))
+	arc.Get("____0")
+	arc.Get("____1")
+	if len(*removals) != 0 {
+		t.Errorf("ARC reported a promotion from t1 to t2. Got %v, Expected %v", *removals, []removal{})
+		t.FailNow()
+	}
+
+	arc.Replace("____2")
+	if ok := arc.policy.b2.contains("____0"); !ok {
+		t.Errorf("Replace did not demote the LRU end of t2 into b2. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	checkRemovals(t, arc, *removals, []removal{
+		{"____0", "____0", ReasonEvicted},
+	})
+
+	arc.Set("____2", []byte("____2"))
+	arc.Replace("____3")
+	if ok := arc.policy.b1.contains("____2"); !ok {
+		t.Errorf("Replace did not demote the LRU end of t1 into b1. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	checkRemovals(t, arc, *removals, []removal{
+		{"____0", "____0", ReasonEvicted},
+		{"____2", "____2", ReasonEvicted},
+	})
+
+	// Ghost entries carry no value, so removing one is not reported
+	arc.Remove("____0")
+	if len(*removals) != 2 {
+		t.Errorf("ARC reported removal of a ghost entry. Got %v", *removals)
+		t.FailNow()
+	}
+}
+
+// Check that Synchronized and Sharded caches forward callbacks
+func TestForwardingRemoval(t *testing.T) {
+	caches := []notifyingCache{
+		Synchronized(NewLru(1024)).(notifyingCache),
+		NewSharded(4, 1024, func(limit int) Cache { return NewLru(limit) }),
+	}
+	for _, cache := range caches {
+		removals := recordRemovals(cache)
+		cache.Set("key", []byte("value"))
+		cache.Remove("key")
+
+		checkRemovals(t, cache, *removals, []removal{
+			{"key", "value", ReasonRemoved},
+		})
+	}
+}
+
+// Check that reasons have readable names
+func TestStringRemoval(t *testing.T) {
+	names := map[RemovalReason]string{
+		ReasonEvicted:  "evicted",
+		ReasonRemoved:  "removed",
+		ReasonReplaced: "replaced",
+		ReasonEmptied:  "emptied",
+		ReasonExpired:  "expired",
+	}
+	for reason, name := range names {
+		if reason.String() != name {
+			t.Errorf("Wrong name for reason %d. Got %v, Expected %v", int(reason), reason.String(), name)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package cache
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+const (
+	// DefaultSmallFraction is the share of an S3FIFO's capacity the small
+	// queue may hold before it is preferred for eviction, as recommended by
+	// the S3-FIFO paper.
+	DefaultSmallFraction = 0.1
+
+	// s3MaxFreq is the largest access count an S3FIFO keeps for a binding,
+	// which fits in two bits.
+	s3MaxFreq = 3
+)
+
+// An s3Entry is a binding in an S3FIFO along with its access count
+type s3Entry struct {
+	key    string
+	value  []byte
+	freq   uint32        // Accesses since insertion or the last sweep, up to s3MaxFreq; only accessed atomically
+	inMain bool          // Whether the binding is in the main queue
+	node   *list.Element // Element of the queue holding the entry
+}
+
+// An S3FIFO is a fixed-size in-memory cache using S3-FIFO eviction (Yang et
+// al., SOSP '23), built from three FIFO queues. A new binding enters the
+// small queue. When it reaches the tail of the small queue, it moves to the
+// main queue if it was accessed while in the small queue, and is evicted and
+// remembered in the ghost queue otherwise. A key set again while remembered
+// goes straight into the main queue. The main queue works like CLOCK with a
+// small access count instead of a reference bit, so most one-hit wonders
+// leave through the small queue without disturbing the main one.
+//
+// A hit only increments the binding's access count and the hit counters, all
+// atomically, so hits never move bindings or write to the queues, and
+// concurrent Gets may share a read lock (see Synchronized). All sizes are in
+// bytes; the ghost queue remembers bindings up to the size of the main queue.
+type S3FIFO struct {
+	lookups lookupCounts // Hits and misses, first so that they are 64-bit aligned
+
+	cachedValues map[string]*s3Entry // Map containing key-entry pairings
+	small        list.List           // Small queue, newest at the front
+	main         list.List           // Main queue, newest at the front
+	ghost        *ghostList          // Keys evicted from the small queue
+
+	capacity              int // To hold the capacity of the cache
+	smallCapacity         int // Bytes the small queue may hold before it is preferred for eviction
+	smallSize             int // Bytes held by the small queue
+	currentlyUsedCapacity int // Currently used capacity of the cache
+
+	stats   Stats            // Every other counter for the cache
+	onEvict EvictionCallback // Called whenever a binding leaves the cache
+}
+
+// NewS3Fifo returns a pointer to a new S3FIFO with a capacity to store limit
+// bytes, with a small queue of DefaultSmallFraction of it.
+func NewS3Fifo(limit int) *S3FIFO {
+	s3 := &S3FIFO{cachedValues: make(map[string]*s3Entry), ghost: newGhostList(), capacity: limit, stats: Stats{}}
+	s3.SetSmallFraction(DefaultSmallFraction)
+	return s3
+}
+
+func init() {
+	Register("s3fifo", func(limit int) Cache { return NewS3Fifo(limit) })
+}
+
+// SetSmallFraction sets the share of the capacity the small queue may hold
+// before it is preferred for eviction. The ghost queue remembers bindings up
+// to the rest of the capacity. fraction is clamped to [0, 1].
+func (s3 *S3FIFO) SetSmallFraction(fraction float64) {
+	s3.smallCapacity = int(clampFraction(fraction) * float64(s3.capacity))
+	s3.trimGhost()
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the S3FIFO,
+// replacing any previously registered callback. A binding evicted into the
+// ghost queue is reported as evicted, since its value is dropped.
+func (s3 *S3FIFO) OnEvict(fn EvictionCallback) {
+	s3.onEvict = fn
+}
+
+// notify records in the stats that a binding left the S3FIFO, and reports it
+// to the eviction callback, if any.
+func (s3 *S3FIFO) notify(entry *s3Entry, reason RemovalReason) {
+	s3.stats.recordRemoval(reason)
+	if s3.onEvict != nil {
+		s3.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this S3FIFO can store
+func (s3 *S3FIFO) MaxStorage() int {
+	return s3.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this S3FIFO
+func (s3 *S3FIFO) RemainingStorage() int {
+	return s3.capacity - s3.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not count as an access or update the stats.
+// ok is true if a value was found and false otherwise.
+func (s3 *S3FIFO) Peek(key string) (value []byte, ok bool) {
+	entry, ok := s3.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and
+// counts an access to it. Get only reads the queues, so it is safe to call
+// concurrently with other Gets.
+// ok is true if a value was found and false otherwise.
+func (s3 *S3FIFO) Get(key string) (value []byte, ok bool) {
+	entry, ok := s3.cachedValues[key]
+	if !ok {
+		s3.lookups.recordMiss()
+		return nil, false
+	}
+
+	entry.access()
+	s3.lookups.recordHit(entry.value)
+	return entry.value, true
+}
+
+// concurrentGet marks S3FIFO.Get as safe to call concurrently with other Gets.
+func (s3 *S3FIFO) concurrentGet() {}
+
+// access counts an access to entry, up to s3MaxFreq.
+func (entry *s3Entry) access() {
+	for {
+		freq := atomic.LoadUint32(&entry.freq)
+		if freq >= s3MaxFreq || atomic.CompareAndSwapUint32(&entry.freq, freq, freq+1) {
+			return
+		}
+	}
+}
+
+// Remove removes and returns the value associated with the given key, if it
+// exists. A key remembered in the ghost queue is forgotten, but is not a
+// binding.
+// ok is true if a value was found and false otherwise
+func (s3 *S3FIFO) Remove(key string) (value []byte, ok bool) {
+	s3.ghost.remove(key)
+
+	entry, ok := s3.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+
+	s3.unlink(entry)
+	s3.notify(entry, ReasonRemoved)
+	return entry.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. Overwriting a binding counts as an access to it and never
+// evicts it. Returns true if the binding was added successfully, else false.
+func (s3 *S3FIFO) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > s3.capacity {
+		s3.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if entry, ok := s3.cachedValues[key]; ok {
+		replaced := s3Entry{key: entry.key, value: entry.value}
+		entry.access()
+
+		growth := len(value) - len(entry.value)
+		for s3.RemainingStorage() < growth {
+			s3.evict(entry)
+		}
+
+		entry.value = value
+		s3.currentlyUsedCapacity += growth
+		if !entry.inMain {
+			s3.smallSize += growth
+		}
+		s3.stats.Sets += 1
+		s3.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	// Forget key before making room, since that may trim the ghost queue
+	_, remembered := s3.ghost.remove(key)
+	for s3.RemainingStorage() < currentObjectSize {
+		s3.evict(nil)
+	}
+
+	entry := &s3Entry{key: key, value: value, inMain: remembered}
+	if remembered {
+		entry.node = s3.main.PushFront(entry)
+	} else {
+		entry.node = s3.small.PushFront(entry)
+		s3.smallSize += currentObjectSize
+	}
+	s3.cachedValues[key] = entry
+	s3.currentlyUsedCapacity += currentObjectSize
+	s3.stats.Sets += 1
+	s3.stats.MissBytes += len(value)
+	return true
+}
+
+// Empty removes every binding from the S3FIFO and forgets its ghost queue.
+func (s3 *S3FIFO) Empty() {
+	if s3.onEvict != nil {
+		for _, entry := range s3.cachedValues {
+			s3.notify(entry, ReasonEmptied)
+		}
+	}
+	s3.cachedValues = make(map[string]*s3Entry)
+	s3.small.Init()
+	s3.main.Init()
+	s3.ghost.reset()
+	s3.smallSize = 0
+	s3.currentlyUsedCapacity = 0
+}
+
+// Evict removes one binding from the S3FIFO, as if to make room for a new
+// one, and returns its key.
+// ok is false if the S3FIFO was already empty.
+func (s3 *S3FIFO) Evict() (key string, ok bool) {
+	if len(s3.cachedValues) == 0 {
+		return "", false
+	}
+	return s3.evict(nil).key, true
+}
+
+// evict evicts a binding other than skip and returns it. It takes bindings
+// from the tail of the small queue while that queue is over its share of the
+// capacity or the main queue is empty, moving those accessed since insertion
+// to the main queue; otherwise it sweeps the main queue, giving bindings
+// accessed since the last sweep another pass. skip always survives, as if it
+// had been accessed. There must be a binding other than skip.
+func (s3 *S3FIFO) evict(skip *s3Entry) *s3Entry {
+	for {
+		if s3.small.Len() > 0 && (s3.smallSize >= s3.smallCapacity || s3.main.Len() == 0) {
+			entry := s3.small.Back().Value.(*s3Entry)
+			if atomic.LoadUint32(&entry.freq) > 0 || entry == skip {
+				s3.small.Remove(entry.node)
+				s3.smallSize -= len(entry.key) + len(entry.value)
+				atomic.StoreUint32(&entry.freq, 0)
+				entry.inMain = true
+				entry.node = s3.main.PushFront(entry)
+				continue
+			}
+
+			s3.unlink(entry)
+			s3.ghost.push(entry.key, len(entry.key)+len(entry.value))
+			s3.trimGhost()
+			s3.notify(entry, ReasonEvicted)
+			return entry
+		}
+
+		entry := s3.main.Back().Value.(*s3Entry)
+		if freq := atomic.LoadUint32(&entry.freq); freq > 0 || entry == skip {
+			if freq > 0 {
+				atomic.StoreUint32(&entry.freq, freq-1)
+			}
+			s3.main.MoveToFront(entry.node)
+			continue
+		}
+
+		s3.unlink(entry)
+		s3.notify(entry, ReasonEvicted)
+		return entry
+	}
+}
+
+// trimGhost forgets the oldest keys in the ghost queue until it is within the
+// size of the main queue.
+func (s3 *S3FIFO) trimGhost() {
+	for s3.ghost.size > s3.capacity-s3.smallCapacity {
+		s3.ghost.popOldest()
+	}
+}
+
+// unlink removes entry from the S3FIFO without notifying the eviction callback.
+func (s3 *S3FIFO) unlink(entry *s3Entry) {
+	if entry.inMain {
+		s3.main.Remove(entry.node)
+	} else {
+		s3.small.Remove(entry.node)
+		s3.smallSize -= len(entry.key) + len(entry.value)
+	}
+	delete(s3.cachedValues, entry.key)
+	s3.currentlyUsedCapacity -= len(entry.key) + len(entry.value)
+}
+
+// Len returns the number of bindings in the S3FIFO.
+func (s3 *S3FIFO) Len() int {
+	return len(s3.cachedValues)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+// The result is a copy, since hits and misses are counted separately.
+func (s3 *S3FIFO) Stats() *Stats {
+	return s3.lookups.with(s3.stats)
+}
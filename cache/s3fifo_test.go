@@ -0,0 +1,126 @@
+/******************************************************************************
+ * s3fifo_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for s3fifo.go. The Cache contract S3FIFO shares with
+ *    every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/workload"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a binding accessed while in the small queue moves to the main
+// queue when it reaches the tail, and that one never accessed is evicted and
+// remembered in the ghost queue
+func TestSmallQueueS3Fifo(t *testing.T) {
+	s3 := NewS3Fifo(40)
+	s3.SetSmallFraction(0.5)
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("____%d", i)
+		s3.Set(key, []byte(key))
+	}
+	s3.Get("____0")
+
+	removals := recordRemovals(s3)
+	s3.Set("____4", []byte("____4"))
+	checkRemovals(t, s3, *removals, []removal{
+		{"____1", "____1", ReasonEvicted},
+	})
+	if entry := s3.cachedValues["____0"]; !entry.inMain || entry.freq != 0 {
+		t.Errorf("Accessed binding not moved to the main queue. Got %v, %v, Expected %v, %v", entry.inMain, entry.freq, true, 0)
+		t.FailNow()
+	}
+	if !s3.ghost.contains("____1") {
+		t.Errorf("Evicted binding not remembered. Got %v, Expected %v", false, true)
+		t.FailNow()
+	}
+
+	// A key set again while remembered goes straight into the main queue
+	s3.Set("____1", []byte("____1"))
+	if entry := s3.cachedValues["____1"]; entry == nil || !entry.inMain {
+		t.Errorf("Remembered key not set in the main queue. Got %v, Expected %v", entry, "____1")
+		t.FailNow()
+	}
+	if s3.ghost.contains("____1") || s3.RemainingStorage() != 0 {
+		t.Errorf("Wrong state after ghost hit. Got %v, %v, Expected %v, %v", s3.ghost.contains("____1"), s3.RemainingStorage(), false, 0)
+		t.FailNow()
+	}
+}
+
+// Check that the main queue gives a binding one more pass per access, up to
+// the maximum count
+func TestMainQueueS3Fifo(t *testing.T) {
+	s3 := NewS3Fifo(30)
+	s3.SetSmallFraction(0)
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("____%d", i)
+		s3.Set(key, []byte(key))
+		s3.Evict()
+		s3.Set(key, []byte(key))
+	}
+	if s3.main.Len() != 3 {
+		t.Errorf("Setup did not fill the main queue. Got %v, Expected %v", s3.main.Len(), 3)
+		t.FailNow()
+	}
+
+	for i := 0; i < 5; i++ {
+		s3.Get("____0")
+	}
+	s3.Get("____1")
+	if s3.cachedValues["____0"].freq != s3MaxFreq {
+		t.Errorf("Access count not capped. Got %v, Expected %v", s3.cachedValues["____0"].freq, s3MaxFreq)
+		t.FailNow()
+	}
+
+	removals := recordRemovals(s3)
+	for i := 0; i < 4; i++ {
+		s3.Evict()
+	}
+	checkRemovals(t, s3, *removals, []removal{
+		{"____2", "____2", ReasonEvicted},
+		{"____1", "____1", ReasonEvicted},
+		{"____0", "____0", ReasonEvicted},
+	})
+}
+
+// Check that small fractions outside [0, 1] are clamped to it
+func TestSmallFractionClampedS3Fifo(t *testing.T) {
+	s3 := NewS3Fifo(40)
+	for _, c := range []struct {
+		fraction      float64
+		smallCapacity int
+	}{{-0.5, 0}, {1.5, 40}} {
+		s3.SetSmallFraction(c.fraction)
+		if s3.smallCapacity != c.smallCapacity {
+			t.Errorf("Small fraction %v not clamped. Got %v, Expected %v", c.fraction, s3.smallCapacity, c.smallCapacity)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that S3FIFO's hit ratio is at least LRU's on the benchmark workloads
+func TestComparableToLruS3Fifo(t *testing.T) {
+	for name, newConfig := range benchmarkWorkloads() {
+		events := workload.New(newConfig()).Events(50000)
+		s3, lru := NewS3Fifo(benchmarkCapacity), NewLru(benchmarkCapacity)
+		replayEvents(s3, events)
+		replayEvents(lru, events)
+
+		s3Ratio, lruRatio := s3.Stats().HitRatio(), lru.Stats().HitRatio()
+		if s3Ratio < lruRatio-0.01 {
+			t.Errorf("%s: S3FIFO hit ratio below LRU's. Got %v, Expected at least %v", name, s3Ratio, lruRatio)
+			t.FailNow()
+		}
+	}
+}
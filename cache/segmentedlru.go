@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// DefaultProtectedFraction is the share of a SegmentedLRU's capacity its
+// protected segment may hold.
+const DefaultProtectedFraction = 0.8
+
+// A SegmentedLRU is a fixed-size in-memory cache with segmented LRU eviction
+// (Karedla et al., IEEE Computer '94). A new binding enters the probationary
+// segment. A hit there, the binding's second use, promotes it to the
+// protected segment, and the protected segment's least recently used binding
+// goes back to the front of probation when the protected segment overflows.
+// Victims are taken from the back of probation, so bindings used only once,
+// such as those of a sequential scan, are evicted before any binding that has
+// been used twice.
+type SegmentedLRU struct {
+	capacity          int // To hold the capacity of the cache
+	protectedCapacity int // Bytes the protected segment may hold
+
+	probation *LRU // To hold bindings used once since they were set or demoted
+	protected *LRU // To hold bindings used again while in probation
+
+	stats   Stats            // Hits and misses for the cache
+	onEvict EvictionCallback // Called whenever a binding leaves the cache
+}
+
+// NewSegmentedLru returns a pointer to a new SegmentedLRU with a capacity to
+// store limit bytes, with a protected segment of DefaultProtectedFraction of it.
+func NewSegmentedLru(limit int) *SegmentedLRU {
+	slru := &SegmentedLRU{capacity: limit, probation: NewLru(limit), protected: NewLru(limit), stats: Stats{}}
+	slru.SetProtectedFraction(DefaultProtectedFraction)
+	return slru
+}
+
+func init() {
+	Register("slru", func(limit int) Cache { return NewSegmentedLru(limit) })
+}
+
+// SetProtectedFraction sets the share of the capacity the protected segment
+// may hold, demoting bindings from it if it already holds more. Probation
+// may use whatever the protected segment leaves free. fraction is clamped to
+// [0, 1].
+func (slru *SegmentedLRU) SetProtectedFraction(fraction float64) {
+	slru.protectedCapacity = int(clampFraction(fraction) * float64(slru.capacity))
+	slru.balance()
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the
+// SegmentedLRU, replacing any previously registered callback. Moves between
+// segments are not reported.
+func (slru *SegmentedLRU) OnEvict(fn EvictionCallback) {
+	slru.onEvict = fn
+}
+
+// notify records in the stats that a binding left the SegmentedLRU, and
+// reports it to the eviction callback, if any.
+func (slru *SegmentedLRU) notify(currMapping mapping, reason RemovalReason) {
+	slru.stats.recordRemoval(reason)
+	if slru.onEvict != nil {
+		slru.onEvict(currMapping.key, currMapping.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this SegmentedLRU can store
+func (slru *SegmentedLRU) MaxStorage() int {
+	return slru.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this SegmentedLRU
+func (slru *SegmentedLRU) RemainingStorage() int {
+	return slru.capacity - slru.usedCapacity()
+}
+
+// usedCapacity returns the number of bytes held by both segments.
+func (slru *SegmentedLRU) usedCapacity() int {
+	return slru.probation.currentlyUsedCapacity + slru.protected.currentlyUsedCapacity
+}
+
+// lookup returns the binding for key and the segment holding it, if it exists.
+func (slru *SegmentedLRU) lookup(key string) (mapping, *LRU) {
+	if currMapping, ok := slru.probation.cachedValues[key]; ok {
+		return currMapping, slru.probation
+	}
+	if currMapping, ok := slru.protected.cachedValues[key]; ok {
+		return currMapping, slru.protected
+	}
+	return mapping{}, nil
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not count as a use or update the stats.
+// ok is true if a value was found and false otherwise.
+func (slru *SegmentedLRU) Peek(key string) (value []byte, ok bool) {
+	currMapping, segment := slru.lookup(key)
+	if segment == nil {
+		return nil, false
+	}
+	return currMapping.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and
+// makes it the most recently used binding of the protected segment.
+// ok is true if a value was found and false otherwise.
+func (slru *SegmentedLRU) Get(key string) (value []byte, ok bool) {
+	currMapping, segment := slru.lookup(key)
+	if segment == nil {
+		slru.stats.Misses += 1
+		return nil, false
+	}
+
+	slru.touch(currMapping, segment)
+	slru.stats.Hits += 1
+	slru.stats.HitBytes += len(currMapping.value)
+	return currMapping.value, true
+}
+
+// touch makes the binding the most recently used of the protected segment,
+// promoting it from probation if needed.
+func (slru *SegmentedLRU) touch(currMapping mapping, segment *LRU) {
+	if segment == slru.protected {
+		slru.protected.access(currMapping.key)
+		return
+	}
+
+	slru.probation.removeMapping(currMapping.key)
+	slru.protected.insert(currMapping.key, currMapping.value, time.Time{})
+	slru.balance()
+}
+
+// balance demotes the least recently used bindings of the protected segment
+// to the front of probation until the protected segment is within its share.
+func (slru *SegmentedLRU) balance() {
+	for slru.protected.currentlyUsedCapacity > slru.protectedCapacity {
+		currMapping, _ := slru.protected.evict()
+		slru.probation.insert(currMapping.key, currMapping.value, time.Time{})
+	}
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (slru *SegmentedLRU) Remove(key string) (value []byte, ok bool) {
+	_, segment := slru.lookup(key)
+	if segment == nil {
+		return nil, false
+	}
+
+	currMapping, _ := segment.removeMapping(key)
+	slru.notify(currMapping, ReasonRemoved)
+	return currMapping.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. A new binding enters probation; overwriting a binding counts
+// as a use of it and never evicts it.
+// Returns true if the binding was added successfully, else false.
+func (slru *SegmentedLRU) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > slru.capacity {
+		slru.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if replaced, segment := slru.lookup(key); segment != nil {
+		slru.touch(replaced, segment)
+		_, segment = slru.lookup(key)
+
+		// Take the binding out while making room, so that it cannot be
+		// chosen as a victim
+		segment.removeMapping(key)
+		for slru.RemainingStorage() < currentObjectSize {
+			slru.Evict()
+		}
+		segment.insert(key, value, time.Time{})
+		slru.balance()
+
+		slru.stats.Sets += 1
+		slru.notify(replaced, ReasonReplaced)
+		return true
+	}
+
+	for slru.RemainingStorage() < currentObjectSize {
+		slru.Evict()
+	}
+	slru.probation.insert(key, value, time.Time{})
+	slru.stats.Sets += 1
+	slru.stats.MissBytes += len(value)
+	return true
+}
+
+// Empty removes every binding from the SegmentedLRU.
+func (slru *SegmentedLRU) Empty() {
+	for _, segment := range []*LRU{slru.probation, slru.protected} {
+		if slru.onEvict != nil {
+			for _, currMapping := range segment.cachedValues {
+				slru.notify(currMapping, ReasonEmptied)
+			}
+		}
+		segment.reset()
+	}
+}
+
+// Evict removes the least recently used binding of probation, or of the
+// protected segment if probation is empty, and returns its key.
+// ok is false if the SegmentedLRU was already empty.
+func (slru *SegmentedLRU) Evict() (key string, ok bool) {
+	currMapping, ok := slru.evict()
+	if !ok {
+		return "", false
+	}
+	slru.notify(currMapping, ReasonEvicted)
+	return currMapping.key, true
+}
+
+// evict removes and returns the next victim without notifying the eviction
+// callback.
+func (slru *SegmentedLRU) evict() (mapping, bool) {
+	if currMapping, ok := slru.probation.evict(); ok {
+		return currMapping, true
+	}
+	return slru.protected.evict()
+}
+
+// victimAfter returns the element holding the victim that follows elem, or
+// the next victim if elem is nil, or nil if there is none: probation from
+// least recently used, then the protected segment.
+func (slru *SegmentedLRU) victimAfter(elem *list.Element) *list.Element {
+	switch {
+	case elem == nil && slru.probation.Len() > 0:
+		return slru.probation.policy.order.Back()
+	case elem == nil:
+		return slru.protected.policy.order.Back()
+	case elem.Prev() != nil:
+		return elem.Prev()
+	case slru.probation.Len() > 0 && elem == slru.probation.policy.order.Front():
+		return slru.protected.policy.order.Back()
+	default:
+		return nil
+	}
+}
+
+// Len returns the number of bindings in the SegmentedLRU.
+func (slru *SegmentedLRU) Len() int {
+	return slru.probation.Len() + slru.protected.Len()
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (slru *SegmentedLRU) Stats() *Stats {
+	return &slru.stats
+}
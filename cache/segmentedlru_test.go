@@ -0,0 +1,148 @@
+/******************************************************************************
+ * segmentedlru_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for segmentedlru.go. The Cache contract SegmentedLRU
+ *    shares with every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a binding is promoted to the protected segment on its second
+// use, and that it is then evicted after bindings used only once
+func TestPromoteSegmentedLru(t *testing.T) {
+	slru := NewSegmentedLru(40)
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("____%d", i)
+		slru.Set(key, []byte(key))
+	}
+	if slru.probation.Len() != 4 || slru.protected.Len() != 0 {
+		t.Errorf("Wrong segment lengths. Got %v, %v, Expected %v, %v", slru.probation.Len(), slru.protected.Len(), 4, 0)
+		t.FailNow()
+	}
+
+	slru.Get("____0")
+	if _, ok := slru.protected.Peek("____0"); !ok {
+		t.Errorf("Binding not promoted on its second use. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+
+	for i := 0; i < 3; i++ {
+		key, _ := slru.Evict()
+		if expected := fmt.Sprintf("____%d", i+1); key != expected {
+			t.Errorf("Evicted the wrong binding. Got %v, Expected %v", key, expected)
+			t.FailNow()
+		}
+	}
+	if key, _ := slru.Evict(); key != "____0" {
+		t.Errorf("Evicted the wrong binding. Got %v, Expected %v", key, "____0")
+		t.FailNow()
+	}
+}
+
+// Check that the least recently used protected binding is demoted to the
+// front of probation when the protected segment overflows
+func TestDemoteSegmentedLru(t *testing.T) {
+	slru := NewSegmentedLru(100)
+	slru.SetProtectedFraction(0.2)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("____%d", i)
+		slru.Set(key, []byte(key))
+	}
+	for i := 0; i < 3; i++ {
+		slru.Get(fmt.Sprintf("____%d", i))
+	}
+
+	if slru.protected.currentlyUsedCapacity > slru.protectedCapacity || slru.protected.Len() != 2 {
+		t.Errorf("Protected segment not bounded. Got %v bytes, %v bindings, Expected at most %v bytes, %v bindings", slru.protected.currentlyUsedCapacity, slru.protected.Len(), slru.protectedCapacity, 2)
+		t.FailNow()
+	}
+	if front := slru.probation.policy.order.Front().Value.(lruEntry).key; front != "____0" {
+		t.Errorf("Demoted binding not at the front of probation. Got %v, Expected %v", front, "____0")
+		t.FailNow()
+	}
+	if slru.Len() != 5 || slru.RemainingStorage() != 50 {
+		t.Errorf("Demotion changed the contents. Got %v bindings, %v bytes free, Expected %v, %v", slru.Len(), slru.RemainingStorage(), 5, 50)
+		t.FailNow()
+	}
+}
+
+// Check that a sequential scan of keys used once does not evict keys that
+// were hit, where an LRU of the same size loses them
+func TestScanResistanceSegmentedLru(t *testing.T) {
+	slru := NewSegmentedLru(100)
+	lru := NewLru(100)
+	for _, cache := range []Cache{slru, lru} {
+		for i := 0; i < 5; i++ {
+			key := fmt.Sprintf("____%d", i)
+			cache.Set(key, []byte(key))
+			cache.Get(key)
+		}
+		for i := 10; i < 100; i++ {
+			key := fmt.Sprintf("___%d", i)
+			cache.Set(key, []byte(key))
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("____%d", i)
+		if value, ok := slru.Get(key); !ok || !bytesEqual(value, []byte(key)) {
+			t.Errorf("Scan evicted a protected binding. Got %v, Expected %v", ok, true)
+			t.FailNow()
+		}
+		if _, ok := lru.Peek(key); ok {
+			t.Errorf("LRU kept %s. Got %v, Expected %v", key, ok, false)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that protected fractions outside [0, 1] are clamped to it
+func TestProtectedFractionClampedSegmentedLru(t *testing.T) {
+	slru := NewSegmentedLru(100)
+	for _, c := range []struct {
+		fraction          float64
+		protectedCapacity int
+	}{{-0.5, 0}, {1.5, 100}} {
+		slru.SetProtectedFraction(c.fraction)
+		if slru.protectedCapacity != c.protectedCapacity {
+			t.Errorf("Protected fraction %v not clamped. Got %v, Expected %v", c.fraction, slru.protectedCapacity, c.protectedCapacity)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that overwriting a binding counts as a use of it
+func TestOverwriteSegmentedLru(t *testing.T) {
+	slru := NewSegmentedLru(30)
+	slru.Set("____0", []byte("____0"))
+	slru.Set("____1", []byte("____1"))
+	if ok := slru.Set("____0", []byte("_________0")); !ok {
+		t.Errorf("Failed to overwrite binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if _, ok := slru.protected.Peek("____0"); !ok {
+		t.Errorf("Overwritten binding not promoted. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+
+	slru.Set("____2", []byte("____2"))
+	if _, ok := slru.Peek("____1"); ok {
+		t.Errorf("Binding used once not evicted first. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if value, ok := slru.Peek("____0"); !ok || !bytesEqual(value, []byte("_________0")) {
+		t.Errorf("Overwritten binding lost. Got %v, Expected %v", value, []byte("_________0"))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"time"
+)
+
+// A Sharded cache spreads its keys across several independent caches, each
+// guarded by its own lock, so that goroutines working on different keys
+// rarely contend with each other
+type Sharded struct {
+	shards   []Cache // Independently locked caches the keys are hashed across
+	capacity int     // To hold the capacity of the cache, summed over all shards
+}
+
+// NewSharded returns a pointer to a new Sharded cache with a capacity to store
+// limit bytes, split as evenly as possible across the given number of shards.
+// newShard builds the cache for a single shard from its share of the limit.
+// Since a binding lives in exactly one shard, bindings larger than a shard's
+// share of the limit are rejected.
+func NewSharded(shards int, limit int, newShard func(limit int) Cache) *Sharded {
+	if shards < 1 {
+		shards = 1
+	}
+
+	sharded := &Sharded{shards: make([]Cache, shards), capacity: limit}
+	for i := range sharded.shards {
+		shardLimit := limit / shards
+		if i < limit%shards {
+			shardLimit++
+		}
+		sharded.shards[i] = Synchronized(newShard(shardLimit))
+	}
+	return sharded
+}
+
+// shard returns the cache responsible for the given key, using 32-bit FNV-1a.
+func (sharded *Sharded) shard(key string) Cache {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return sharded.shards[hash%uint32(len(sharded.shards))]
+}
+
+// MaxStorage returns the maximum number of bytes this Sharded cache can store
+func (sharded *Sharded) MaxStorage() int {
+	return sharded.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available across all shards
+func (sharded *Sharded) RemainingStorage() int {
+	remaining := 0
+	for _, shard := range sharded.shards {
+		remaining += shard.RemainingStorage()
+	}
+	return remaining
+}
+
+// Get returns the value associated with the given key, if it exists.
+// This operation counts as a "use" for that key-value pair
+// ok is true if a value was found and false otherwise.
+func (sharded *Sharded) Get(key string) (value []byte, ok bool) {
+	return sharded.shard(key).Get(key)
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (sharded *Sharded) Remove(key string) (value []byte, ok bool) {
+	return sharded.shard(key).Remove(key)
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// from the key's shard to make room. Returns true if the binding was added
+// successfully, else false.
+func (sharded *Sharded) Set(key string, value []byte) bool {
+	return sharded.shard(key).Set(key, value)
+}
+
+// Peek returns the value associated with the given key, if it exists, without
+// updating the "recently used"-ness of the key.
+func (sharded *Sharded) Peek(key string) (value []byte, ok bool) {
+	return sharded.shard(key).Peek(key)
+}
+
+// SetWithTTL sets a binding in the key's shard that expires once ttl has
+// elapsed. It returns false if the shards do not support expiration.
+func (sharded *Sharded) SetWithTTL(key string, value []byte, ttl time.Duration) bool {
+	return sharded.shard(key).(Expirer).SetWithTTL(key, value, ttl)
+}
+
+// RemoveExpired removes every expired binding from every shard and returns how
+// many were removed.
+func (sharded *Sharded) RemoveExpired() int {
+	removed := 0
+	for _, shard := range sharded.shards {
+		removed += shard.(Expirer).RemoveExpired()
+	}
+	return removed
+}
+
+// OnEvict registers fn with every shard. Since shards are locked
+// independently, fn may be called from several goroutines at once.
+func (sharded *Sharded) OnEvict(fn EvictionCallback) {
+	for _, shard := range sharded.shards {
+		shard.(EvictionNotifier).OnEvict(fn)
+	}
+}
+
+// Empty removes every binding from every shard.
+func (sharded *Sharded) Empty() {
+	for _, shard := range sharded.shards {
+		shard.Empty()
+	}
+}
+
+// Len returns the number of bindings across all shards.
+func (sharded *Sharded) Len() int {
+	length := 0
+	for _, shard := range sharded.shards {
+		length += shard.Len()
+	}
+	return length
+}
+
+// Stats returns the statistics of all shards summed together. The result is a
+// fresh copy; shards are read one at a time, so it is not an atomic snapshot of
+// the whole cache.
+func (sharded *Sharded) Stats() *Stats {
+	stats := Stats{}
+	for _, shard := range sharded.shards {
+		stats.add(shard.Stats())
+	}
+	return &stats
+}
@@ -0,0 +1,216 @@
+/******************************************************************************
+ * sharded_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -bench Sharded -cpu 1,2,4,8`
+ * Description:
+ *    A unit testing suite and throughput benchmarks for sharded.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that NewSharded() splits the byte budget across its shards
+func TestNewSharded(t *testing.T) {
+	capacity := 1030
+	sharded := NewSharded(8, capacity, func(limit int) Cache { return NewLru(limit) })
+	checkCapacity(t, sharded, capacity)
+
+	if len(sharded.shards) != 8 {
+		t.Errorf("NewSharded returned wrong number of shards. Got %v, Expected %v", len(sharded.shards), 8)
+		t.FailNow()
+	}
+
+	total := 0
+	for i, shard := range sharded.shards {
+		expected := 128
+		if i < 6 {
+			expected = 129
+		}
+		if shard.MaxStorage() != expected {
+			t.Errorf("Shard %d has wrong MaxStorage. Got %v, Expected %v", i, shard.MaxStorage(), expected)
+			t.FailNow()
+		}
+		total += shard.MaxStorage()
+	}
+	if total != capacity {
+		t.Errorf("Shards do not add up to the limit. Got %v, Expected %v", total, capacity)
+		t.FailNow()
+	}
+
+	if sharded.RemainingStorage() != capacity {
+		t.Errorf("NewSharded returned wrong remainingStorage on init. Got %v, Expected %v", sharded.RemainingStorage(), capacity)
+		t.FailNow()
+	}
+	if sharded.Len() != 0 {
+		t.Errorf("NewSharded returned wrong length on init. Got %v, Expected %v", sharded.Len(), 0)
+		t.FailNow()
+	}
+}
+
+// Check that bindings are routed to a single shard and can be read back
+func TestRoutingSharded(t *testing.T) {
+	capacity := 4096
+	sharded := NewSharded(4, capacity, func(limit int) Cache { return NewArc(limit) })
+
+	used := 0
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if !sharded.Set(key, []byte(key)) {
+			t.Errorf("Failed to add binding with key: %s", key)
+			t.FailNow()
+		}
+		used += 2 * len(key)
+	}
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		value, ok := sharded.Get(key)
+		if !ok || !bytesEqual(value, []byte(key)) {
+			t.Errorf("Fetched wrong value for key %s. Got %v, Expected %v", key, value, []byte(key))
+			t.FailNow()
+		}
+
+		owners := 0
+		for _, shard := range sharded.shards {
+			if _, ok := shard.Peek(key); ok {
+				owners++
+			}
+		}
+		if owners != 1 {
+			t.Errorf("Key %s stored in wrong number of shards. Got %v, Expected %v", key, owners, 1)
+			t.FailNow()
+		}
+	}
+
+	if sharded.Len() != 50 {
+		t.Errorf("Len wrong after adding bindings. Got %v, Expected %v", sharded.Len(), 50)
+		t.FailNow()
+	}
+	if sharded.RemainingStorage() != capacity-used {
+		t.Errorf("RemainingStorage wrong after adding bindings. Got %v, Expected %v", sharded.RemainingStorage(), capacity-used)
+		t.FailNow()
+	}
+
+	if _, ok := sharded.Remove("key0"); !ok {
+		t.Errorf("Failed to remove binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if _, ok := sharded.Peek("key0"); ok {
+		t.Errorf("Peeked a removed binding. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+
+	sharded.Empty()
+	if sharded.Len() != 0 || sharded.RemainingStorage() != capacity {
+		t.Errorf("Empty left bindings behind. Got Len %v, RemainingStorage %v", sharded.Len(), sharded.RemainingStorage())
+		t.FailNow()
+	}
+}
+
+// Check that Stats() sums the statistics of every shard
+func TestStatsSharded(t *testing.T) {
+	sharded := NewSharded(4, 1024, func(limit int) Cache { return NewLru(limit) })
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		sharded.Set(key, []byte(key))
+		sharded.Get(key)
+		sharded.Get("miss" + key)
+	}
+
+	stats := sharded.Stats()
+	if stats.Hits != 10 {
+		t.Errorf("Hits wrong. Got %v, Expected %v", stats.Hits, 10)
+		t.FailNow()
+	}
+	if stats.Misses != 10 {
+		t.Errorf("Misses wrong. Got %v, Expected %v", stats.Misses, 10)
+		t.FailNow()
+	}
+}
+
+// Hammer a Sharded cache from many goroutines under the race detector
+func TestConcurrentSharded(t *testing.T) {
+	sharded := NewSharded(8, 2048, func(limit int) Cache { return NewLru(limit) })
+
+	var wg sync.WaitGroup
+	for g := 0; g < syncGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < syncIterations; i++ {
+				key := fmt.Sprintf("key%d", (g*syncIterations+i)%syncKeys)
+				switch i % 3 {
+				case 0:
+					sharded.Set(key, []byte(key))
+				case 1:
+					sharded.Get(key)
+				case 2:
+					sharded.Remove(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if sharded.RemainingStorage() < 0 {
+		t.Errorf("RemainingStorage negative. Got %v", sharded.RemainingStorage())
+		t.FailNow()
+	}
+}
+
+/******************************************************************************/
+/*                                Benchmarks                                  */
+/******************************************************************************/
+
+// benchmarkParallel runs a 3:1 Get/Set mix against cache from b.RunParallel
+// goroutines, with GOMAXPROCS set to procs for the duration of the run.
+func benchmarkParallel(b *testing.B, procs int, cache Cache) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		cache.Set(keys[i], []byte(keys[i]))
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%4 == 0 {
+				cache.Set(key, []byte(key))
+			} else {
+				cache.Get(key)
+			}
+			i += 7
+		}
+	})
+}
+
+// Compare a single globally locked LRU with a Sharded LRU as GOMAXPROCS grows.
+// Throughput of the Sharded cache should scale with the number of procs, while
+// the single lock should not.
+func BenchmarkShardedScaling(b *testing.B) {
+	capacity := 1 << 16
+	for _, procs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("Synchronized/procs=%d", procs), func(b *testing.B) {
+			benchmarkParallel(b, procs, Synchronized(NewLru(capacity)))
+		})
+		b.Run(fmt.Sprintf("Sharded/procs=%d", procs), func(b *testing.B) {
+			benchmarkParallel(b, procs, NewSharded(4*procs, capacity, func(limit int) Cache { return NewLru(limit) }))
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// A sieveEntry is a binding in a SIEVE along with its visited bit
+type sieveEntry struct {
+	key     string
+	value   []byte
+	visited uint32 // 1 if used since the hand last passed, else 0; only accessed atomically
+}
+
+// A SIEVE is a fixed-size in-memory cache using SIEVE eviction (Zhang et al.,
+// NSDI '24). Bindings sit in a FIFO queue, newest at the head, and a hit only
+// sets the binding's visited bit and the hit counters, all atomically, so
+// hits never move bindings or write to the queue, and concurrent Gets may
+// share a read lock (see Synchronized). When room is needed, a hand moves from the tail towards the
+// head, clearing the visited bit of each binding it passes and evicting the
+// first unvisited one. Unlike CLOCK, the hand then stays where it is, so
+// bindings that survive a sweep keep their place behind newer ones, which
+// lets new, unpopular bindings be evicted quickly.
+type SIEVE struct {
+	lookups lookupCounts // Hits and misses, first so that they are 64-bit aligned
+
+	cachedValues          map[string]*list.Element // Map containing key-element pairings
+	cachedList            list.List                // Queue of bindings, newest at the front
+	hand                  *list.Element            // The next binding to examine, or nil for the tail
+	capacity              int                      // To hold the capacity of the cache
+	currentlyUsedCapacity int                      // Currently used capacity of the cache
+	stats                 Stats                    // Every other counter for the cache
+	onEvict               EvictionCallback         // Called whenever a binding leaves the cache
+}
+
+// NewSieve returns a pointer to a new SIEVE with a capacity to store limit bytes
+func NewSieve(limit int) *SIEVE {
+	return &SIEVE{cachedValues: make(map[string]*list.Element), capacity: limit, currentlyUsedCapacity: 0, stats: Stats{}}
+}
+
+func init() {
+	Register("sieve", func(limit int) Cache { return NewSieve(limit) })
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the SIEVE,
+// replacing any previously registered callback.
+func (sieve *SIEVE) OnEvict(fn EvictionCallback) {
+	sieve.onEvict = fn
+}
+
+// notify records in the stats that a binding left the SIEVE, and reports it
+// to the eviction callback, if any.
+func (sieve *SIEVE) notify(entry *sieveEntry, reason RemovalReason) {
+	sieve.stats.recordRemoval(reason)
+	if sieve.onEvict != nil {
+		sieve.onEvict(entry.key, entry.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this SIEVE can store
+func (sieve *SIEVE) MaxStorage() int {
+	return sieve.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this SIEVE
+func (sieve *SIEVE) RemainingStorage() int {
+	return sieve.capacity - sieve.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not set the visited bit or update the stats.
+// ok is true if a value was found and false otherwise.
+func (sieve *SIEVE) Peek(key string) (value []byte, ok bool) {
+	elem, ok := sieve.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*sieveEntry).value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and sets
+// its visited bit. Get only reads the queue, so it is safe to call
+// concurrently with other Gets.
+// ok is true if a value was found and false otherwise.
+func (sieve *SIEVE) Get(key string) (value []byte, ok bool) {
+	elem, ok := sieve.cachedValues[key]
+	if !ok {
+		sieve.lookups.recordMiss()
+		return nil, false
+	}
+
+	entry := elem.Value.(*sieveEntry)
+	atomic.StoreUint32(&entry.visited, 1)
+	sieve.lookups.recordHit(entry.value)
+	return entry.value, true
+}
+
+// concurrentGet marks SIEVE.Get as safe to call concurrently with other Gets.
+func (sieve *SIEVE) concurrentGet() {}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (sieve *SIEVE) Remove(key string) (value []byte, ok bool) {
+	elem, ok := sieve.cachedValues[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := sieve.unlink(elem)
+	sieve.notify(entry, ReasonRemoved)
+	return entry.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. A new binding is placed at the head of the queue, unvisited;
+// overwriting a binding sets its visited bit and never evicts it.
+// Returns true if the binding was added successfully, else false.
+func (sieve *SIEVE) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > sieve.capacity {
+		sieve.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if elem, ok := sieve.cachedValues[key]; ok {
+		entry := elem.Value.(*sieveEntry)
+		replaced := sieveEntry{key: entry.key, value: entry.value}
+
+		growth := len(value) - len(entry.value)
+		for sieve.RemainingStorage() < growth {
+			sieve.evict(elem)
+		}
+
+		entry.value = value
+		atomic.StoreUint32(&entry.visited, 1)
+		sieve.currentlyUsedCapacity += growth
+		sieve.stats.Sets += 1
+		sieve.notify(&replaced, ReasonReplaced)
+		return true
+	}
+
+	for sieve.RemainingStorage() < currentObjectSize {
+		if _, ok := sieve.Evict(); !ok {
+			return false
+		}
+	}
+
+	sieve.cachedValues[key] = sieve.cachedList.PushFront(&sieveEntry{key: key, value: value})
+	sieve.currentlyUsedCapacity += currentObjectSize
+	sieve.stats.Sets += 1
+	sieve.stats.MissBytes += len(value)
+	return true
+}
+
+// Empty removes every binding from the SIEVE.
+func (sieve *SIEVE) Empty() {
+	if sieve.onEvict != nil {
+		for _, elem := range sieve.cachedValues {
+			sieve.notify(elem.Value.(*sieveEntry), ReasonEmptied)
+		}
+	}
+	sieve.cachedValues = make(map[string]*list.Element)
+	sieve.cachedList.Init()
+	sieve.hand = nil
+	sieve.currentlyUsedCapacity = 0
+}
+
+// Evict moves the hand towards the head until it finds an unvisited binding,
+// then removes that binding and returns its key.
+// ok is false if the SIEVE was already empty.
+func (sieve *SIEVE) Evict() (key string, ok bool) {
+	if sieve.cachedList.Len() == 0 {
+		return "", false
+	}
+	return sieve.evict(nil).key, true
+}
+
+// evict moves the hand past visited bindings and skip, wrapping from the head
+// back to the tail, evicts the first other binding and returns it. There must
+// be such a binding.
+func (sieve *SIEVE) evict(skip *list.Element) *sieveEntry {
+	elem := sieve.hand
+	for {
+		if elem == nil {
+			elem = sieve.cachedList.Back()
+		}
+		entry := elem.Value.(*sieveEntry)
+		if elem == skip || atomic.LoadUint32(&entry.visited) == 1 {
+			atomic.StoreUint32(&entry.visited, 0)
+			elem = elem.Prev()
+			continue
+		}
+
+		sieve.hand = elem
+		sieve.unlink(elem)
+		sieve.notify(entry, ReasonEvicted)
+		return entry
+	}
+}
+
+// unlink removes elem from the SIEVE, moving the hand past it towards the
+// head if needed, without notifying the eviction callback.
+func (sieve *SIEVE) unlink(elem *list.Element) *sieveEntry {
+	if sieve.hand == elem {
+		sieve.hand = elem.Prev()
+	}
+
+	entry := sieve.cachedList.Remove(elem).(*sieveEntry)
+	delete(sieve.cachedValues, entry.key)
+	sieve.currentlyUsedCapacity -= len(entry.key) + len(entry.value)
+	return entry
+}
+
+// Len returns the number of bindings in the SIEVE.
+func (sieve *SIEVE) Len() int {
+	return len(sieve.cachedValues)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+// The result is a copy, since hits and misses are counted separately.
+func (sieve *SIEVE) Stats() *Stats {
+	return sieve.lookups.with(sieve.stats)
+}
@@ -0,0 +1,93 @@
+/******************************************************************************
+ * sieve_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for sieve.go. The Cache contract SIEVE shares with
+ *    every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/workload"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a hit does not move the binding, and that the hand keeps its
+// place between evictions, so that new, unvisited bindings are evicted before
+// the visited ones it has already passed
+func TestEvictionOrderSieve(t *testing.T) {
+	sieve := NewSieve(40)
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("____%d", i)
+		sieve.Set(key, []byte(key))
+	}
+	sieve.Get("____0")
+	sieve.Get("____2")
+	if tail := sieve.cachedList.Back().Value.(*sieveEntry).key; tail != "____0" {
+		t.Errorf("Hit moved a binding. Got %v, Expected %v", tail, "____0")
+		t.FailNow()
+	}
+
+	removals := recordRemovals(sieve)
+	for i := 4; i < 8; i++ {
+		key := fmt.Sprintf("____%d", i)
+		sieve.Set(key, []byte(key))
+	}
+	checkRemovals(t, sieve, *removals, []removal{
+		{"____1", "____1", ReasonEvicted},
+		{"____3", "____3", ReasonEvicted},
+		{"____4", "____4", ReasonEvicted},
+		{"____5", "____5", ReasonEvicted},
+	})
+	for _, key := range []string{"____0", "____2"} {
+		if _, ok := sieve.Peek(key); !ok {
+			t.Errorf("Visited binding %s evicted. Got %v, Expected %v", key, ok, true)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that the hand wraps from the head back to the tail when every
+// binding it passes has been visited
+func TestWrapSieve(t *testing.T) {
+	sieve := NewSieve(30)
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("____%d", i)
+		sieve.Set(key, []byte(key))
+		sieve.Get(key)
+	}
+
+	sieve.Set("____3", []byte("____3"))
+	if _, ok := sieve.Peek("____0"); ok {
+		t.Errorf("Wrong binding evicted after wrapping. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if sieve.Len() != 3 || sieve.RemainingStorage() != 0 {
+		t.Errorf("Wrong size after wrapping. Got %v, %v, Expected %v, %v", sieve.Len(), sieve.RemainingStorage(), 3, 0)
+		t.FailNow()
+	}
+}
+
+// Check that SIEVE's hit ratio is at least LRU's on the benchmark workloads
+func TestComparableToLruSieve(t *testing.T) {
+	for name, newConfig := range benchmarkWorkloads() {
+		events := workload.New(newConfig()).Events(50000)
+		sieve, lru := NewSieve(benchmarkCapacity), NewLru(benchmarkCapacity)
+		replayEvents(sieve, events)
+		replayEvents(lru, events)
+
+		sieveRatio, lruRatio := sieve.Stats().HitRatio(), lru.Stats().HitRatio()
+		if sieveRatio < lruRatio-0.01 {
+			t.Errorf("%s: SIEVE hit ratio below LRU's. Got %v, Expected at least %v", name, sieveRatio, lruRatio)
+			t.FailNow()
+		}
+	}
+}
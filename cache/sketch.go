@@ -0,0 +1,143 @@
+package cache
+
+const (
+	// sketchDepth is the number of rows of counters in a CountMinSketch
+	sketchDepth = 4
+
+	// sketchMaxCount is the largest value a CountMinSketch counter holds,
+	// as in a four-bit counter.
+	sketchMaxCount = 15
+
+	// sketchResetFactor is how many increments per counter in a row a
+	// CountMinSketch takes before halving its counts.
+	sketchResetFactor = 10
+
+	// doorkeeperProbes is the number of bits set for each key in the
+	// doorkeeper Bloom filter of a CountMinSketch.
+	doorkeeperProbes = 3
+)
+
+// A CountMinSketch estimates how often keys have been seen recently, in a
+// fixed amount of memory, for use in admission decisions. It counts each key
+// in one counter per row and estimates its count as the smallest of them, so
+// estimates can be too high when keys collide but never too low.
+//
+// A key seen for the first time is only recorded in a doorkeeper Bloom filter,
+// which keeps keys seen once from taking up counters. After a number of
+// increments proportional to the width, every count is halved and the
+// doorkeeper is cleared, so that the sketch follows changes in popularity.
+// This is the frequency sketch of TinyLFU (Einziger et al., ACM TOS '17).
+type CountMinSketch struct {
+	rows       [sketchDepth][]uint8 // Counters, indexed by a different hash in each row
+	mask       uint64               // Width of each row minus one
+	doorkeeper []uint64             // Bloom filter of the keys seen since the last reset
+	increments int                  // Increments since the last reset
+	resetAfter int                  // Increments between resets
+}
+
+// NewCountMinSketch returns a pointer to a new CountMinSketch with rows of at
+// least width counters, which should be about the number of distinct keys the
+// sketch must tell apart.
+func NewCountMinSketch(width int) *CountMinSketch {
+	size := 1
+	for size < width {
+		size *= 2
+	}
+
+	sketch := &CountMinSketch{mask: uint64(size - 1), doorkeeper: make([]uint64, size/8+1), resetAfter: sketchResetFactor * size}
+	for i := range sketch.rows {
+		sketch.rows[i] = make([]uint8, size)
+	}
+	return sketch
+}
+
+// sketchHash returns a 64-bit hash of key: FNV-1a, followed by a finalizer so
+// that both halves are well mixed.
+func sketchHash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h
+}
+
+// probe returns the i-th of a sequence of indexes derived from hash, within
+// mask, by double hashing.
+func probe(hash uint64, i int, mask uint64) uint64 {
+	return ((hash & 0xffffffff) + uint64(i)*(hash>>32|1)) & mask
+}
+
+// Increment records that key has been seen once more, halving every count if
+// the sketch is due for a reset.
+func (sketch *CountMinSketch) Increment(key string) {
+	hash := sketchHash(key)
+	if sketch.inDoorkeeper(hash) {
+		for i := range sketch.rows {
+			counter := &sketch.rows[i][probe(hash, i, sketch.mask)]
+			if *counter < sketchMaxCount {
+				*counter += 1
+			}
+		}
+	} else {
+		for i := 0; i < doorkeeperProbes; i++ {
+			bit := sketch.doorkeeperBit(hash, i)
+			sketch.doorkeeper[bit/64] |= 1 << (bit % 64)
+		}
+	}
+
+	sketch.increments += 1
+	if sketch.increments >= sketch.resetAfter {
+		sketch.Reset()
+	}
+}
+
+// Estimate returns an estimate of how often key has been seen recently, which
+// may be too high but never too low.
+func (sketch *CountMinSketch) Estimate(key string) int {
+	hash := sketchHash(key)
+	count := sketchMaxCount
+	for i := range sketch.rows {
+		if c := int(sketch.rows[i][probe(hash, i, sketch.mask)]); c < count {
+			count = c
+		}
+	}
+	if sketch.inDoorkeeper(hash) {
+		count += 1
+	}
+	return count
+}
+
+// Reset halves every count and clears the doorkeeper, forgetting keys seen
+// only once.
+func (sketch *CountMinSketch) Reset() {
+	for i := range sketch.rows {
+		for j := range sketch.rows[i] {
+			sketch.rows[i][j] /= 2
+		}
+	}
+	for i := range sketch.doorkeeper {
+		sketch.doorkeeper[i] = 0
+	}
+	sketch.increments = 0
+}
+
+// doorkeeperBit returns the index of the i-th bit of the doorkeeper for hash.
+// The hash is rotated so that the doorkeeper and the rows use different bits.
+func (sketch *CountMinSketch) doorkeeperBit(hash uint64, i int) uint64 {
+	return probe(hash>>29|hash<<35, i, ^uint64(0)) % uint64(len(sketch.doorkeeper)*64)
+}
+
+// inDoorkeeper reports whether the doorkeeper may hold hash.
+func (sketch *CountMinSketch) inDoorkeeper(hash uint64) bool {
+	for i := 0; i < doorkeeperProbes; i++ {
+		bit := sketch.doorkeeperBit(hash, i)
+		if sketch.doorkeeper[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+/******************************************************************************
+ * sketch_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for the frequency sketch in sketch.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that estimates start at zero, count the first use in the doorkeeper
+// and are never below the true count, up to the largest count held
+func TestEstimateSketch(t *testing.T) {
+	sketch := NewCountMinSketch(256)
+	if estimate := sketch.Estimate("key"); estimate != 0 {
+		t.Errorf("Estimate of an unseen key wrong. Got %v, Expected %v", estimate, 0)
+		t.FailNow()
+	}
+
+	sketch.Increment("key")
+	if estimate := sketch.Estimate("key"); estimate != 1 {
+		t.Errorf("Estimate after one use wrong. Got %v, Expected %v", estimate, 1)
+		t.FailNow()
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		for j := 0; j <= i%8; j++ {
+			sketch.Increment(key)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if estimate := sketch.Estimate(key); estimate < i%8+1 {
+			t.Errorf("Estimate of %s too low. Got %v, Expected at least %v", key, estimate, i%8+1)
+			t.FailNow()
+		}
+	}
+
+	for i := 0; i < 40; i++ {
+		sketch.Increment("key")
+	}
+	if estimate := sketch.Estimate("key"); estimate != sketchMaxCount+1 {
+		t.Errorf("Estimate not capped. Got %v, Expected %v", estimate, sketchMaxCount+1)
+		t.FailNow()
+	}
+}
+
+// Check that Reset halves the counts and forgets keys seen once, and that the
+// sketch resets itself after enough increments
+func TestResetSketch(t *testing.T) {
+	sketch := NewCountMinSketch(64)
+	for i := 0; i < 9; i++ {
+		sketch.Increment("hot")
+	}
+	sketch.Increment("cold")
+
+	sketch.Reset()
+	if estimate := sketch.Estimate("hot"); estimate != 4 {
+		t.Errorf("Reset did not halve the count. Got %v, Expected %v", estimate, 4)
+		t.FailNow()
+	}
+	if estimate := sketch.Estimate("cold"); estimate != 0 {
+		t.Errorf("Reset did not forget a key seen once. Got %v, Expected %v", estimate, 0)
+		t.FailNow()
+	}
+
+	for i := 0; i < sketch.resetAfter-1; i++ {
+		sketch.Increment(fmt.Sprintf("key%d", i%1000))
+	}
+	before := sketch.Estimate("hot")
+	sketch.Increment("other")
+	if after := sketch.Estimate("hot"); after >= before || sketch.increments != 0 {
+		t.Errorf("Sketch did not reset itself. Got %v, Expected less than %v", after, before)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,167 @@
+/******************************************************************************
+ * stats_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for Stats in cache.go and the counters each policy
+ *    maintains.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that Equals() compares every field
+func TestEqualsStats(t *testing.T) {
+	base := Stats{Hits: 1, Misses: 2, Sets: 3, HitBytes: 4, TotalLoadTime: time.Second}
+
+	same := base
+	if !base.Equals(&same) {
+		t.Errorf("Equal stats compared unequal. Got %v, Expected %v", false, true)
+		t.FailNow()
+	}
+
+	changes := []func(stats *Stats){
+		func(stats *Stats) { stats.B1Hits++ },
+		func(stats *Stats) { stats.B2Hits++ },
+		func(stats *Stats) { stats.Sets++ },
+		func(stats *Stats) { stats.RejectedSets++ },
+		func(stats *Stats) { stats.Evictions++ },
+		func(stats *Stats) { stats.Removals++ },
+		func(stats *Stats) { stats.HitBytes++ },
+		func(stats *Stats) { stats.MissBytes++ },
+		func(stats *Stats) { stats.LoadFailures++ },
+		func(stats *Stats) { stats.TotalLoadTime++ },
+	}
+	for i, change := range changes {
+		other := base
+		change(&other)
+		if base.Equals(&other) {
+			t.Errorf("Change %d was ignored by Equals. Got %v, Expected %v", i, true, false)
+			t.FailNow()
+		}
+	}
+
+	var nilStats *Stats
+	if !nilStats.Equals(nil) || nilStats.Equals(&base) || base.Equals(nil) {
+		t.Errorf("Equals handled nil stats wrong")
+		t.FailNow()
+	}
+}
+
+// Check that a Snapshot() is not changed by later operations and that Delta()
+// subtracts an earlier one
+func TestSnapshotDeltaStats(t *testing.T) {
+	lru := NewLru(1024)
+	lru.Set("key", []byte("value"))
+	lru.Get("key")
+
+	before := lru.Stats().Snapshot()
+	lru.Get("key")
+	lru.Get("miss")
+	lru.Set("other", []byte("value"))
+	after := lru.Stats().Snapshot()
+
+	if before.Hits != 1 || before.Misses != 0 {
+		t.Errorf("Snapshot was modified. Got %v hits and %v misses, Expected %v and %v", before.Hits, before.Misses, 1, 0)
+		t.FailNow()
+	}
+
+	delta := after.Delta(before)
+	expected := Stats{Hits: 1, Misses: 1, Sets: 1, HitBytes: 5, MissBytes: 5}
+	if !delta.Equals(&expected) {
+		t.Errorf("Delta wrong. Got %+v, Expected %+v", delta, expected)
+		t.FailNow()
+	}
+}
+
+// Check the ratio helpers, including when nothing has been requested
+func TestRatiosStats(t *testing.T) {
+	empty := Stats{}
+	if empty.HitRatio() != 0 || empty.ByteHitRatio() != 0 {
+		t.Errorf("Ratios of empty stats wrong. Got %v and %v, Expected %v and %v", empty.HitRatio(), empty.ByteHitRatio(), 0, 0)
+		t.FailNow()
+	}
+
+	stats := Stats{Hits: 3, Misses: 1, HitBytes: 10, MissBytes: 30}
+	if math.Abs(stats.HitRatio()-0.75) > 1e-9 {
+		t.Errorf("HitRatio wrong. Got %v, Expected %v", stats.HitRatio(), 0.75)
+		t.FailNow()
+	}
+	if math.Abs(stats.ByteHitRatio()-0.25) > 1e-9 {
+		t.Errorf("ByteHitRatio wrong. Got %v, Expected %v", stats.ByteHitRatio(), 0.25)
+		t.FailNow()
+	}
+}
+
+// Check that every policy counts sets, rejections, removals and bytes. The
+// value of a rejected Set counts as missed bytes.
+func TestCountersStats(t *testing.T) {
+	for _, cache := range newPolicies(t, 30) {
+		cache.Set("____0", []byte("____0"))
+		cache.Set("____1", []byte("____1"))
+		cache.Set("____1", []byte("_____1"))
+		cache.Set("too large", []byte("for a cache of thirty bytes"))
+		cache.Get("____0")
+		cache.Get("____1")
+		cache.Get("miss")
+		cache.Remove("____0")
+		cache.Set("____2", []byte("____2"))
+		cache.Set("____3", []byte("____3"))
+		cache.Set("____4", []byte("____4"))
+
+		stats := cache.Stats()
+		expected := map[string][2]int{
+			"Hits":         {stats.Hits, 2},
+			"Misses":       {stats.Misses, 1},
+			"Sets":         {stats.Sets, 6},
+			"RejectedSets": {stats.RejectedSets, 1},
+			"Removals":     {stats.Removals, 1},
+			"HitBytes":     {stats.HitBytes, 11},
+			"MissBytes":    {stats.MissBytes, 25 + len("for a cache of thirty bytes")},
+		}
+		for name, values := range expected {
+			if values[0] != values[1] {
+				t.Errorf("%s %s wrong. Got %v, Expected %v", cacheType(cache), name, values[0], values[1])
+				t.FailNow()
+			}
+		}
+	}
+}
+
+// Check that every policy counts evictions made to admit new bindings
+func TestEvictionsStats(t *testing.T) {
+	for _, cache := range newPolicies(t, 30) {
+		cache.Set("____0", []byte("____0"))
+		cache.Set("____1", []byte("____1"))
+		cache.Set("____2", []byte("____2"))
+		cache.Set("____3", []byte("____3"))
+
+		if cache.Stats().Evictions != 1 {
+			t.Errorf("%s Evictions wrong. Got %v, Expected %v", cacheType(cache), cache.Stats().Evictions, 1)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that a Sharded cache sums the new counters across shards
+func TestShardedCountersStats(t *testing.T) {
+	sharded := NewSharded(4, 1024, func(limit int) Cache { return NewLru(limit) })
+	sharded.Set("key", []byte("value"))
+	sharded.Get("key")
+	sharded.Remove("key")
+
+	expected := Stats{Hits: 1, Sets: 1, Removals: 1, HitBytes: 5, MissBytes: 5}
+	if !sharded.Stats().Equals(&expected) {
+		t.Errorf("Sharded stats wrong. Got %+v, Expected %+v", *sharded.Stats(), expected)
+		t.FailNow()
+	}
+}
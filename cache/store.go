@@ -0,0 +1,297 @@
+package cache
+
+import (
+	"time"
+)
+
+// A Policy decides which binding a Store evicts to make room. The Store keeps
+// the bindings, their sizes and the stats, and reports every binding that
+// enters, is used or leaves it through the hooks below, so a Policy only
+// keeps whatever order over the keys it needs to choose a victim.
+type Policy interface {
+	// OnInsert is called when Set adds a binding for key of size bytes,
+	// before the Store makes room for it.
+	OnInsert(key string, size int)
+
+	// OnAccess is called when the binding for key is used, by a hit or by
+	// Set overwriting it, with the size of the binding after the use. An
+	// overwrite calls OnAccess before the Store makes room for the new value.
+	OnAccess(key string, size int)
+
+	// OnRemove is called when the binding for key has left the Store.
+	OnRemove(key string, reason RemovalReason)
+
+	// Victim returns the key of the binding to evict next. While it holds
+	// any other key, it must not return the key most recently passed to
+	// OnInsert or OnAccess, so that a binding is never evicted to make room
+	// for itself. ok is false if the Policy holds no keys.
+	Victim() (key string, ok bool)
+}
+
+// A Store is a fixed-size in-memory cache that keeps the bindings and their
+// sizes, and leaves the choice of which binding to evict to a Policy.
+type Store struct {
+	cachedValues          map[string]mapping // Map containing key-value pairings
+	policy                Policy             // Decides which binding to evict
+	capacity              int                // To hold the capacity of the cache
+	currentlyUsedCapacity int                // Currently used capacity of the cache
+	stats                 Stats              // Hits and misses for the cache
+	clock                 Clock              // Source of the current time for expiration
+	onEvict               EvictionCallback   // Called whenever a binding leaves the cache
+}
+
+// NewStore returns a pointer to a new Store with a capacity to store limit
+// bytes, evicting the bindings chosen by policy. The policy must hold no keys.
+func NewStore(limit int, policy Policy) *Store {
+	return &Store{cachedValues: make(map[string]mapping), policy: policy, capacity: limit, currentlyUsedCapacity: 0, stats: Stats{}, clock: systemClock{}}
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the Store,
+// replacing any previously registered callback.
+func (store *Store) OnEvict(fn EvictionCallback) {
+	store.onEvict = fn
+}
+
+// notify records in the stats that a binding left the Store, and reports it
+// to the eviction callback, if any.
+func (store *Store) notify(currMapping mapping, reason RemovalReason) {
+	store.stats.recordRemoval(reason)
+	if store.onEvict != nil {
+		store.onEvict(currMapping.key, currMapping.value, reason)
+	}
+}
+
+// SetClock replaces the clock the Store uses to decide whether a binding has expired.
+func (store *Store) SetClock(clock Clock) {
+	store.clock = clock
+}
+
+// MaxStorage returns the maximum number of bytes this Store can store
+func (store *Store) MaxStorage() int {
+	return store.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this Store
+func (store *Store) RemainingStorage() int {
+	return store.capacity - store.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not count as a use or update the stats.
+// ok is true if a value was found and false otherwise. Expired bindings are
+// reported as missing but left in place, so Peek never modifies the Store.
+func (store *Store) Peek(key string) (value []byte, ok bool) {
+	currMapping, ok := store.cachedValues[key]
+	if ok && currMapping.expired(store.clock.Now()) {
+		return nil, false
+	}
+	return currMapping.value, ok
+}
+
+// Get returns the value associated with the given key, if it exists.
+// This operation counts as a use of the binding.
+// ok is true if a value was found and false otherwise.
+// Expired bindings count as misses and are removed.
+func (store *Store) Get(key string) (value []byte, ok bool) {
+	currMapping, ok := store.cachedValues[key]
+	if ok && currMapping.expired(store.clock.Now()) {
+		store.unlink(key, ReasonExpired)
+		store.notify(currMapping, ReasonExpired)
+		ok = false
+	}
+
+	if !ok {
+		store.stats.Misses += 1
+		return nil, false
+	}
+
+	store.policy.OnAccess(key, len(currMapping.key)+len(currMapping.value))
+	store.stats.Hits += 1
+	store.stats.HitBytes += len(currMapping.value)
+	return currMapping.value, true
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (store *Store) Remove(key string) (value []byte, ok bool) {
+	currMapping, ok := store.unlink(key, ReasonRemoved)
+	if !ok {
+		return nil, false
+	}
+
+	store.notify(currMapping, ReasonRemoved)
+	return currMapping.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. Overwriting a binding counts as a use of it and never evicts
+// it, unless it has expired, in which case it is removed as expired and the
+// new binding is added as if it were not there.
+// Returns true if the binding was added successfully, else false.
+func (store *Store) Set(key string, value []byte) bool {
+	return store.setWithExpiry(key, value, time.Time{})
+}
+
+// SetWithTTL behaves like Set, but the binding expires once ttl has elapsed.
+// A ttl <= 0 means the binding never expires.
+func (store *Store) SetWithTTL(key string, value []byte, ttl time.Duration) bool {
+	return store.setWithExpiry(key, value, expiryFor(store.clock, ttl))
+}
+
+// setWithExpiry is Set for a binding that expires at the given time, or never
+// if expires is the zero time.
+func (store *Store) setWithExpiry(key string, value []byte, expires time.Time) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > store.capacity {
+		store.stats.recordRejectedSet(value)
+		return false
+	}
+
+	// An expired binding is removed as if by Get, and replaced by a new one
+	if expired, ok := store.cachedValues[key]; ok && expired.expired(store.clock.Now()) {
+		store.unlink(key, ReasonExpired)
+		store.notify(expired, ReasonExpired)
+	}
+
+	if replaced, ok := store.cachedValues[key]; ok {
+		store.policy.OnAccess(key, currentObjectSize)
+		store.currentlyUsedCapacity -= len(replaced.key) + len(replaced.value)
+		store.put(mapping{key: key, value: value, expires: expires})
+		store.makeRoom(0)
+
+		store.stats.Sets += 1
+		store.notify(replaced, ReasonReplaced)
+		return true
+	}
+
+	store.policy.OnInsert(key, currentObjectSize)
+	store.makeRoom(currentObjectSize)
+	store.put(mapping{key: key, value: value, expires: expires})
+	store.stats.Sets += 1
+	store.stats.MissBytes += len(value)
+	return true
+}
+
+// makeRoom evicts the bindings chosen by the policy until size more bytes fit.
+func (store *Store) makeRoom(size int) {
+	for store.RemainingStorage() < size {
+		if _, ok := store.Evict(); !ok {
+			return
+		}
+	}
+}
+
+// insert adds a new binding for key without updating the stats, as if it had
+// been set. The caller must have made room for it.
+func (store *Store) insert(key string, value []byte, expires time.Time) {
+	store.policy.OnInsert(key, len(key)+len(value))
+	store.put(mapping{key: key, value: value, expires: expires})
+}
+
+// put stores currMapping and counts its size, without telling the policy.
+func (store *Store) put(currMapping mapping) {
+	store.cachedValues[currMapping.key] = currMapping
+	store.currentlyUsedCapacity += len(currMapping.key) + len(currMapping.value)
+}
+
+// access counts a use of the binding for key, if it exists, without updating
+// the stats.
+func (store *Store) access(key string) {
+	if currMapping, ok := store.cachedValues[key]; ok {
+		store.policy.OnAccess(key, len(currMapping.key)+len(currMapping.value))
+	}
+}
+
+// unlink removes and returns the binding for key, if it exists, and tells the
+// policy why it left, without notifying the eviction callback.
+func (store *Store) unlink(key string, reason RemovalReason) (mapping, bool) {
+	currMapping, ok := store.cachedValues[key]
+	if !ok {
+		return mapping{}, false
+	}
+
+	delete(store.cachedValues, key)
+	store.currentlyUsedCapacity -= len(currMapping.key) + len(currMapping.value)
+	store.policy.OnRemove(key, reason)
+	return currMapping, true
+}
+
+// removeMapping removes and returns the binding for key, if it exists, without
+// notifying the eviction callback.
+func (store *Store) removeMapping(key string) (mapping, bool) {
+	return store.unlink(key, ReasonRemoved)
+}
+
+// Empty removes every binding from the Store.
+func (store *Store) Empty() {
+	if store.onEvict != nil {
+		for _, currMapping := range store.cachedValues {
+			store.notify(currMapping, ReasonEmptied)
+		}
+	}
+	store.reset()
+}
+
+// reset removes every binding from the Store without notifying the eviction
+// callback.
+func (store *Store) reset() {
+	for key := range store.cachedValues {
+		store.policy.OnRemove(key, ReasonEmptied)
+	}
+	store.cachedValues = make(map[string]mapping)
+	store.currentlyUsedCapacity = 0
+}
+
+// Evict removes the binding chosen by the policy and returns its key.
+// ok is false if the Store was already empty.
+func (store *Store) Evict() (key string, ok bool) {
+	currMapping, ok := store.evict()
+	if !ok {
+		return "", false
+	}
+	store.notify(currMapping, ReasonEvicted)
+	return currMapping.key, true
+}
+
+// evict removes and returns the binding chosen by the policy without
+// notifying the eviction callback.
+func (store *Store) evict() (mapping, bool) {
+	key, ok := store.policy.Victim()
+	if !ok {
+		return mapping{}, false
+	}
+	return store.unlink(key, ReasonEvicted)
+}
+
+// Len returns the number of bindings in the Store.
+func (store *Store) Len() int {
+	return len(store.cachedValues)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (store *Store) Stats() *Stats {
+	return &store.stats
+}
+
+// RemoveExpired removes every binding whose time-to-live has run out and
+// returns how many were removed.
+func (store *Store) RemoveExpired() int {
+	now := store.clock.Now()
+	removed := 0
+	for key, currMapping := range store.cachedValues {
+		if currMapping.expired(now) {
+			store.unlink(key, ReasonExpired)
+			store.notify(currMapping, ReasonExpired)
+			removed++
+		}
+	}
+	return removed
+}
+
+// peekMapping returns the full binding for key, expired or not, without
+// counting as a use.
+func (store *Store) peekMapping(key string) (mapping, bool) {
+	currMapping, ok := store.cachedValues[key]
+	return currMapping, ok
+}
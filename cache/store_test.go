@@ -0,0 +1,118 @@
+/******************************************************************************
+ * store_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for the storage engine in store.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// A recordingPolicy is an LRU policy that records every hook called on it.
+type recordingPolicy struct {
+	lruPolicy
+	calls []string
+}
+
+func (policy *recordingPolicy) OnInsert(key string, size int) {
+	policy.calls = append(policy.calls, fmt.Sprintf("insert %s %d", key, size))
+	policy.lruPolicy.OnInsert(key, size)
+}
+
+func (policy *recordingPolicy) OnAccess(key string, size int) {
+	policy.calls = append(policy.calls, fmt.Sprintf("access %s %d", key, size))
+	policy.lruPolicy.OnAccess(key, size)
+}
+
+func (policy *recordingPolicy) OnRemove(key string, reason RemovalReason) {
+	policy.calls = append(policy.calls, fmt.Sprintf("remove %s %v", key, reason))
+	policy.lruPolicy.OnRemove(key, reason)
+}
+
+// checkCalls fails t if the hooks called on policy since the last check are
+// not expected, in order.
+func checkCalls(t *testing.T, policy *recordingPolicy, expected ...string) {
+	if fmt.Sprint(policy.calls) != fmt.Sprint(expected) {
+		t.Errorf("Wrong hooks called. Got %v, Expected %v", policy.calls, expected)
+		t.FailNow()
+	}
+	policy.calls = nil
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that the Store calls the hooks of its policy for every binding that
+// enters, is used or leaves it
+func TestHooksStore(t *testing.T) {
+	clock := newFakeClock()
+	policy := &recordingPolicy{lruPolicy: *newLruPolicy()}
+	store := NewStore(20, policy)
+	store.SetClock(clock)
+
+	store.Set("a", []byte("1234"))
+	store.Set("b", []byte("1234"))
+	checkCalls(t, policy, "insert a 5", "insert b 5")
+
+	store.Get("a")
+	store.Get("missing")
+	store.Peek("b")
+	checkCalls(t, policy, "access a 5")
+
+	store.Set("a", []byte("12345678"))
+	checkCalls(t, policy, "access a 9")
+
+	store.Set("c", []byte("12345678"))
+	checkCalls(t, policy, "insert c 9", "remove b evicted")
+
+	store.Remove("a")
+	store.Remove("a")
+	checkCalls(t, policy, "remove a removed")
+
+	store.SetWithTTL("d", nil, time.Minute)
+	clock.Advance(time.Minute)
+	store.Get("d")
+	checkCalls(t, policy, "insert d 1", "remove d expired")
+
+	store.Empty()
+	checkCalls(t, policy, "remove c emptied")
+	if policy.Len() != 0 || store.Len() != 0 || store.RemainingStorage() != 20 {
+		t.Errorf("Empty left bindings behind. Got %v keys, %v bindings, %v bytes free, Expected %v, %v, %v", policy.Len(), store.Len(), store.RemainingStorage(), 0, 0, 20)
+		t.FailNow()
+	}
+}
+
+// Check that growing a binding evicts others to make room, but never the
+// binding itself, even when it is the next victim
+func TestOverwriteStore(t *testing.T) {
+	for _, cache := range []Cache{NewLru(30), NewArc(30)} {
+		for i := 0; i < 3; i++ {
+			key := fmt.Sprintf("____%d", i)
+			cache.Set(key, []byte(key))
+		}
+
+		if ok := cache.Set("____0", []byte("____0_________")); !ok {
+			t.Errorf("%s failed to grow a binding. Got %v, Expected %v", cacheType(cache), ok, true)
+			t.FailNow()
+		}
+		if value, ok := cache.Peek("____0"); !ok || !bytesEqual(value, []byte("____0_________")) {
+			t.Errorf("%s lost the grown binding. Got %v, Expected %v", cacheType(cache), value, []byte("____0_________"))
+			t.FailNow()
+		}
+		if cache.Len() != 2 || cache.RemainingStorage() != 1 {
+			t.Errorf("%s made the wrong room. Got %v bindings, %v bytes free, Expected %v, %v", cacheType(cache), cache.Len(), cache.RemainingStorage(), 2, 1)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrNotSupported is returned when a wrapper is asked to perform an operation
+// that the cache it wraps does not implement.
+var ErrNotSupported = errors.New("cache: operation not supported by the wrapped cache")
+
+// A SyncCache wraps another Cache so that every method is safe for
+// concurrent use by multiple goroutines
+type SyncCache struct {
+	mu    sync.RWMutex // Guards every access to cache
+	cache Cache        // The wrapped, non thread-safe cache
+}
+
+// A concurrentGetter is a Cache whose Get only reads its structure and updates
+// atomic fields, so concurrent Gets may share a read lock.
+type concurrentGetter interface {
+	concurrentGet()
+}
+
+// Synchronized returns a Cache that serializes access to c. Operations that
+// only read c (Peek, Len, MaxStorage, RemainingStorage and Stats) share a read
+// lock, while operations that may reorder or modify it take the write lock.
+// Gets on a SIEVE or an S3FIFO also share the read lock, since their hits
+// never move bindings. c must not be used directly once it has been wrapped.
+func Synchronized(c Cache) Cache {
+	if sc, ok := c.(*SyncCache); ok {
+		return sc
+	}
+	return &SyncCache{cache: c}
+}
+
+// MaxStorage returns the maximum number of bytes the wrapped cache can store
+func (sc *SyncCache) MaxStorage() int {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.cache.MaxStorage()
+}
+
+// RemainingStorage returns the number of unused bytes available in the wrapped cache
+func (sc *SyncCache) RemainingStorage() int {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.cache.RemainingStorage()
+}
+
+// Get returns the value associated with the given key, if it exists.
+// A Get may reorder the wrapped cache and update its stats, so it takes the
+// write lock unless the wrapped cache's Get is safe for concurrent use.
+func (sc *SyncCache) Get(key string) (value []byte, ok bool) {
+	if _, ok := sc.cache.(concurrentGetter); ok {
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return sc.cache.Get(key)
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.cache.Get(key)
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (sc *SyncCache) Remove(key string) (value []byte, ok bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.cache.Remove(key)
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. Returns true if the binding was added successfully, else false.
+func (sc *SyncCache) Set(key string, value []byte) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.cache.Set(key, value)
+}
+
+// Peek returns the value associated with the given key without updating the
+// "recently used"-ness of the key. Concurrent Peeks do not block each other.
+func (sc *SyncCache) Peek(key string) (value []byte, ok bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.cache.Peek(key)
+}
+
+// Empty removes every binding from the wrapped cache.
+func (sc *SyncCache) Empty() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.cache.Empty()
+}
+
+// Len returns the number of bindings in the wrapped cache.
+func (sc *SyncCache) Len() int {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.cache.Len()
+}
+
+// Stats returns a copy of the wrapped cache's statistics. The copy is taken
+// under the lock, so its counters are consistent with each other and callers
+// may read it without further synchronization.
+func (sc *SyncCache) Stats() *Stats {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	stats := *sc.cache.Stats()
+	return &stats
+}
+
+// SetWithTTL sets a binding that expires once ttl has elapsed. It returns
+// false without setting anything if the wrapped cache is not an Expirer.
+func (sc *SyncCache) SetWithTTL(key string, value []byte, ttl time.Duration) bool {
+	expirer, ok := sc.cache.(Expirer)
+	if !ok {
+		return false
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return expirer.SetWithTTL(key, value, ttl)
+}
+
+// RemoveExpired removes every expired binding from the wrapped cache and
+// returns how many were removed, or 0 if the wrapped cache is not an Expirer.
+func (sc *SyncCache) RemoveExpired() int {
+	expirer, ok := sc.cache.(Expirer)
+	if !ok {
+		return 0
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return expirer.RemoveExpired()
+}
+
+// OnEvict registers fn with the wrapped cache, if it is an EvictionNotifier.
+// fn is called with the lock held, so it must not call back into the cache.
+func (sc *SyncCache) OnEvict(fn EvictionCallback) {
+	notifier, ok := sc.cache.(EvictionNotifier)
+	if !ok {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	notifier.OnEvict(fn)
+}
+
+// SaveTo writes a snapshot of the wrapped cache to w, if it is a Persister.
+// Saving only reads the cache, so it does not block concurrent Peeks.
+func (sc *SyncCache) SaveTo(w io.Writer) error {
+	persister, ok := sc.cache.(Persister)
+	if !ok {
+		return ErrNotSupported
+	}
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return persister.SaveTo(w)
+}
+
+// LoadFrom replaces the contents of the wrapped cache with a snapshot read
+// from r, if it is a Persister.
+func (sc *SyncCache) LoadFrom(r io.Reader) error {
+	persister, ok := sc.cache.(Persister)
+	if !ok {
+		return ErrNotSupported
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return persister.LoadFrom(r)
+}
@@ -0,0 +1,183 @@
+/******************************************************************************
+ * synchronized_test.go
+ * Author:
+ * Usage:    `go test -race`  or  `go test -race -v`
+ * Description:
+ *    A unit testing suite for synchronized.go. The concurrent tests are only
+ *    meaningful when run under the race detector.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                Constants                                   */
+/******************************************************************************/
+
+const (
+	syncGoroutines = 16  // Number of goroutines hammering a cache at once
+	syncIterations = 500 // Number of operations per goroutine
+	syncKeys       = 64  // Size of the key space the goroutines share
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that Synchronized() behaves exactly like the cache it wraps when used
+// from a single goroutine
+func TestSingleGoroutineSynchronized(t *testing.T) {
+	capacity := 1024
+	cache := Synchronized(NewLru(capacity))
+	checkCapacity(t, cache, capacity)
+
+	ok := cache.Set("key", []byte("value"))
+	if !ok {
+		t.Errorf("Failed to add binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if cache.RemainingStorage() != 1016 {
+		t.Errorf("RemainingStorage wrong after adding binding. Got %v, Expected %v", cache.RemainingStorage(), 1016)
+		t.FailNow()
+	}
+
+	value, ok := cache.Peek("key")
+	if !ok || !bytes.Equal(value, []byte("value")) {
+		t.Errorf("Peeked wrong value. Got %v, Expected %v", value, []byte("value"))
+		t.FailNow()
+	}
+	value, ok = cache.Get("key")
+	if !ok || !bytes.Equal(value, []byte("value")) {
+		t.Errorf("Fetched wrong value. Got %v, Expected %v", value, []byte("value"))
+		t.FailNow()
+	}
+	cache.Get("miss")
+
+	if cache.Stats().Hits != 1 || cache.Stats().Misses != 1 {
+		t.Errorf("Stats wrong. Got %v, Expected %v", *cache.Stats(), Stats{Hits: 1, Misses: 1})
+		t.FailNow()
+	}
+
+	if _, ok := cache.Remove("key"); !ok {
+		t.Errorf("Failed to remove binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Len wrong after removing. Got %v, Expected %v", cache.Len(), 0)
+		t.FailNow()
+	}
+
+	cache.Set("key", []byte("value"))
+	cache.Empty()
+	if cache.RemainingStorage() != capacity {
+		t.Errorf("RemainingStorage wrong after Empty. Got %v, Expected %v", cache.RemainingStorage(), capacity)
+		t.FailNow()
+	}
+}
+
+// Check that wrapping a cache twice does not add a second lock
+func TestDoubleWrapSynchronized(t *testing.T) {
+	cache := Synchronized(NewLru(64))
+	if Synchronized(cache) != cache {
+		t.Errorf("Synchronized wrapped an already synchronized cache")
+		t.FailNow()
+	}
+}
+
+// Check that Stats() returns a copy that later operations do not modify
+func TestStatsCopySynchronized(t *testing.T) {
+	cache := Synchronized(NewLru(64))
+	stats := cache.Stats()
+	cache.Get("miss")
+
+	if stats.Misses != 0 {
+		t.Errorf("Stats copy was modified. Got %v, Expected %v", stats.Misses, 0)
+		t.FailNow()
+	}
+	if cache.Stats().Misses != 1 {
+		t.Errorf("Misses wrong. Got %v, Expected %v", cache.Stats().Misses, 1)
+		t.FailNow()
+	}
+}
+
+// Hammer Set(), Get(), Peek() and Remove() on every policy from many
+// goroutines at once
+func TestConcurrentSynchronized(t *testing.T) {
+	for _, name := range Names() {
+		capacity := 256
+		cache := Synchronized(mustNew(t, name, capacity))
+
+		var wg sync.WaitGroup
+		for g := 0; g < syncGoroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < syncIterations; i++ {
+					key := fmt.Sprintf("key%d", (g*syncIterations+i)%syncKeys)
+					switch i % 5 {
+					case 0, 1:
+						cache.Set(key, []byte(key))
+					case 2:
+						if value, ok := cache.Get(key); ok && !bytes.Equal(value, []byte(key)) {
+							t.Errorf("Fetched wrong value for key %s. Got %v, Expected %v", key, value, []byte(key))
+						}
+					case 3:
+						cache.Peek(key)
+						cache.Len()
+						cache.Stats()
+					case 4:
+						cache.Remove(key)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		if cache.RemainingStorage() < 0 || cache.RemainingStorage() > capacity {
+			t.Errorf("RemainingStorage out of range for %s. Got %v", name, cache.RemainingStorage())
+			t.FailNow()
+		}
+		stats := cache.Stats()
+		if stats.Hits+stats.Misses != syncGoroutines*syncIterations/5 {
+			t.Errorf("Lost stats updates for %s. Got %v, Expected %v", name, stats.Hits+stats.Misses, syncGoroutines*syncIterations/5)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that Gets on a SIEVE and an S3FIFO, which share the read lock, count
+// every hit and keep S3FIFO's access count capped
+func TestConcurrentHitsSynchronized(t *testing.T) {
+	s3 := NewS3Fifo(64)
+	for _, cache := range []Cache{Synchronized(NewSieve(64)), Synchronized(s3)} {
+		cache.Set("____0", []byte("____0"))
+
+		var wg sync.WaitGroup
+		for g := 0; g < syncGoroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < syncIterations; i++ {
+					cache.Get("____0")
+				}
+			}()
+		}
+		wg.Wait()
+
+		if hits := cache.Stats().Hits; hits != syncGoroutines*syncIterations {
+			t.Errorf("Lost hits for %s. Got %v, Expected %v", cacheType(cache), hits, syncGoroutines*syncIterations)
+			t.FailNow()
+		}
+	}
+
+	if freq := s3.cachedValues["____0"].freq; freq != s3MaxFreq {
+		t.Errorf("Access count not capped. Got %v, Expected %v", freq, s3MaxFreq)
+		t.FailNow()
+	}
+}
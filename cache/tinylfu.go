@@ -0,0 +1,286 @@
+package cache
+
+import (
+	"time"
+)
+
+const (
+	// DefaultWindowFraction is the share of a TinyLFU's capacity held by its
+	// window, as recommended by the W-TinyLFU paper.
+	DefaultWindowFraction = 0.01
+
+	// tinyLfuBindingSize is the binding size assumed when sizing the sketch
+	// of a TinyLFU from its capacity in bytes.
+	tinyLfuBindingSize = 32
+)
+
+// A TinyLFU is a fixed-size in-memory cache using W-TinyLFU (Einziger et al.,
+// ACM TOS '17). A new binding enters a small LRU window. A binding pushed out
+// of the window is only admitted to the main area, a segmented LRU, if a
+// CountMinSketch estimates that its key has been used at least as often
+// recently as those of the bindings it would displace; otherwise it is
+// evicted instead. One-hit wonders therefore pass through the window without
+// pushing out valuable bindings. The window always keeps the newest binding,
+// so Set never refuses a binding that fits in the cache.
+//
+// The main area is a SegmentedLRU: admitted bindings start in its probation
+// segment and enter its protected segment when they are used again.
+type TinyLFU struct {
+	capacity       int // To hold the capacity of the cache
+	windowCapacity int // Bytes the window may hold
+
+	window *LRU            // To hold new bindings before admission
+	main   *SegmentedLRU   // To hold admitted bindings
+	sketch *CountMinSketch // Estimates how often each key was used recently
+
+	stats   Stats            // Hits and misses for the cache
+	onEvict EvictionCallback // Called whenever a binding leaves the cache
+}
+
+// NewTinyLfu returns a pointer to a new TinyLFU with a capacity to store limit
+// bytes, with a window of DefaultWindowFraction of it.
+func NewTinyLfu(limit int) *TinyLFU {
+	width := limit / tinyLfuBindingSize
+	if width < 64 {
+		width = 64
+	}
+
+	tl := &TinyLFU{capacity: limit, window: NewLru(limit), main: NewSegmentedLru(limit), sketch: NewCountMinSketch(width), stats: Stats{}}
+	tl.SetWindowFraction(DefaultWindowFraction)
+	return tl
+}
+
+func init() {
+	Register("tinylfu", func(limit int) Cache { return NewTinyLfu(limit) })
+}
+
+// SetWindowFraction sets the share of the capacity held by the window. The
+// main area holds the rest, of which its protected segment holds
+// DefaultProtectedFraction. fraction is clamped to [0, 1].
+func (tl *TinyLFU) SetWindowFraction(fraction float64) {
+	tl.windowCapacity = int(clampFraction(fraction) * float64(tl.capacity))
+	tl.main.capacity = tl.capacity - tl.windowCapacity
+	tl.main.SetProtectedFraction(DefaultProtectedFraction)
+	tl.maintain()
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the TinyLFU,
+// replacing any previously registered callback. A binding refused admission
+// after leaving the window is reported as evicted.
+func (tl *TinyLFU) OnEvict(fn EvictionCallback) {
+	tl.onEvict = fn
+}
+
+// notify records in the stats that a binding left the TinyLFU, and reports it
+// to the eviction callback, if any.
+func (tl *TinyLFU) notify(currMapping mapping, reason RemovalReason) {
+	tl.stats.recordRemoval(reason)
+	if tl.onEvict != nil {
+		tl.onEvict(currMapping.key, currMapping.value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this TinyLFU can store
+func (tl *TinyLFU) MaxStorage() int {
+	return tl.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this TinyLFU
+func (tl *TinyLFU) RemainingStorage() int {
+	return tl.capacity - tl.window.currentlyUsedCapacity - tl.main.usedCapacity()
+}
+
+// lookup returns the binding for key and the LRU holding it, if it exists.
+func (tl *TinyLFU) lookup(key string) (mapping, *LRU) {
+	if currMapping, ok := tl.window.cachedValues[key]; ok {
+		return currMapping, tl.window
+	}
+	return tl.main.lookup(key)
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not count as a use or update the stats.
+// ok is true if a value was found and false otherwise.
+func (tl *TinyLFU) Peek(key string) (value []byte, ok bool) {
+	currMapping, segment := tl.lookup(key)
+	if segment == nil {
+		return nil, false
+	}
+	return currMapping.value, true
+}
+
+// Get returns the value associated with the given key, if it exists, and
+// counts a use of it in the sketch, whether or not it was found.
+// ok is true if a value was found and false otherwise.
+func (tl *TinyLFU) Get(key string) (value []byte, ok bool) {
+	tl.sketch.Increment(key)
+
+	currMapping, segment := tl.lookup(key)
+	if segment == nil {
+		tl.stats.Misses += 1
+		return nil, false
+	}
+
+	tl.touch(currMapping, segment)
+	tl.stats.Hits += 1
+	tl.stats.HitBytes += len(currMapping.value)
+	return currMapping.value, true
+}
+
+// touch makes the binding the most recently used in the window, or in the
+// protected segment of the main area.
+func (tl *TinyLFU) touch(currMapping mapping, segment *LRU) {
+	if segment == tl.window {
+		tl.window.access(currMapping.key)
+		return
+	}
+	tl.main.touch(currMapping, segment)
+}
+
+// Remove removes and returns the value associated with the given key, if it exists.
+// ok is true if a value was found and false otherwise
+func (tl *TinyLFU) Remove(key string) (value []byte, ok bool) {
+	_, segment := tl.lookup(key)
+	if segment == nil {
+		return nil, false
+	}
+
+	currMapping, _ := segment.removeMapping(key)
+	tl.notify(currMapping, ReasonRemoved)
+	return currMapping.value, true
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. A new binding enters the window; overwriting a binding counts
+// as a use of it and never evicts it.
+// Returns true if the binding was added successfully, else false.
+func (tl *TinyLFU) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > tl.capacity {
+		tl.stats.recordRejectedSet(value)
+		return false
+	}
+	tl.sketch.Increment(key)
+
+	if replaced, segment := tl.lookup(key); segment != nil {
+		tl.touch(replaced, segment)
+		_, segment = tl.lookup(key)
+
+		// Take the binding out while making room, so that it cannot be
+		// chosen as a victim
+		segment.removeMapping(key)
+		for tl.RemainingStorage() < currentObjectSize {
+			tl.Evict()
+		}
+		segment.insert(key, value, time.Time{})
+		tl.main.balance()
+		tl.maintain()
+
+		tl.stats.Sets += 1
+		tl.notify(replaced, ReasonReplaced)
+		return true
+	}
+
+	tl.window.insert(key, value, time.Time{})
+	tl.maintain()
+	tl.stats.Sets += 1
+	tl.stats.MissBytes += len(value)
+	return true
+}
+
+// maintain moves bindings from the back of the window to the main area, or
+// evicts them if they are refused admission, until the window is within its
+// share of the capacity or holds a single binding. It then evicts from the
+// main area until the TinyLFU is within its capacity, since the main area may
+// use space the window leaves free.
+func (tl *TinyLFU) maintain() {
+	for tl.window.currentlyUsedCapacity > tl.windowCapacity && tl.window.Len() > 1 {
+		candidate, _ := tl.window.evict()
+		if !tl.admit(candidate) {
+			tl.notify(candidate, ReasonEvicted)
+		}
+	}
+
+	for tl.RemainingStorage() < 0 {
+		tl.evictMain()
+	}
+}
+
+// admit adds candidate to the front of probation if the main area can make
+// room for it by evicting only bindings whose keys the sketch estimates were
+// used no more often than the candidate's, evicting them, and reports whether
+// it did.
+func (tl *TinyLFU) admit(candidate mapping) bool {
+	size := len(candidate.key) + len(candidate.value)
+	if size > tl.capacity-tl.window.currentlyUsedCapacity {
+		return false
+	}
+
+	// Check every victim before evicting any of them
+	frequency := tl.sketch.Estimate(candidate.key)
+	needed := size - tl.RemainingStorage()
+	victims := 0
+	for elem := tl.main.victimAfter(nil); needed > 0; elem = tl.main.victimAfter(elem) {
+		victim := elem.Value.(lruEntry)
+		if tl.sketch.Estimate(victim.key) > frequency {
+			return false
+		}
+		needed -= victim.size
+		victims += 1
+	}
+
+	for ; victims > 0; victims-- {
+		tl.evictMain()
+	}
+	tl.main.probation.insert(candidate.key, candidate.value, time.Time{})
+	return true
+}
+
+// evictMain evicts the first victim of the main area and returns its key.
+// ok is false if the main area was empty.
+func (tl *TinyLFU) evictMain() (key string, ok bool) {
+	currMapping, ok := tl.main.evict()
+	if !ok {
+		return "", false
+	}
+	tl.notify(currMapping, ReasonEvicted)
+	return currMapping.key, true
+}
+
+// Empty removes every binding from the TinyLFU. The sketch is kept.
+func (tl *TinyLFU) Empty() {
+	for _, segment := range []*LRU{tl.window, tl.main.probation, tl.main.protected} {
+		if tl.onEvict != nil {
+			for _, currMapping := range segment.cachedValues {
+				tl.notify(currMapping, ReasonEmptied)
+			}
+		}
+		segment.reset()
+	}
+}
+
+// Evict removes the first victim of the main area, or the least recently used
+// binding of the window if the main area is empty, and returns its key.
+// ok is false if the TinyLFU was already empty.
+func (tl *TinyLFU) Evict() (key string, ok bool) {
+	if key, ok := tl.evictMain(); ok {
+		return key, true
+	}
+	currMapping, ok := tl.window.evict()
+	if !ok {
+		return "", false
+	}
+	tl.notify(currMapping, ReasonEvicted)
+	return currMapping.key, true
+}
+
+// Len returns the number of bindings in the TinyLFU.
+func (tl *TinyLFU) Len() int {
+	return tl.window.Len() + tl.main.Len()
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (tl *TinyLFU) Stats() *Stats {
+	return &tl.stats
+}
@@ -0,0 +1,142 @@
+/******************************************************************************
+ * tinylfu_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for tinylfu.go. The Cache contract TinyLFU shares
+ *    with every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/workload"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a stream of keys used once is refused admission to the main area
+// instead of pushing out keys used often, where an LRU loses all of them
+func TestAdmissionTinyLfu(t *testing.T) {
+	tl, lru := NewTinyLfu(100), NewLru(100)
+	tl.SetWindowFraction(0)
+	for _, cache := range []Cache{tl, lru} {
+		for i := 0; i < 9; i++ {
+			key := fmt.Sprintf("____%d", i)
+			cache.Set(key, []byte(key))
+			cache.Get(key)
+			cache.Get(key)
+		}
+		for i := 10; i < 60; i++ {
+			key := fmt.Sprintf("___%d", i)
+			if !cache.Set(key, []byte(key)) {
+				t.Errorf("%s refused a new binding. Got %v, Expected %v", cacheType(cache), false, true)
+				t.FailNow()
+			}
+		}
+	}
+
+	for i := 0; i < 9; i++ {
+		key := fmt.Sprintf("____%d", i)
+		if _, ok := tl.Peek(key); !ok {
+			t.Errorf("One-hit wonders pushed out %s. Got %v, Expected %v", key, ok, true)
+			t.FailNow()
+		}
+		if _, ok := lru.Peek(key); ok {
+			t.Errorf("LRU kept %s. Got %v, Expected %v", key, ok, false)
+			t.FailNow()
+		}
+	}
+	if tl.Stats().Evictions != 49 || tl.window.Len() != 1 {
+		t.Errorf("Wrong evictions or window length. Got %v, %v, Expected %v, %v", tl.Stats().Evictions, tl.window.Len(), 49, 1)
+		t.FailNow()
+	}
+}
+
+// Check that window fractions outside [0, 1] are clamped to it
+func TestWindowFractionClampedTinyLfu(t *testing.T) {
+	tl := NewTinyLfu(100)
+	for _, c := range []struct {
+		fraction       float64
+		windowCapacity int
+	}{{-0.5, 0}, {1.5, 100}} {
+		tl.SetWindowFraction(c.fraction)
+		if tl.windowCapacity != c.windowCapacity || tl.main.capacity != 100-c.windowCapacity {
+			t.Errorf("Window fraction %v not clamped. Got %v, %v, Expected %v, %v", c.fraction, tl.windowCapacity, tl.main.capacity, c.windowCapacity, 100-c.windowCapacity)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that a binding used again in probation moves to the protected
+// segment, and that the protected segment overflows back into probation
+func TestSegmentsTinyLfu(t *testing.T) {
+	tl := NewTinyLfu(100)
+	tl.SetWindowFraction(0.1)
+	for i := 0; i < 9; i++ {
+		key := fmt.Sprintf("____%d", i)
+		tl.Set(key, []byte(key))
+	}
+	if tl.window.Len() != 1 || tl.main.probation.Len() != 8 {
+		t.Errorf("Wrong segment lengths. Got %v, %v, Expected %v, %v", tl.window.Len(), tl.main.probation.Len(), 1, 8)
+		t.FailNow()
+	}
+
+	for i := 0; i < 8; i++ {
+		tl.Get(fmt.Sprintf("____%d", i))
+	}
+	if tl.main.protected.currentlyUsedCapacity > tl.main.protectedCapacity || tl.main.protected.Len() != 7 {
+		t.Errorf("Protected segment not bounded. Got %v bytes, %v bindings, Expected at most %v bytes, %v bindings", tl.main.protected.currentlyUsedCapacity, tl.main.protected.Len(), tl.main.protectedCapacity, 7)
+		t.FailNow()
+	}
+	if _, ok := tl.main.probation.Peek("____0"); !ok {
+		t.Errorf("Least recently used protected binding not demoted. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+}
+
+// Check that a binding growing past the free space is not evicted to make
+// room for itself, in any segment
+func TestOverwriteTinyLfu(t *testing.T) {
+	tl := NewTinyLfu(100)
+	tl.SetWindowFraction(0.2)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("____%d", i)
+		tl.Set(key, []byte(key))
+	}
+	tl.Get("____0")
+
+	for _, key := range []string{"____0", "____1", "____9"} {
+		value := []byte("_________________________")
+		tl.Set(key, value)
+		if got, ok := tl.Peek(key); !ok || !bytesEqual(got, value) {
+			t.Errorf("Overwrite of %s lost the binding. Got %v, Expected %v", key, got, value)
+			t.FailNow()
+		}
+		if tl.RemainingStorage() < 0 {
+			t.Errorf("Overwrite exceeded the capacity. Got %v, Expected at least %v", tl.RemainingStorage(), 0)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that TinyLFU's hit ratio is at least LRU's on the benchmark workloads
+func TestComparableToLruTinyLfu(t *testing.T) {
+	for name, newConfig := range benchmarkWorkloads() {
+		events := workload.New(newConfig()).Events(50000)
+		tl, lru := NewTinyLfu(benchmarkCapacity), NewLru(benchmarkCapacity)
+		replayEvents(tl, events)
+		replayEvents(lru, events)
+
+		tlRatio, lruRatio := tl.Stats().HitRatio(), lru.Stats().HitRatio()
+		if tlRatio < lruRatio-0.01 {
+			t.Errorf("%s: TinyLFU hit ratio below LRU's. Got %v, Expected at least %v", name, tlRatio, lruRatio)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// An Expirer is a cache whose bindings can carry a time-to-live
+type Expirer interface {
+	// SetWithTTL behaves like Set, but the binding expires once ttl has
+	// elapsed. A ttl <= 0 means the binding never expires.
+	SetWithTTL(key string, value []byte, ttl time.Duration) bool
+
+	// RemoveExpired removes every binding whose time-to-live has run out and
+	// returns how many were removed.
+	RemoveExpired() int
+}
+
+// expiryFor returns the time at which a binding set now with the given ttl
+// expires, or the zero time if it never does.
+func expiryFor(clock Clock, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return clock.Now().Add(ttl)
+}
+
+// StartJanitor starts a goroutine that calls c.RemoveExpired() every interval,
+// so that expired bindings are reclaimed even if they are never accessed
+// again. The returned function stops the janitor and waits for it to exit; it
+// is safe to call more than once. Since the janitor runs concurrently with the
+// caller, c must be safe for concurrent use, e.g. a cache wrapped with
+// Synchronized. StartJanitor panics if interval is not positive.
+func StartJanitor(c Expirer, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		panic("cache: StartJanitor interval must be positive")
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.RemoveExpired()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
@@ -0,0 +1,272 @@
+/******************************************************************************
+ * ttl_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for per-binding expiration in ttl.go, lru.go and
+ *    arc.go, driven by a fake clock.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// expiringCache is a Cache that supports per-binding expiration and a fake clock.
+type expiringCache interface {
+	Cache
+	Expirer
+	SetClock(clock Clock)
+}
+
+// newExpiringCaches returns one of each expiring cache, all driven by clock.
+func newExpiringCaches(capacity int, clock Clock) []expiringCache {
+	caches := []expiringCache{NewLru(capacity), NewArc(capacity)}
+	for _, cache := range caches {
+		cache.SetClock(clock)
+	}
+	return caches
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a binding is visible until its TTL runs out, and a miss afterwards
+func TestExpiryTTL(t *testing.T) {
+	clock := newFakeClock()
+	for _, cache := range newExpiringCaches(1024, clock) {
+		ok := cache.SetWithTTL("key", []byte("value"), time.Minute)
+		if !ok {
+			t.Errorf("%s failed to add binding. Got %v, Expected %v", cacheType(cache), ok, true)
+			t.FailNow()
+		}
+
+		if _, ok := cache.Peek("key"); !ok {
+			t.Errorf("%s lost binding before TTL. Got %v, Expected %v", cacheType(cache), ok, true)
+			t.FailNow()
+		}
+		if _, ok := cache.Get("key"); !ok {
+			t.Errorf("%s lost binding before TTL. Got %v, Expected %v", cacheType(cache), ok, true)
+			t.FailNow()
+		}
+
+		clock.Advance(time.Minute)
+
+		if value, ok := cache.Peek("key"); ok || value != nil {
+			t.Errorf("%s peeked expired binding. Got %v, Expected %v", cacheType(cache), value, nil)
+			t.FailNow()
+		}
+		if cache.Len() != 1 {
+			t.Errorf("%s Peek removed a binding. Got %v, Expected %v", cacheType(cache), cache.Len(), 1)
+			t.FailNow()
+		}
+
+		if value, ok := cache.Get("key"); ok || value != nil {
+			t.Errorf("%s fetched expired binding. Got %v, Expected %v", cacheType(cache), value, nil)
+			t.FailNow()
+		}
+		if cache.Len() != 0 {
+			t.Errorf("%s did not reclaim expired binding on Get. Got %v, Expected %v", cacheType(cache), cache.Len(), 0)
+			t.FailNow()
+		}
+		if cache.RemainingStorage() != 1024 {
+			t.Errorf("%s RemainingStorage wrong after expiry. Got %v, Expected %v", cacheType(cache), cache.RemainingStorage(), 1024)
+			t.FailNow()
+		}
+
+		stats := cache.Stats()
+		if stats.Hits != 1 || stats.Misses != 1 {
+			t.Errorf("%s Stats wrong. Got %v hits and %v misses, Expected %v and %v", cacheType(cache), stats.Hits, stats.Misses, 1, 1)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that bindings without a TTL, or with a non-positive one, never expire
+func TestNoExpiryTTL(t *testing.T) {
+	clock := newFakeClock()
+	for _, cache := range newExpiringCaches(1024, clock) {
+		cache.Set("forever", []byte("value"))
+		cache.SetWithTTL("zero", []byte("value"), 0)
+		cache.SetWithTTL("negative", []byte("value"), -time.Second)
+
+		clock.Advance(1000 * time.Hour)
+
+		for _, key := range []string{"forever", "zero", "negative"} {
+			if _, ok := cache.Get(key); !ok {
+				t.Errorf("%s expired binding %s without TTL. Got %v, Expected %v", cacheType(cache), key, ok, true)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+// Check that Set() replaces the TTL of an existing binding
+func TestOverwriteTTL(t *testing.T) {
+	clock := newFakeClock()
+	for _, cache := range newExpiringCaches(1024, clock) {
+		cache.SetWithTTL("cleared", []byte("old"), time.Second)
+		cache.Set("cleared", []byte("new"))
+		cache.Set("added", []byte("old"))
+		cache.SetWithTTL("added", []byte("new"), time.Second)
+
+		clock.Advance(time.Second)
+
+		if _, ok := cache.Get("cleared"); !ok {
+			t.Errorf("%s kept TTL after Set. Got %v, Expected %v", cacheType(cache), ok, true)
+			t.FailNow()
+		}
+		if _, ok := cache.Get("added"); ok {
+			t.Errorf("%s ignored TTL set on existing binding. Got %v, Expected %v", cacheType(cache), ok, false)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that setting a key whose binding has expired reports the old binding
+// as expired rather than replaced, and adds the new one as a fresh binding
+func TestOverwriteExpiredTTL(t *testing.T) {
+	clock := newFakeClock()
+	for _, cache := range newExpiringCaches(1024, clock) {
+		removals := recordRemovals(cache.(notifyingCache))
+		cache.SetWithTTL("key", []byte("old"), time.Second)
+		clock.Advance(time.Second)
+
+		if ok := cache.Set("key", []byte("new")); !ok {
+			t.Errorf("%s failed to set an expired key. Got %v, Expected %v", cacheType(cache), ok, true)
+			t.FailNow()
+		}
+		checkRemovals(t, cache, *removals, []removal{{"key", "old", ReasonExpired}})
+		if value, ok := cache.Peek("key"); !ok || !bytesEqual(value, []byte("new")) || cache.RemainingStorage() != 1024-len("keynew") {
+			t.Errorf("%s did not add the new binding. Got %v with %v bytes left, Expected %v with %v", cacheType(cache), value, cache.RemainingStorage(), []byte("new"), 1024-len("keynew"))
+			t.FailNow()
+		}
+	}
+
+	// ARC adds the new binding to t1, rather than promoting the expired one
+	arc := NewArc(1024)
+	arc.SetClock(clock)
+	arc.SetWithTTL("key", []byte("old"), time.Second)
+	clock.Advance(time.Second)
+	arc.Set("key", []byte("new"))
+	if _, ok := arc.policy.t1.nodes["key"]; !ok || arc.policy.t2.Len() != 0 {
+		t.Errorf("Expired binding was promoted to t2. Got %v in t1, Expected %v", ok, true)
+		t.FailNow()
+	}
+}
+
+// Check that ARC keeps a binding's TTL when promoting it from t1 to t2
+func TestPromotionTTLArc(t *testing.T) {
+	clock := newFakeClock()
+	arc := NewArc(1024)
+	arc.SetClock(clock)
+
+	arc.SetWithTTL("key", []byte("value"), time.Minute)
+	arc.Get("key")
+	if _, ok := arc.policy.t2.nodes["key"]; !ok {
+		t.Errorf("Binding was not promoted to t2. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := arc.Get("key"); ok {
+		t.Errorf("Promoted binding lost its TTL. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if arc.RemainingStorage() != 1024 {
+		t.Errorf("RemainingStorage wrong after expiry. Got %v, Expected %v", arc.RemainingStorage(), 1024)
+		t.FailNow()
+	}
+}
+
+// Check that RemoveExpired() reclaims exactly the expired bindings
+func TestRemoveExpiredTTL(t *testing.T) {
+	clock := newFakeClock()
+	for _, cache := range newExpiringCaches(1024, clock) {
+		cache.SetWithTTL("short1", []byte("value"), time.Second)
+		cache.SetWithTTL("short2", []byte("value"), time.Second)
+		cache.SetWithTTL("long", []byte("value"), time.Hour)
+		cache.Set("forever", []byte("value"))
+		cache.Get("short2")
+
+		clock.Advance(time.Minute)
+
+		removed := cache.RemoveExpired()
+		if removed != 2 {
+			t.Errorf("%s RemoveExpired removed wrong number of bindings. Got %v, Expected %v", cacheType(cache), removed, 2)
+			t.FailNow()
+		}
+		if cache.Len() != 2 {
+			t.Errorf("%s Len wrong after RemoveExpired. Got %v, Expected %v", cacheType(cache), cache.Len(), 2)
+			t.FailNow()
+		}
+		expected := 1024 - len("long") - len("forever") - 2*len("value")
+		if cache.RemainingStorage() != expected {
+			t.Errorf("%s RemainingStorage wrong after RemoveExpired. Got %v, Expected %v", cacheType(cache), cache.RemainingStorage(), expected)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that the janitor reclaims expired bindings in the background
+func TestJanitorTTL(t *testing.T) {
+	clock := newFakeClock()
+	lru := NewLru(1024)
+	lru.SetClock(clock)
+	cache := Synchronized(lru)
+
+	cache.(Expirer).SetWithTTL("key", []byte("value"), time.Second)
+	clock.Advance(time.Second)
+
+	stop := StartJanitor(cache.(Expirer), time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Errorf("Janitor did not reclaim expired binding. Got %v, Expected %v", cache.Len(), 0)
+			t.FailNow()
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stop()
+	stop()
+}
+
+// Check that StartJanitor refuses an interval that is not positive, rather
+// than panicking inside its goroutine
+func TestJanitorIntervalTTL(t *testing.T) {
+	cache := Synchronized(NewLru(1024)).(Expirer)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if r := recover(); r != "cache: StartJanitor interval must be positive" {
+					t.Errorf("Wrong panic for interval %v. Got %v, Expected %v", interval, r, "cache: StartJanitor interval must be positive")
+				}
+			}()
+			stop := StartJanitor(cache, interval)
+			stop()
+		}()
+	}
+}
+
+// Check that a Synchronized cache only accepts TTLs if the wrapped cache does
+func TestSynchronizedTTL(t *testing.T) {
+	cache := Synchronized(NewFifo(1024)).(Expirer)
+	if ok := cache.SetWithTTL("key", []byte("value"), time.Second); ok {
+		t.Errorf("Set TTL on a cache without expiration. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if removed := cache.RemoveExpired(); removed != 0 {
+		t.Errorf("RemoveExpired wrong on a cache without expiration. Got %v, Expected %v", removed, 0)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"time"
+)
+
+const (
+	// DefaultInFraction is the share of a TwoQueue's capacity A1in may hold
+	// before it is preferred for eviction, as recommended by the 2Q paper.
+	DefaultInFraction = 0.25
+
+	// DefaultOutFraction is the total size, as a share of a TwoQueue's
+	// capacity, of the bindings A1out remembers, as recommended by the 2Q
+	// paper.
+	DefaultOutFraction = 0.5
+)
+
+// A TwoQueue is a fixed-size in-memory cache using the full 2Q algorithm
+// (Johnson and Shasha, VLDB '94). A new binding enters A1in, a FIFO, and a hit
+// there does not move it. When A1in outgrows its share of the capacity, its
+// oldest binding is evicted and remembered in A1out, a queue of ghost
+// entries. A key set again while it is remembered in A1out has been reused at
+// a distance longer than A1in, so it goes into Am, an LRU. A scan only ever
+// passes through A1in and A1out, leaving Am untouched.
+type TwoQueue struct {
+	capacity int // To hold the capacity of the cache
+	inSize   int // Bytes A1in may hold before it is preferred for eviction
+	outSize  int // Total size of the bindings A1out may remember
+
+	a1in  *FIFO      // To hold bindings seen once recently
+	a1out *ghostList // To hold ghost entries evicted from a1in
+	am    *LRU       // To hold bindings reused after leaving a1in
+
+	stats   Stats            // Hits and misses for the cache
+	onEvict EvictionCallback // Called whenever a binding leaves the cache
+}
+
+// NewTwoQueue returns a pointer to a new TwoQueue with a capacity to store
+// limit bytes, with queue sizes of DefaultInFraction and DefaultOutFraction.
+func NewTwoQueue(limit int) *TwoQueue {
+	tq := &TwoQueue{capacity: limit, a1in: NewFifo(limit), a1out: newGhostList(), am: NewLru(limit), stats: Stats{}}
+	tq.SetQueueFractions(DefaultInFraction, DefaultOutFraction)
+
+	// Bindings leaving a1in or am are reported, and those evicted from
+	// a1in are remembered in a1out
+	tq.a1in.OnEvict(func(key string, value []byte, reason RemovalReason) {
+		if reason == ReasonEvicted {
+			tq.a1out.push(key, len(key)+len(value))
+			tq.trimOut()
+		}
+		tq.notify(key, value, reason)
+	})
+	tq.am.OnEvict(tq.notify)
+	return tq
+}
+
+func init() {
+	Register("2q", func(limit int) Cache { return NewTwoQueue(limit) })
+}
+
+// SetQueueFractions sets the share of the capacity A1in may hold before it is
+// preferred for eviction, and the total size of the bindings A1out remembers,
+// also as a share of the capacity. Both are clamped to [0, 1].
+func (tq *TwoQueue) SetQueueFractions(in float64, out float64) {
+	tq.inSize = int(clampFraction(in) * float64(tq.capacity))
+	tq.outSize = int(clampFraction(out) * float64(tq.capacity))
+	tq.trimOut()
+}
+
+// OnEvict registers fn to be called whenever a binding leaves the TwoQueue,
+// replacing any previously registered callback. A binding evicted from A1in
+// is reported as evicted, since A1out keeps only its key.
+func (tq *TwoQueue) OnEvict(fn EvictionCallback) {
+	tq.onEvict = fn
+}
+
+// notify records in the stats that a binding left the TwoQueue, and reports
+// it to the eviction callback, if any.
+func (tq *TwoQueue) notify(key string, value []byte, reason RemovalReason) {
+	tq.stats.recordRemoval(reason)
+	if tq.onEvict != nil {
+		tq.onEvict(key, value, reason)
+	}
+}
+
+// MaxStorage returns the maximum number of bytes this TwoQueue can store
+func (tq *TwoQueue) MaxStorage() int {
+	return tq.capacity
+}
+
+// RemainingStorage returns the number of unused bytes available in this TwoQueue
+func (tq *TwoQueue) RemainingStorage() int {
+	return tq.capacity - tq.a1in.currentlyUsedCapacity - tq.am.currentlyUsedCapacity
+}
+
+// Peek returns the value associated with the given key, if it exists.
+// This operation does not counts as a "use" for that key-value pair
+// ok is true if a value was found and false otherwise.
+func (tq *TwoQueue) Peek(key string) (value []byte, ok bool) {
+	if value, ok := tq.a1in.Peek(key); ok {
+		return value, ok
+	}
+	return tq.am.Peek(key)
+}
+
+// Get returns the value associated with the given key, if it exists.
+// A hit in Am makes the binding the most recently used; a hit in A1in leaves
+// it where it is.
+// ok is true if a value was found and false otherwise.
+func (tq *TwoQueue) Get(key string) (value []byte, ok bool) {
+	value, ok = tq.am.Get(key)
+	if !ok {
+		value, ok = tq.a1in.Get(key)
+	}
+
+	if ok {
+		tq.stats.Hits += 1
+		tq.stats.HitBytes += len(value)
+	} else {
+		tq.stats.Misses += 1
+	}
+	return value, ok
+}
+
+// Remove removes and returns the value associated with the given key, if it
+// exists. A key remembered in A1out is forgotten, but is not a binding.
+// ok is true if a value was found and false otherwise
+func (tq *TwoQueue) Remove(key string) (value []byte, ok bool) {
+	tq.a1out.remove(key)
+	if value, ok := tq.a1in.Remove(key); ok {
+		return value, ok
+	}
+	return tq.am.Remove(key)
+}
+
+// Set associates the given value with the given key, possibly evicting values
+// to make room. Overwriting a binding never evicts it; if it grows, it is
+// moved to the newest end of its queue.
+// Returns true if the binding was added successfully, else false.
+func (tq *TwoQueue) Set(key string, value []byte) bool {
+	currentObjectSize := len(key) + len(value)
+	// If objectSize is larger than the whole cache
+	if currentObjectSize > tq.capacity {
+		tq.stats.recordRejectedSet(value)
+		return false
+	}
+
+	if replaced, ok := tq.a1in.cachedValues[key]; ok {
+		tq.overwrite(replaced, value, func() { tq.a1in.Set(key, value) }, tq.a1in.removeMapping)
+		return true
+	}
+	if replaced, ok := tq.am.cachedValues[key]; ok {
+		tq.overwrite(replaced, value, func() { tq.am.Set(key, value) }, tq.am.removeMapping)
+		return true
+	}
+
+	// Forget key before making room, since that may trim A1out
+	_, reused := tq.a1out.remove(key)
+	for tq.RemainingStorage() < currentObjectSize {
+		tq.reclaim()
+	}
+
+	if reused {
+		tq.am.insert(key, value, time.Time{})
+	} else {
+		tq.a1in.Set(key, value)
+	}
+	tq.stats.Sets += 1
+	tq.stats.MissBytes += len(value)
+	return true
+}
+
+// overwrite replaces the binding replaced with value. If the new value fits,
+// set overwrites it in place in the queue holding it, which reports it
+// replaced. Otherwise the binding is taken out with remove while room is
+// made, so that it cannot evict itself, and put back by set.
+func (tq *TwoQueue) overwrite(replaced mapping, value []byte, set func(), remove func(key string) (mapping, bool)) {
+	if growth := len(value) - len(replaced.value); growth <= tq.RemainingStorage() {
+		set()
+		tq.stats.Sets += 1
+		return
+	}
+
+	remove(replaced.key)
+	for tq.RemainingStorage() < len(replaced.key)+len(value) {
+		tq.reclaim()
+	}
+	set()
+	tq.stats.Sets += 1
+	tq.notify(replaced.key, replaced.value, ReasonReplaced)
+}
+
+// Empty removes every binding from the TwoQueue and forgets A1out.
+func (tq *TwoQueue) Empty() {
+	tq.a1in.Empty()
+	tq.am.Empty()
+	tq.a1out.reset()
+}
+
+// Evict removes one binding from the TwoQueue, as if to make room for a new
+// one, and returns its key.
+// ok is false if the TwoQueue was already empty.
+func (tq *TwoQueue) Evict() (key string, ok bool) {
+	if tq.Len() == 0 {
+		return "", false
+	}
+	return tq.reclaim(), true
+}
+
+// reclaim evicts the oldest binding of A1in if A1in is over its share of the
+// capacity, or the least recently used binding of Am otherwise, and returns
+// its key. The TwoQueue must not be empty.
+func (tq *TwoQueue) reclaim() string {
+	if tq.a1in.currentlyUsedCapacity > tq.inSize || tq.am.Len() == 0 {
+		key, _ := tq.a1in.Evict()
+		return key
+	}
+	key, _ := tq.am.Evict()
+	return key
+}
+
+// trimOut forgets the oldest entries of A1out until it is within its size.
+func (tq *TwoQueue) trimOut() {
+	for tq.a1out.size > tq.outSize {
+		tq.a1out.popOldest()
+	}
+}
+
+// Len returns the number of bindings in the TwoQueue.
+func (tq *TwoQueue) Len() int {
+	return tq.a1in.Len() + tq.am.Len()
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (tq *TwoQueue) Stats() *Stats {
+	return &tq.stats
+}
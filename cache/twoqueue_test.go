@@ -0,0 +1,168 @@
+/******************************************************************************
+ * twoqueue_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for twoqueue.go. The Cache contract 2Q shares with
+ *    every policy is checked by conformance_test.go.
+ ******************************************************************************/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that a binding evicted from A1in is remembered in A1out, and that
+// setting it again while it is remembered puts it in Am
+func TestPromoteTwoQueue(t *testing.T) {
+	tq := NewTwoQueue(40)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("____%d", i)
+		tq.Set(key, []byte(key))
+	}
+	if !tq.a1out.contains("____0") {
+		t.Errorf("Binding evicted from A1in not remembered in A1out. Got %v, Expected %v", false, true)
+		t.FailNow()
+	}
+
+	tq.Set("____0", []byte("____0"))
+	if _, ok := tq.am.Peek("____0"); !ok {
+		t.Errorf("Binding remembered in A1out not set in Am. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if tq.a1out.contains("____0") || !tq.a1out.contains("____1") {
+		t.Errorf("Wrong keys remembered in A1out. Got %v, %v, Expected %v, %v", tq.a1out.contains("____0"), tq.a1out.contains("____1"), false, true)
+		t.FailNow()
+	}
+	if tq.Stats().Evictions != 2 {
+		t.Errorf("Wrong number of evictions. Got %v, Expected %v", tq.Stats().Evictions, 2)
+		t.FailNow()
+	}
+}
+
+// Check that a hit in A1in neither moves the binding nor promotes it to Am
+func TestCorrelatedHitTwoQueue(t *testing.T) {
+	tq := NewTwoQueue(40)
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("____%d", i)
+		tq.Set(key, []byte(key))
+	}
+	tq.Get("____0")
+	tq.Set("____4", []byte("____4"))
+
+	if _, ok := tq.Peek("____0"); ok {
+		t.Errorf("Hit in A1in saved the oldest binding. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if tq.am.Len() != 0 {
+		t.Errorf("Hit in A1in promoted a binding to Am. Got %v, Expected %v", tq.am.Len(), 0)
+		t.FailNow()
+	}
+}
+
+// Check that a scan of keys seen once never evicts a binding from Am, and that
+// A1out stays within its size
+func TestScanResistanceTwoQueue(t *testing.T) {
+	tq := NewTwoQueue(40)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("____%d", i)
+		tq.Set(key, []byte(key))
+	}
+	tq.Set("____0", []byte("____0"))
+
+	for i := 10; i < 100; i++ {
+		key := fmt.Sprintf("___%d", i)
+		tq.Set(key, []byte(key))
+		if tq.a1out.size > tq.outSize {
+			t.Errorf("A1out grew past its size. Got %v, Expected at most %v", tq.a1out.size, tq.outSize)
+			t.FailNow()
+		}
+	}
+	if value, ok := tq.Get("____0"); !ok || !bytesEqual(value, []byte("____0")) {
+		t.Errorf("Scan evicted a binding from Am. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+}
+
+// Check that the queue sizes follow the fractions of the capacity
+func TestQueueFractionsTwoQueue(t *testing.T) {
+	tq := NewTwoQueue(40)
+	if tq.inSize != 10 || tq.outSize != 20 {
+		t.Errorf("Wrong default queue sizes. Got %v, %v, Expected %v, %v", tq.inSize, tq.outSize, 10, 20)
+		t.FailNow()
+	}
+
+	tq.SetQueueFractions(0.5, 0.25)
+	for i := 0; i < 6; i++ {
+		key := fmt.Sprintf("____%d", i)
+		tq.Set(key, []byte(key))
+	}
+	if tq.a1out.len() != 1 || !tq.a1out.contains("____1") {
+		t.Errorf("A1out not limited to its fraction. Got %v, Expected %v", tq.a1out.len(), 1)
+		t.FailNow()
+	}
+
+	// Setting a key remembered in A1out must not trim it from A1out first
+	tq.Set("____1", []byte("____1"))
+	if _, ok := tq.am.Peek("____1"); !ok {
+		t.Errorf("Binding remembered in A1out not set in Am. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+
+	// Am is evicted once A1in is within its fraction
+	tq.Remove("____3")
+	tq.Set("____6", []byte("_______________"))
+	if _, ok := tq.am.Peek("____1"); ok || tq.a1in.Len() != 3 {
+		t.Errorf("Evicted from A1in within its fraction. Got %v, %v, Expected %v, %v", ok, tq.a1in.Len(), false, 3)
+		t.FailNow()
+	}
+}
+
+// Check that queue fractions outside [0, 1] are clamped to it
+func TestQueueFractionsClampedTwoQueue(t *testing.T) {
+	tq := NewTwoQueue(40)
+	tq.SetQueueFractions(-0.5, 1.5)
+	if tq.inSize != 0 || tq.outSize != 40 {
+		t.Errorf("Queue fractions not clamped. Got %v, %v, Expected %v, %v", tq.inSize, tq.outSize, 0, 40)
+		t.FailNow()
+	}
+}
+
+// Check that a binding growing past the free space is not evicted to make
+// room for itself, in either A1in or Am
+func TestOverwriteTwoQueue(t *testing.T) {
+	tq := NewTwoQueue(40)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("____%d", i)
+		tq.Set(key, []byte(key))
+	}
+	tq.Set("____0", []byte("____0"))
+
+	for _, key := range []string{"____0", "____4"} {
+		queue := Cache(tq.a1in)
+		if _, ok := tq.am.Peek(key); ok {
+			queue = tq.am
+		}
+
+		value := []byte("_______________")
+		tq.Set(key, value)
+		if _, ok := queue.Peek(key); !ok {
+			t.Errorf("Overwrite moved %s out of its queue. Got %v, Expected %v", key, ok, true)
+			t.FailNow()
+		}
+		if got, ok := tq.Peek(key); !ok || !bytesEqual(got, value) {
+			t.Errorf("Overwrite of %s lost the binding. Got %v, Expected %v", key, got, value)
+			t.FailNow()
+		}
+		if tq.RemainingStorage() < 0 {
+			t.Errorf("Overwrite exceeded the capacity. Got %v, Expected at least %v", tq.RemainingStorage(), 0)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,457 @@
+// Package cachetest checks that an implementation of cache.Cache honours the
+// contract every policy in the cache package shares, whatever it evicts.
+//
+// A test for a new policy only has to call RunConformance with a constructor:
+//
+//	func TestConformance(t *testing.T) {
+//		cachetest.RunConformance(t, func(limit int) cache.Cache { return NewMyPolicy(limit) })
+//	}
+package cachetest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/cache"
+)
+
+// checks lists the parts of the contract, by subtest name
+var checks = []struct {
+	name string
+	run  func(t *testing.T, newCache func(limit int) cache.Cache)
+}{
+	{"New", testNew},
+	{"GetEmpty", testGetEmpty},
+	{"PeekEmpty", testPeekEmpty},
+	{"SingleBinding", testSingleBinding},
+	{"Storage", testStorage},
+	{"SetFull", testSetFull},
+	{"SetTooLarge", testSetTooLarge},
+	{"SetZero", testSetZero},
+	{"EmptyStringValid", testEmptyStringValid},
+	{"EmptyValid", testEmptyValid},
+	{"NilValid", testNilValid},
+	{"BinaryValues", testBinaryValues},
+	{"UnicodeValues", testUnicodeValues},
+	{"SetOverwrite", testSetOverwrite},
+	{"SetOverwriteStorage", testSetOverwriteStorage},
+	{"RemovePreventGet", testRemovePreventGet},
+	{"RemoveStorage", testRemoveStorage},
+	{"RemoveOverwritten", testRemoveOverwritten},
+	{"RemoveEmpty", testRemoveEmpty},
+	{"RemoveRemoved", testRemoveRemoved},
+	{"Stats", testStats},
+	{"PeekStats", testPeekStats},
+	{"Empty", testEmpty},
+}
+
+// RunConformance runs every check of the Cache contract as a subtest of t,
+// each against fresh caches from newCache, which must return an empty cache
+// with a capacity to store limit bytes. The contract is the one the cache
+// package documents: a binding takes len(key) + len(value) bytes, the empty
+// key and empty or nil values are valid, a binding larger than the capacity
+// is rejected, overwriting a key replaces its value and its size, Peek is not
+// counted in the stats, and Empty removes every binding.
+func RunConformance(t *testing.T, newCache func(limit int) cache.Cache) {
+	for _, check := range checks {
+		check := check
+		t.Run(check.name, func(t *testing.T) {
+			check.run(t, newCache)
+		})
+	}
+}
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// newChecked returns a cache from newCache with a capacity of limit bytes,
+// failing t if it is not empty or does not have that capacity.
+func newChecked(t *testing.T, newCache func(limit int) cache.Cache, limit int) cache.Cache {
+	c := newCache(limit)
+	if c.Len() != 0 {
+		t.Errorf("New cache returned wrong length. Got %v, Expected %v", c.Len(), 0)
+		t.FailNow()
+	}
+	if c.MaxStorage() != limit {
+		t.Errorf("New cache returned wrong maxStorage. Got %v, Expected %v", c.MaxStorage(), limit)
+		t.FailNow()
+	}
+	if c.RemainingStorage() != limit {
+		t.Errorf("New cache returned wrong remainingStorage. Got %v, Expected %v", c.RemainingStorage(), limit)
+		t.FailNow()
+	}
+	return c
+}
+
+// checkBinding fails t unless setting key to value in an empty cache with a
+// capacity of 1024 bytes succeeds, uses len(key) + len(value) bytes, and can
+// be read back.
+func checkBinding(t *testing.T, newCache func(limit int) cache.Cache, key string, value []byte) {
+	capacity := 1024
+	c := newChecked(t, newCache, capacity)
+
+	ok := c.Set(key, value)
+	if !ok {
+		t.Errorf("Failed to add binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if expected := capacity - len(key) - len(value); c.RemainingStorage() != expected {
+		t.Errorf("RemainingStorage wrong after adding binding. Got %v, Expected %v", c.RemainingStorage(), expected)
+		t.FailNow()
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() wrong. Got %v, Expected %v", c.Len(), 1)
+		t.FailNow()
+	}
+	if c.MaxStorage() != capacity {
+		t.Errorf("MaxStorage wrong. Got %v, Expected %v", c.MaxStorage(), capacity)
+		t.FailNow()
+	}
+
+	got, ok := c.Get(key)
+	if !ok || !bytes.Equal(got, value) {
+		t.Errorf("Fetched wrong value. Got %v, Expected %v", got, value)
+		t.FailNow()
+	}
+}
+
+// checkGet fails t unless c holds value for key.
+func checkGet(t *testing.T, c cache.Cache, key string, value []byte) {
+	got, ok := c.Get(key)
+	if !ok || !bytes.Equal(got, value) {
+		t.Errorf("Fetched wrong value for %q. Got %v, Expected %v", key, got, value)
+		t.FailNow()
+	}
+}
+
+// checkUsage fails t unless c holds length bindings and has remaining bytes free.
+func checkUsage(t *testing.T, c cache.Cache, length int, remaining int) {
+	if c.Len() != length {
+		t.Errorf("Len wrong. Got %v, Expected %v", c.Len(), length)
+		t.FailNow()
+	}
+	if c.RemainingStorage() != remaining {
+		t.Errorf("RemainingStorage wrong. Got %v, Expected %v", c.RemainingStorage(), remaining)
+		t.FailNow()
+	}
+}
+
+/******************************************************************************/
+/*                                  Checks                                    */
+/******************************************************************************/
+
+// Check that newCache returns an empty cache of the correct size
+func testNew(t *testing.T, newCache func(limit int) cache.Cache) {
+	for _, capacity := range []int{0, 16, 32, 64, 128} {
+		newChecked(t, newCache, capacity)
+	}
+}
+
+// Check that Get() returns no binding when called on an empty cache
+func testGetEmpty(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	for _, key := range []string{"Hello", "a", "ssup", ""} {
+		if value, ok := c.Get(key); ok || value != nil {
+			t.Errorf("Returned wrong value for empty cache. Got %v, %v, Expected %v, %v", value, ok, nil, false)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that Peek() returns no binding when called on an empty cache
+func testPeekEmpty(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	for _, key := range []string{"Hello", "a", "ssup", ""} {
+		if value, ok := c.Peek(key); ok || value != nil {
+			t.Errorf("Returned wrong value for empty cache. Got %v, %v, Expected %v, %v", value, ok, nil, false)
+			t.FailNow()
+		}
+	}
+}
+
+// Check various operations on a cache with a single binding
+func testSingleBinding(t *testing.T, newCache func(limit int) cache.Cache) {
+	capacities := []int{16, 64, 256}
+	keys := []string{"Hello", "Foo", "COS"}
+	values := []string{"World", "Bar", "316"}
+
+	for i := range keys {
+		c := newChecked(t, newCache, capacities[i])
+		c.Set(keys[i], []byte(values[i]))
+		checkGet(t, c, keys[i], []byte(values[i]))
+		checkUsage(t, c, 1, capacities[i]-len(keys[i])-len(values[i]))
+	}
+}
+
+// Add 20 bindings to a cache, checking each one consumes the right storage
+func testStorage(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	for i := 0; i < 20; i++ {
+		remainingBefore := c.RemainingStorage()
+		key := fmt.Sprintf("key%d", i)
+		if ok := c.Set(key, []byte(key)); !ok {
+			t.Errorf("Failed to add binding with key: %s", key)
+			t.FailNow()
+		}
+		checkUsage(t, c, i+1, remainingBefore-2*len(key))
+	}
+}
+
+// Check that Set() adds bindings to a 'full' cache by evicting old ones
+func testSetFull(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 30)
+	for i := 0; i < 6; i++ {
+		key := fmt.Sprintf("____%d", i)
+		if ok := c.Set(key, []byte(key)); !ok {
+			t.Errorf("Failed to add binding to full cache. Got %v, Expected %v", ok, true)
+			t.FailNow()
+		}
+		if i >= 2 {
+			checkUsage(t, c, 3, 0)
+		}
+	}
+}
+
+// Check that Set() rejects bindings too large for the cache, leaving it as it was
+func testSetTooLarge(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 10)
+	c.Set("a", []byte("b"))
+
+	if ok := c.Set("123456", []byte("123456")); ok {
+		t.Errorf("Failed to reject binding too large for cache. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	checkUsage(t, c, 1, 8)
+	if _, ok := c.Get("123456"); ok {
+		t.Errorf("Fetched binding too large for cache. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	checkGet(t, c, "a", []byte("b"))
+}
+
+// Check that Set() only allows zero-size bindings in a zero-capacity cache
+func testSetZero(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 0)
+	if ok := c.Set("hello", []byte("world")); ok {
+		t.Errorf("Failed to reject binding too large for cache. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if ok := c.Set("foo", []byte("boo")); ok {
+		t.Errorf("Failed to reject binding too large for cache. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if ok := c.Set("", []byte("")); !ok {
+		t.Errorf("Failed to add zero-size binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	checkUsage(t, c, 1, 0)
+}
+
+// Check that the cache allows the empty string as a valid key
+func testEmptyStringValid(t *testing.T, newCache func(limit int) cache.Cache) {
+	checkBinding(t, newCache, "", []byte("Value"))
+}
+
+// Check that the cache allows the empty []byte as a valid value
+func testEmptyValid(t *testing.T, newCache func(limit int) cache.Cache) {
+	checkBinding(t, newCache, "key", []byte{})
+}
+
+// Check that the cache allows nil as a valid value
+func testNilValid(t *testing.T, newCache func(limit int) cache.Cache) {
+	checkBinding(t, newCache, "key", nil)
+}
+
+// Check that values can be non-ASCII (binary)
+func testBinaryValues(t *testing.T, newCache func(limit int) cache.Cache) {
+	checkBinding(t, newCache, "key", []byte("\x00\x01\xff\x15\xfe"))
+}
+
+// Check that keys and values can be non-ASCII (Unicode)
+func testUnicodeValues(t *testing.T, newCache func(limit int) cache.Cache) {
+	checkBinding(t, newCache, "😂_🚀", []byte("✔_🚗"))
+}
+
+// Test that Set() overwrites values when called with an existing key
+func testSetOverwrite(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	if ok := c.Set("key", []byte("old")); !ok {
+		t.Errorf("Failed to add binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	checkGet(t, c, "key", []byte("old"))
+
+	if ok := c.Set("key", []byte("new")); !ok {
+		t.Errorf("Failed to overwrite binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	checkGet(t, c, "key", []byte("new"))
+	checkUsage(t, c, 1, 1018)
+}
+
+// Test that Set() accounts for the size of the new value when overwriting
+func testSetOverwriteStorage(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	c.Set("key", []byte("old"))
+	checkUsage(t, c, 1, 1018)
+
+	c.Set("key", []byte("nw"))
+	checkGet(t, c, "key", []byte("nw"))
+	checkUsage(t, c, 1, 1019)
+
+	c.Set("key", []byte("longer"))
+	checkGet(t, c, "key", []byte("longer"))
+	checkUsage(t, c, 1, 1015)
+}
+
+// Check that Remove() returns the value and prevents Get() from retrieving it
+func testRemovePreventGet(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	c.Set("key", []byte("value"))
+	checkGet(t, c, "key", []byte("value"))
+
+	value, ok := c.Remove("key")
+	if !ok || !bytes.Equal(value, []byte("value")) {
+		t.Errorf("Failed to remove binding. Got %v, %v, Expected %v, %v", value, ok, []byte("value"), true)
+		t.FailNow()
+	}
+	if _, ok := c.Get("key"); ok {
+		t.Errorf("Fetched a removed value. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	if _, ok := c.Peek("key"); ok {
+		t.Errorf("Peeked at a removed value. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+}
+
+// Check that Remove() correctly updates available storage
+func testRemoveStorage(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("__%d", i)
+		c.Set(key, []byte(key))
+	}
+
+	c.Remove("__0")
+	checkUsage(t, c, 3, 1006)
+	c.Remove("__1")
+	checkUsage(t, c, 2, 1012)
+}
+
+// Check that Remove() works as expected on bindings whose values have been overwritten
+func testRemoveOverwritten(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	c.Set("key", []byte("old"))
+	c.Set("key", []byte("newval"))
+
+	value, ok := c.Remove("key")
+	if !ok || !bytes.Equal(value, []byte("newval")) {
+		t.Errorf("Failed to remove overwritten binding. Got %v, %v, Expected %v, %v", value, ok, []byte("newval"), true)
+		t.FailNow()
+	}
+	if _, ok := c.Get("key"); ok {
+		t.Errorf("Fetched a removed value. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+	checkUsage(t, c, 0, 1024)
+}
+
+// Check that Remove() has no effect when called on an empty cache
+func testRemoveEmpty(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	for _, key := range []string{"key", "foo", "bar", ""} {
+		if _, ok := c.Remove(key); ok {
+			t.Errorf("Removed absent binding. Got %v, Expected %v", ok, false)
+			t.FailNow()
+		}
+	}
+	checkUsage(t, c, 0, 1024)
+}
+
+// Attempt to Remove() a binding that has already been removed
+func testRemoveRemoved(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	c.Set("key", []byte("value"))
+	if _, ok := c.Remove("key"); !ok {
+		t.Errorf("Failed to remove binding. Got %v, Expected %v", ok, true)
+		t.FailNow()
+	}
+	if _, ok := c.Remove("key"); ok {
+		t.Errorf("Removed removed binding. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+}
+
+// Check that Stats() returns correct values when there are mixed cache hits and misses
+func testStats(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	c.Set("____1", []byte("____1"))
+	checkGet(t, c, "____1", []byte("____1"))
+	if _, ok := c.Get("miss"); ok {
+		t.Errorf("Fetched absent binding. Got %v, Expected %v", ok, false)
+		t.FailNow()
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Hits and misses wrong. Got %v, %v, Expected %v, %v", stats.Hits, stats.Misses, 1, 1)
+		t.FailNow()
+	}
+}
+
+// Ensures that Peek does not update hits and misses, as it does not count as an access
+func testPeekStats(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 64)
+	for i := 0; i < 15; i++ {
+		key := fmt.Sprintf("key%d", i)
+		value := []byte(key)
+		if ok := c.Set(key, value); !ok {
+			t.Errorf("Failed to add binding with key: %s", key)
+			t.FailNow()
+		}
+		checkGet(t, c, key, value)
+
+		before := *c.Stats()
+		peeked, ok := c.Peek(key)
+		c.Peek("missing")
+		after := *c.Stats()
+
+		if !ok || !bytes.Equal(peeked, value) {
+			t.Errorf("Peeked wrong value for %q. Got %v, Expected %v", key, peeked, value)
+			t.FailNow()
+		}
+		if before != after {
+			t.Errorf("Peek changed the stats. Got %+v, Expected %+v", after, before)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that Empty() removes every binding and frees all the storage
+func testEmpty(t *testing.T, newCache func(limit int) cache.Cache) {
+	c := newChecked(t, newCache, 1024)
+	c.Empty()
+	checkUsage(t, c, 0, 1024)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		c.Set(key, []byte(key))
+	}
+	c.Empty()
+	checkUsage(t, c, 0, 1024)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := c.Peek(key); ok {
+			t.Errorf("Peeked at a binding after Empty. Got %v, Expected %v", ok, false)
+			t.FailNow()
+		}
+	}
+
+	// The cache is still usable afterwards
+	c.Set("key", []byte("value"))
+	checkGet(t, c, "key", []byte("value"))
+	checkUsage(t, c, 1, 1016)
+}
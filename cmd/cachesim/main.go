@@ -0,0 +1,123 @@
+// Command cachesim replays an access trace through the eviction policies in
+// the cache package and reports how each one performs at a range of
+// capacities.
+//
+// Usage:
+//
+//	cachesim [-format lis] [-policies fifo,lfu,lru,arc] [-capacities 1024,4096] [-csv] [trace]
+//
+// The trace may be in any format the trace package reads, and is read from
+// standard input if no file is given. Every get in the trace is replayed as a
+// look-aside client would: a Get, and a Set of the value if the Get missed.
+// A malformed line stops the simulation.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"cos316.princeton.edu/assignment3/trace"
+)
+
+func main() {
+	format := flag.String("format", "lis", "trace `format`, one of "+strings.Join(trace.Formats(), ", "))
+	policyList := flag.String("policies", "fifo,lfu,lru,arc", "comma-separated `list` of policies to simulate, from "+strings.Join(policyNames(), ", "))
+	capacityList := flag.String("capacities", "1024,4096,16384,65536", "comma-separated `list` of capacities in bytes")
+	asCSV := flag.Bool("csv", false, "write results as CSV instead of a table")
+	flag.Parse()
+
+	names, err := parsePolicies(*policyList)
+	if err != nil {
+		fail(err)
+	}
+	capacities, err := parseCapacities(*capacityList)
+	if err != nil {
+		fail(err)
+	}
+
+	in := io.Reader(os.Stdin)
+	if flag.NArg() > 1 {
+		fail(fmt.Errorf("expected at most one trace file, got %d", flag.NArg()))
+	} else if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fail(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	reader, err := trace.Open(*format, in)
+	if err != nil {
+		fail(err)
+	}
+	runs, err := newRuns(names, capacities)
+	if err != nil {
+		fail(err)
+	}
+	sim := &simulator{runs: runs}
+	if err := replayAll(reader, sim.replay); err != nil {
+		fail(err)
+	}
+
+	if *asCSV {
+		err = writeCSV(os.Stdout, sim.results())
+	} else {
+		err = writeTable(os.Stdout, sim.results())
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// fail reports err and exits.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "cachesim:", err)
+	os.Exit(1)
+}
+
+// parsePolicies splits a comma-separated list of policy names and checks that
+// each one is known.
+func parsePolicies(list string) ([]string, error) {
+	names := strings.Split(list, ",")
+	for i, name := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+		if _, err := newCache(names[i], 0); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// parseCapacities splits a comma-separated list of capacities, in bytes.
+func parseCapacities(list string) ([]int, error) {
+	fields := strings.Split(list, ",")
+	capacities := make([]int, 0, len(fields))
+	for _, field := range fields {
+		capacity, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || capacity < 0 {
+			return nil, fmt.Errorf("invalid capacity %q", field)
+		}
+		capacities = append(capacities, capacity)
+	}
+	return capacities, nil
+}
+
+// replayAll passes every event of reader to replay, stopping at the first
+// error.
+func replayAll(reader trace.Reader, replay func(event trace.Event)) error {
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		replay(event)
+	}
+}
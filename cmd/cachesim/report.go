@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// reportHeader names the columns of both report formats
+var reportHeader = []string{"policy", "capacity", "requests", "hits", "hit_ratio", "byte_hit_ratio", "evictions"}
+
+// writeCSV writes results to w as CSV, with a header row.
+func writeCSV(w io.Writer, results []result) error {
+	out := csv.NewWriter(w)
+	out.Write(reportHeader)
+	for _, r := range results {
+		out.Write([]string{
+			r.Policy,
+			strconv.Itoa(r.Capacity),
+			strconv.Itoa(r.Requests),
+			strconv.Itoa(r.Hits),
+			strconv.FormatFloat(r.HitRatio, 'f', 6, 64),
+			strconv.FormatFloat(r.ByteHitRatio, 'f', 6, 64),
+			strconv.Itoa(r.Evictions),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeTable writes results to w as an aligned, human-readable table, with a
+// blank line between policies.
+func writeTable(w io.Writer, results []result) error {
+	out := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(out, "policy\tcapacity\trequests\thits\thit ratio\tbyte hit ratio\tevictions\t")
+	for i, r := range results {
+		if i > 0 && results[i-1].Policy != r.Policy {
+			fmt.Fprintln(out, "\t\t\t\t\t\t\t")
+		}
+		fmt.Fprintf(out, "%s\t%d\t%d\t%d\t%.2f%%\t%.2f%%\t%d\t\n",
+			r.Policy, r.Capacity, r.Requests, r.Hits, 100*r.HitRatio, 100*r.ByteHitRatio, r.Evictions)
+	}
+	return out.Flush()
+}
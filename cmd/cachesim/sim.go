@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cos316.princeton.edu/assignment3/cache"
+	"cos316.princeton.edu/assignment3/trace"
+)
+
+// variants are the policies cachesim simulates alongside those the cache
+// package registers, by name. They are kept out of the cache registry, which
+// is shared by every user of the package.
+var variants = map[string]cache.Factory{
+	"gdsfbytes": newGdsfBytes,
+}
+
+// newGdsfBytes returns a GDSF that maximizes the byte hit ratio
+func newGdsfBytes(limit int) cache.Cache {
+	gdsf := cache.NewGdsf(limit)
+	gdsf.SetMode(cache.ByteHitRatio)
+	return gdsf
+}
+
+// policyNames returns the names of every policy cachesim can simulate,
+// sorted.
+func policyNames() []string {
+	names := cache.Names()
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCache returns a new cache with a capacity to store limit bytes, built by
+// the variant or the registered policy of the given name, ignoring case.
+func newCache(name string, limit int) (cache.Cache, error) {
+	if factory, ok := variants[strings.ToLower(name)]; ok {
+		return factory(limit), nil
+	}
+	c, err := cache.New(name, limit)
+	if errors.Is(err, cache.ErrUnknownPolicy) {
+		return nil, fmt.Errorf("%w %q, expected one of %s", cache.ErrUnknownPolicy, name, strings.Join(policyNames(), ", "))
+	}
+	return c, err
+}
+
+// A run is one policy at one capacity, replaying the trace
+type run struct {
+	policy   string
+	capacity int
+	cache    cache.Cache
+}
+
+// A result summarizes a finished run
+type result struct {
+	Policy       string
+	Capacity     int
+	Requests     int
+	Hits         int
+	HitRatio     float64
+	ByteHitRatio float64
+	Evictions    int
+}
+
+// newRuns returns a fresh run for every combination of the given policies and
+// capacities, ordered by policy and then by capacity. It fails if a policy is
+// unknown.
+func newRuns(names []string, capacities []int) ([]*run, error) {
+	runs := make([]*run, 0, len(names)*len(capacities))
+	for _, name := range names {
+		for _, capacity := range capacities {
+			c, err := newCache(name, capacity)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, &run{policy: name, capacity: capacity, cache: c})
+		}
+	}
+	return runs, nil
+}
+
+// simulator replays accesses against many runs at once, so that a trace only
+// has to be read once however many runs there are.
+type simulator struct {
+	runs  []*run
+	zeros []byte // Shared backing array for values, grown to the largest size seen
+}
+
+// replay applies event to every run as a look-aside client would: a get is a
+// Get followed by a Set of a value of the event's size if the Get missed, a
+// set is a Set and a delete is a Remove.
+func (sim *simulator) replay(event trace.Event) {
+	if event.Size > len(sim.zeros) {
+		sim.zeros = make([]byte, event.Size)
+	}
+	value := sim.zeros[:event.Size]
+
+	for _, r := range sim.runs {
+		switch event.Op {
+		case trace.OpGet:
+			if _, ok := r.cache.Get(event.Key); !ok {
+				r.cache.Set(event.Key, value)
+			}
+		case trace.OpSet:
+			r.cache.Set(event.Key, value)
+		case trace.OpDelete:
+			r.cache.Remove(event.Key)
+		}
+	}
+}
+
+// results returns a summary of every run so far, in the order of sim.runs.
+func (sim *simulator) results() []result {
+	results := make([]result, 0, len(sim.runs))
+	for _, r := range sim.runs {
+		stats := r.cache.Stats()
+		results = append(results, result{
+			Policy:       r.policy,
+			Capacity:     r.capacity,
+			Requests:     stats.Hits + stats.Misses,
+			Hits:         stats.Hits,
+			HitRatio:     stats.HitRatio(),
+			ByteHitRatio: stats.ByteHitRatio(),
+			Evictions:    stats.Evictions,
+		})
+	}
+	return results
+}
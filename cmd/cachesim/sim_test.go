@@ -0,0 +1,161 @@
+/******************************************************************************
+ * sim_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for the cachesim command.
+ ******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/cache"
+	"cos316.princeton.edu/assignment3/trace"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// newSimulator returns a simulator with a run for every combination of the
+// given policies and capacities, failing t if one cannot be built.
+func newSimulator(t *testing.T, names []string, capacities []int) *simulator {
+	runs, err := newRuns(names, capacities)
+	if err != nil {
+		t.Fatalf("newRuns failed. Got %v, Expected %v", err, nil)
+	}
+	return &simulator{runs: runs}
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that every event of a trace is replayed and that errors stop it
+func TestReplayAllSim(t *testing.T) {
+	reader := trace.NewJSONReader(strings.NewReader(`{"key": "a", "size": 10}
+{"key": "a", "op": "delete"}
+{"key": "b", "size": 3, "op": "set"}
+`))
+	got := make([]trace.Event, 0)
+	if err := replayAll(reader, func(event trace.Event) { got = append(got, event) }); err != nil {
+		t.Errorf("replayAll failed. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+	if len(got) != 3 || got[2] != (trace.Event{Key: "b", Size: 3, Op: trace.OpSet}) {
+		t.Errorf("replayAll returned wrong events. Got %v", got)
+		t.FailNow()
+	}
+
+	reader = trace.NewJSONReader(strings.NewReader("{\"key\": \"a\"}\nbad\n"))
+	err := replayAll(reader, func(event trace.Event) {})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("replayAll ignored a bad line. Got %v, Expected an error on line 2", err)
+		t.FailNow()
+	}
+}
+
+// Check that runs cannot be built for an unknown policy
+func TestUnknownPolicySim(t *testing.T) {
+	if runs, err := newRuns([]string{"lru", "mru"}, []int{1024}); runs != nil || !errors.Is(err, cache.ErrUnknownPolicy) {
+		t.Errorf("Built runs for an unknown policy. Got %v, %v, Expected %v, %v", runs, err, nil, cache.ErrUnknownPolicy)
+		t.FailNow()
+	}
+}
+
+// Check that the variants cachesim adds can be simulated without being
+// registered in the cache package
+func TestVariantsSim(t *testing.T) {
+	sim := newSimulator(t, []string{"gdsf", "GdsfBytes"}, []int{1024})
+	if _, ok := sim.runs[1].cache.(*cache.GDSF); !ok {
+		t.Errorf("Built the wrong cache for gdsfbytes. Got %T, Expected %T", sim.runs[1].cache, &cache.GDSF{})
+		t.FailNow()
+	}
+	if _, err := cache.New("gdsfbytes", 1024); !errors.Is(err, cache.ErrUnknownPolicy) {
+		t.Errorf("gdsfbytes leaked into the cache registry. Got %v, Expected %v", err, cache.ErrUnknownPolicy)
+		t.FailNow()
+	}
+
+	names := policyNames()
+	if len(names) != len(cache.Names())+len(variants) || !sort.StringsAreSorted(names) {
+		t.Errorf("Wrong policy names. Got %v", names)
+		t.FailNow()
+	}
+	if _, err := newCache("mru", 1024); err == nil || !strings.Contains(err.Error(), "gdsfbytes") {
+		t.Errorf("Unknown policy error does not list the variants. Got %v", err)
+		t.FailNow()
+	}
+}
+
+// Check that sets and deletes in a trace are applied without counting as
+// requests
+func TestOpsSim(t *testing.T) {
+	sim := newSimulator(t, []string{"fifo", "lru", "arc"}, []int{1024})
+	sim.replay(trace.Event{Key: "a", Size: 1, Op: trace.OpSet})
+	sim.replay(trace.Event{Key: "a", Size: 1, Op: trace.OpGet})
+	sim.replay(trace.Event{Key: "a", Op: trace.OpDelete})
+	sim.replay(trace.Event{Key: "a", Size: 1, Op: trace.OpGet})
+
+	for _, r := range sim.results() {
+		if r.Requests != 2 || r.Hits != 1 {
+			t.Errorf("%s wrong. Got %v requests and %v hits, Expected %v and %v", r.Policy, r.Requests, r.Hits, 2, 1)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that every run sees the whole trace and that small caches evict
+func TestReplaySim(t *testing.T) {
+	sim := newSimulator(t, []string{"fifo", "lru", "arc"}, []int{4, 1024})
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"a", "b", "c"} {
+			sim.replay(trace.Event{Key: key, Size: 1})
+		}
+	}
+
+	for _, r := range sim.results() {
+		if r.Requests != 9 {
+			t.Errorf("%s at %d saw wrong number of requests. Got %v, Expected %v", r.Policy, r.Capacity, r.Requests, 9)
+			t.FailNow()
+		}
+		if r.Capacity == 1024 && (r.Hits != 6 || r.Evictions != 0) {
+			t.Errorf("%s at %d wrong. Got %v hits and %v evictions, Expected %v and %v", r.Policy, r.Capacity, r.Hits, r.Evictions, 6, 0)
+			t.FailNow()
+		}
+		if r.Capacity == 4 && r.Evictions == 0 {
+			t.Errorf("%s at %d never evicted. Got %v, Expected more than %v", r.Policy, r.Capacity, r.Evictions, 0)
+			t.FailNow()
+		}
+	}
+}
+
+// Check that both report formats have a row per run
+func TestReportSim(t *testing.T) {
+	results := []result{
+		{Policy: "lru", Capacity: 10, Requests: 4, Hits: 1, HitRatio: 0.25, ByteHitRatio: 0.5, Evictions: 2},
+		{Policy: "arc", Capacity: 10, Requests: 4, Hits: 2, HitRatio: 0.5, ByteHitRatio: 0.5, Evictions: 1},
+	}
+
+	var buf bytes.Buffer
+	writeCSV(&buf, results)
+	expected := "policy,capacity,requests,hits,hit_ratio,byte_hit_ratio,evictions\n" +
+		"lru,10,4,1,0.250000,0.500000,2\n" +
+		"arc,10,4,2,0.500000,0.500000,1\n"
+	if buf.String() != expected {
+		t.Errorf("CSV report wrong. Got %q, Expected %q", buf.String(), expected)
+		t.FailNow()
+	}
+
+	buf.Reset()
+	writeTable(&buf, results)
+	if !strings.Contains(buf.String(), "25.00%") || !strings.Contains(buf.String(), "arc") {
+		t.Errorf("Table report missing results. Got %q", buf.String())
+		t.FailNow()
+	}
+}
@@ -1,5 +1,5 @@
 module cos316.princeton.edu/assignment3
 
-go 1.17
+go 1.18
 
 require github.com/emirpasic/gods v1.18.1 // indirect
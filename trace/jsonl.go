@@ -0,0 +1,59 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonReader reads a trace with one JSON object per line:
+//
+//	{"key": "user:42", "size": 512, "op": "get"}
+//
+// "op" is one of "get", "set" or "delete" and defaults to "get". "size"
+// defaults to 0.
+type jsonReader struct {
+	lines *lineReader
+}
+
+// jsonEvent is a line of a JSON-lines trace
+type jsonEvent struct {
+	Key  *string `json:"key"`
+	Size int     `json:"size"`
+	Op   string  `json:"op"`
+}
+
+// NewJSONReader returns a Reader for a JSON-lines trace.
+func NewJSONReader(r io.Reader) Reader {
+	return &jsonReader{lines: newLineReader("jsonl", r)}
+}
+
+func (jr *jsonReader) Next() (Event, error) {
+	line, err := jr.lines.next()
+	if err != nil {
+		return Event{}, err
+	}
+
+	var decoded jsonEvent
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+		return Event{}, jr.lines.errorf("%v", err)
+	}
+	if decoded.Key == nil {
+		return Event{}, jr.lines.errorf("missing key")
+	}
+	if decoded.Size < 0 {
+		return Event{}, jr.lines.errorf("invalid size %d", decoded.Size)
+	}
+
+	event := Event{Key: *decoded.Key, Size: decoded.Size}
+	switch decoded.Op {
+	case "", "get":
+		event.Op = OpGet
+	case "set":
+		event.Op = OpSet
+	case "delete":
+		event.Op = OpDelete
+	default:
+		return Event{}, jr.lines.errorf("invalid op %q", decoded.Op)
+	}
+	return event, nil
+}
@@ -0,0 +1,58 @@
+package trace
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LisBlockSize is the size in bytes of a block in a .lis trace
+const LisBlockSize = 512
+
+// lisReader reads the format of the traces published with the ARC paper
+// (Megiddo and Modha, FAST '03). Each line is
+//
+//	startingBlock numberOfBlocks ignore requestNumber
+//
+// and requests every block from startingBlock to startingBlock+numberOfBlocks-1
+// in turn. Only the first two fields are used.
+type lisReader struct {
+	lines *lineReader
+	next  int64 // The next block of the current request
+	left  int64 // Blocks of the current request not yet returned
+}
+
+// NewLisReader returns a Reader for a trace in the ARC paper's .lis format.
+// Every block requested is a separate OpGet event of LisBlockSize bytes,
+// keyed by its block number.
+func NewLisReader(r io.Reader) Reader {
+	return &lisReader{lines: newLineReader("lis", r)}
+}
+
+func (lr *lisReader) Next() (Event, error) {
+	for lr.left == 0 {
+		line, err := lr.lines.next()
+		if err != nil {
+			return Event{}, err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return Event{}, lr.lines.errorf("expected at least 2 fields, got %d", len(fields))
+		}
+		start, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || start < 0 {
+			return Event{}, lr.lines.errorf("invalid starting block %q", fields[0])
+		}
+		count, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || count < 0 {
+			return Event{}, lr.lines.errorf("invalid number of blocks %q", fields[1])
+		}
+		lr.next, lr.left = start, count
+	}
+
+	event := Event{Key: strconv.FormatInt(lr.next, 10), Size: LisBlockSize, Op: OpGet}
+	lr.next++
+	lr.left--
+	return event, nil
+}
@@ -0,0 +1,53 @@
+package trace
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// msrReader reads the block I/O traces published by Microsoft Research
+// Cambridge (Narayanan et al., FAST '08). Each line is
+//
+//	Timestamp,Hostname,DiskNumber,Type,Offset,Size,ResponseTime
+//
+// where Type is "Read" or "Write", and Offset and Size are in bytes.
+type msrReader struct {
+	lines *lineReader
+}
+
+// NewMSRReader returns a Reader for an MSR Cambridge CSV trace. Every request
+// is a single event keyed by its host, disk and offset. Reads are OpGet and
+// writes are OpSet.
+func NewMSRReader(r io.Reader) Reader {
+	return &msrReader{lines: newLineReader("msr", r)}
+}
+
+func (mr *msrReader) Next() (Event, error) {
+	line, err := mr.lines.next()
+	if err != nil {
+		return Event{}, err
+	}
+
+	fields := strings.Split(line, ",")
+	if len(fields) != 7 {
+		return Event{}, mr.lines.errorf("expected 7 fields, got %d", len(fields))
+	}
+
+	event := Event{Key: fields[1] + "/" + fields[2] + "/" + fields[4]}
+	switch strings.ToLower(fields[3]) {
+	case "read":
+		event.Op = OpGet
+	case "write":
+		event.Op = OpSet
+	default:
+		return Event{}, mr.lines.errorf("invalid type %q", fields[3])
+	}
+	if offset, err := strconv.ParseInt(fields[4], 10, 64); err != nil || offset < 0 {
+		return Event{}, mr.lines.errorf("invalid offset %q", fields[4])
+	}
+	if event.Size, err = strconv.Atoi(fields[5]); err != nil || event.Size < 0 {
+		return Event{}, mr.lines.errorf("invalid size %q", fields[5])
+	}
+	return event, nil
+}
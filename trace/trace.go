@@ -0,0 +1,137 @@
+// Package trace reads cache access traces in a number of published formats
+// into a common stream of events.
+//
+// Every reader streams its input a line at a time, so traces of any length
+// can be replayed in bounded memory. Malformed lines are reported as a
+// *ParseError carrying the line number.
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxLineSize bounds the length of a single line of a trace
+const maxLineSize = 1 << 20
+
+// An Op is the kind of request an Event makes
+type Op int
+
+const (
+	// OpGet reads a key. A client that misses is expected to Set it.
+	OpGet Op = iota
+
+	// OpSet writes a key.
+	OpSet
+
+	// OpDelete removes a key.
+	OpDelete
+)
+
+// String returns the lower-case name of op, as used in JSON-lines traces.
+func (op Op) String() string {
+	switch op {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// An Event is a single request in a trace
+type Event struct {
+	Key  string // The key requested
+	Size int    // The size in bytes of the value stored under Key
+	Op   Op     // The kind of request
+}
+
+// A Reader produces the events of a trace in order
+type Reader interface {
+	// Next returns the next event of the trace, or io.EOF once the trace is
+	// exhausted. A malformed line is reported as a *ParseError; reading may
+	// continue past it.
+	Next() (Event, error)
+}
+
+// A ParseError reports a malformed line of a trace
+type ParseError struct {
+	Format string // The name of the format being read
+	Line   int    // The 1-based line number of the malformed line
+	Err    error  // What was wrong with the line
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("trace: %s line %d: %v", e.Format, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// formats maps the names accepted by Open to reader constructors
+var formats = map[string]func(r io.Reader) Reader{
+	"lis":     NewLisReader,
+	"msr":     NewMSRReader,
+	"twitter": NewTwitterReader,
+	"jsonl":   NewJSONReader,
+}
+
+// Formats returns the names of the formats accepted by Open, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open returns a Reader for the named format ("lis", "msr", "twitter" or
+// "jsonl") reading from r.
+func Open(format string, r io.Reader) (Reader, error) {
+	newReader, ok := formats[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("trace: unknown format %q, expected one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return newReader(r), nil
+}
+
+// lineReader splits a trace into lines, skipping blank lines and counting
+// every line so that errors can be attributed to it.
+type lineReader struct {
+	format  string
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newLineReader(format string, r io.Reader) *lineReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &lineReader{format: format, scanner: scanner}
+}
+
+// next returns the next non-blank line with surrounding space removed, or
+// io.EOF at the end of the input.
+func (lr *lineReader) next() (string, error) {
+	for lr.scanner.Scan() {
+		lr.line++
+		if text := strings.TrimSpace(lr.scanner.Text()); text != "" {
+			return text, nil
+		}
+	}
+	if err := lr.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// errorf returns a *ParseError for the line most recently returned by next.
+func (lr *lineReader) errorf(format string, args ...interface{}) error {
+	return &ParseError{Format: lr.format, Line: lr.line, Err: fmt.Errorf(format, args...)}
+}
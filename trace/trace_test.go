@@ -0,0 +1,181 @@
+/******************************************************************************
+ * trace_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for the trace readers, run against the fixtures in
+ *    testdata/.
+ ******************************************************************************/
+
+package trace
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// readAll returns every event of the named fixture in the given format.
+func readAll(t *testing.T, format string, fixture string) []Event {
+	f, err := os.Open(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	reader, err := Open(format, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make([]Event, 0)
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Errorf("%s reader failed on %s. Got %v, Expected %v", format, fixture, err, nil)
+			t.FailNow()
+		}
+		events = append(events, event)
+	}
+}
+
+// checkEvents fails t if got does not match expected exactly, in order.
+func checkEvents(t *testing.T, format string, got []Event, expected []Event) {
+	if len(got) != len(expected) {
+		t.Errorf("%s reader returned wrong events. Got %v, Expected %v", format, got, expected)
+		t.FailNow()
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("%s reader returned wrong event %d. Got %v, Expected %v", format, i, got[i], expected[i])
+			t.FailNow()
+		}
+	}
+}
+
+// checkParseError fails t unless reading input in the given format fails with
+// a *ParseError on the expected line.
+func checkParseError(t *testing.T, format string, input string, line int) {
+	reader, _ := Open(format, strings.NewReader(input))
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			t.Errorf("%s reader accepted %q. Got %v, Expected an error on line %d", format, input, err, line)
+			t.FailNow()
+		}
+		if err == nil {
+			continue
+		}
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != line || parseErr.Format != format {
+			t.Errorf("%s reader reported wrong error for %q. Got %v, Expected an error on line %d", format, input, err, line)
+			t.FailNow()
+		}
+		return
+	}
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that every block of a .lis request is a separate event
+func TestLis(t *testing.T) {
+	checkEvents(t, "lis", readAll(t, "lis", "sample.lis"), []Event{
+		{"100", LisBlockSize, OpGet},
+		{"101", LisBlockSize, OpGet},
+		{"7", LisBlockSize, OpGet},
+		{"100", LisBlockSize, OpGet},
+		{"101", LisBlockSize, OpGet},
+		{"102", LisBlockSize, OpGet},
+	})
+
+	checkParseError(t, "lis", "1 1 0 0\n2\n", 2)
+	checkParseError(t, "lis", "x 1 0 0\n", 1)
+	checkParseError(t, "lis", "\n\n1 -1 0 0\n", 3)
+}
+
+// Check that MSR requests are keyed by host, disk and offset
+func TestMSR(t *testing.T) {
+	checkEvents(t, "msr", readAll(t, "msr", "sample.msr.csv"), []Event{
+		{"hm/0/3218866176", 4096, OpGet},
+		{"hm/0/3216051712", 8192, OpSet},
+		{"web/1/3218866176", 512, OpGet},
+	})
+
+	checkParseError(t, "msr", "1,hm,0,Read,0,1,1\n1,hm,0,Read,0,1\n", 2)
+	checkParseError(t, "msr", "1,hm,0,Trim,0,1,1\n", 1)
+	checkParseError(t, "msr", "1,hm,0,Read,0,big,1\n", 1)
+}
+
+// Check that Twitter commands map to ops and sizes are the value size alone
+func TestTwitter(t *testing.T) {
+	checkEvents(t, "twitter", readAll(t, "twitter", "sample.twitter.csv"), []Event{
+		{"q:q:1:8WTfjZU", 368, OpGet},
+		{"q:q:1:8WTfjZU", 368, OpSet},
+		{"eeDvf:kAbz", 0, OpDelete},
+		{"a:b", 20, OpGet},
+	})
+
+	event, err := NewTwitterReader(strings.NewReader("7,user:42,7,1000,3,set,60\n")).Next()
+	if err != nil || event != (Event{"user:42", 1000, OpSet}) {
+		t.Errorf("twitter reader parsed the wrong size. Got %v, %v, Expected %v, %v", event, err, Event{"user:42", 1000, OpSet}, nil)
+		t.FailNow()
+	}
+
+	checkParseError(t, "twitter", "0,k,1,1,1,get,0\n0,k,1,1,1,flush,0\n", 2)
+	checkParseError(t, "twitter", "0,k,-1,1,1,get,0\n", 1)
+}
+
+// Check that JSON-lines events default to gets and reject bad lines
+func TestJSON(t *testing.T) {
+	checkEvents(t, "jsonl", readAll(t, "jsonl", "sample.jsonl"), []Event{
+		{"user:1", 512, OpGet},
+		{"user:1", 512, OpSet},
+		{"user:2", 64, OpGet},
+		{"user:1", 0, OpDelete},
+	})
+
+	checkParseError(t, "jsonl", "{\"key\": \"a\"}\n{\"size\": 1}\n", 2)
+	checkParseError(t, "jsonl", "{\"key\": \"a\", \"op\": \"put\"}\n", 1)
+	checkParseError(t, "jsonl", "not json\n", 1)
+}
+
+// Check that a reader can continue past a malformed line
+func TestContinueAfterError(t *testing.T) {
+	reader := NewLisReader(strings.NewReader("1 1\nbad\n2 1\n"))
+	expected := []string{"1", "", "2"}
+	for i, key := range expected {
+		event, err := reader.Next()
+		if (key == "") != (err != nil) || (err == nil && event.Key != key) {
+			t.Errorf("Wrong result %d. Got %v, %v, Expected key %q", i, event, err, key)
+			t.FailNow()
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Reader did not end. Got %v, Expected %v", err, io.EOF)
+		t.FailNow()
+	}
+}
+
+// Check that Open rejects unknown formats
+func TestOpen(t *testing.T) {
+	if _, err := Open("csv", strings.NewReader("")); err == nil {
+		t.Errorf("Open accepted an unknown format. Got %v, Expected an error", err)
+		t.FailNow()
+	}
+	if _, err := Open("LIS", strings.NewReader("")); err != nil {
+		t.Errorf("Open rejected a format name in upper case. Got %v, Expected %v", err, nil)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,59 @@
+package trace
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// twitterReader reads the in-memory cache traces published by Twitter (Yang
+// et al., OSDI '20). Each line is
+//
+//	timestamp,key,key size,value size,client id,operation,TTL
+//
+// where operation is one of the memcached commands.
+type twitterReader struct {
+	lines *lineReader
+}
+
+// NewTwitterReader returns a Reader for a Twitter cache-trace CSV file. The
+// size of each event is its value size; the key size is only validated.
+// get and gets are OpGet; set, add, replace, cas, append, prepend, incr and
+// decr are OpSet; delete is OpDelete. Any other command is an error.
+func NewTwitterReader(r io.Reader) Reader {
+	return &twitterReader{lines: newLineReader("twitter", r)}
+}
+
+func (tr *twitterReader) Next() (Event, error) {
+	line, err := tr.lines.next()
+	if err != nil {
+		return Event{}, err
+	}
+
+	fields := strings.Split(line, ",")
+	if len(fields) != 7 {
+		return Event{}, tr.lines.errorf("expected 7 fields, got %d", len(fields))
+	}
+
+	keySize, err := strconv.Atoi(fields[2])
+	if err != nil || keySize < 0 {
+		return Event{}, tr.lines.errorf("invalid key size %q", fields[2])
+	}
+	valueSize, err := strconv.Atoi(fields[3])
+	if err != nil || valueSize < 0 {
+		return Event{}, tr.lines.errorf("invalid value size %q", fields[3])
+	}
+
+	event := Event{Key: fields[1], Size: valueSize}
+	switch fields[5] {
+	case "get", "gets":
+		event.Op = OpGet
+	case "set", "add", "replace", "cas", "append", "prepend", "incr", "decr":
+		event.Op = OpSet
+	case "delete":
+		event.Op = OpDelete
+	default:
+		return Event{}, tr.lines.errorf("invalid operation %q", fields[5])
+	}
+	return event, nil
+}
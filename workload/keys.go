@@ -0,0 +1,232 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// A KeySource produces a stream of key indices
+type KeySource interface {
+	// Next returns the index of the next key requested.
+	Next() uint64
+}
+
+/******************************************************************************/
+/*                                  Uniform                                   */
+/******************************************************************************/
+
+// uniform draws every key of [0, n) with equal probability
+type uniform struct {
+	rng *rand.Rand
+	n   uint64
+}
+
+// NewUniform returns a KeySource drawing keys from [0, n) uniformly at random.
+func NewUniform(seed int64, n uint64) KeySource {
+	if n == 0 {
+		panic("workload: uniform over no keys")
+	}
+	return &uniform{rng: rand.New(rand.NewSource(seed)), n: n}
+}
+
+func (u *uniform) Next() uint64 {
+	return uint64(u.rng.Int63n(int64(u.n)))
+}
+
+/******************************************************************************/
+/*                                   Zipf                                     */
+/******************************************************************************/
+
+// zipf draws key i of [0, n) with probability proportional to 1/(i+1)^theta,
+// so that key 0 is the most popular. For theta < 1 it uses the method of Gray
+// et al., "Quickly Generating Billion-Record Synthetic Databases" (SIGMOD '94),
+// as YCSB does; for theta > 1 it uses math/rand's Zipf.
+type zipf struct {
+	rng   *rand.Rand
+	n     uint64
+	theta float64
+	alpha float64 // 1/(1-theta)
+	zetan float64 // The sum of 1/i^theta for i in [1, n]
+	eta   float64
+	large *rand.Zipf // Used instead of the above when theta > 1
+}
+
+// NewZipf returns a KeySource drawing keys from [0, n) with a Zipfian
+// distribution of skew theta, where key 0 is the most popular. YCSB's default
+// skew is 0.99. Since computing the distribution's constants is O(n) for theta
+// below 1, construction can be slow for very large n. NewZipf panics if theta
+// is not positive or is exactly 1.
+func NewZipf(seed int64, n uint64, theta float64) KeySource {
+	if n == 0 || theta <= 0 || theta == 1 {
+		panic(fmt.Sprintf("workload: invalid Zipf parameters n=%d theta=%v", n, theta))
+	}
+
+	z := &zipf{rng: rand.New(rand.NewSource(seed)), n: n, theta: theta}
+	if theta > 1 {
+		z.large = rand.NewZipf(z.rng, theta, 1, n-1)
+		return z
+	}
+
+	z.alpha = 1 / (1 - theta)
+	z.zetan = zeta(n, theta)
+	zeta2 := zeta(2, theta)
+	z.eta = (1 - math.Pow(2/float64(n), 1-theta)) / (1 - zeta2/z.zetan)
+	return z
+}
+
+// zeta returns the sum of 1/i^theta for i in [1, n].
+func zeta(n uint64, theta float64) float64 {
+	sum := 0.0
+	for i := uint64(1); i <= n; i++ {
+		sum += 1 / math.Pow(float64(i), theta)
+	}
+	return sum
+}
+
+func (z *zipf) Next() uint64 {
+	if z.large != nil {
+		return z.large.Uint64()
+	}
+
+	u := z.rng.Float64()
+	uz := u * z.zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, z.theta) {
+		return 1
+	}
+	key := uint64(float64(z.n) * math.Pow(z.eta*u-z.eta+1, z.alpha))
+	if key >= z.n {
+		key = z.n - 1
+	}
+	return key
+}
+
+// scrambled spreads the popular keys of a Zipfian source across the key space
+type scrambled struct {
+	source KeySource
+	n      uint64
+}
+
+// NewScrambledZipf returns a KeySource with the same popularity distribution
+// as NewZipf, but with the popular keys scattered over [0, n) by a hash rather
+// than clustered at its start.
+func NewScrambledZipf(seed int64, n uint64, theta float64) KeySource {
+	return &scrambled{source: NewZipf(seed, n, theta), n: n}
+}
+
+func (s *scrambled) Next() uint64 {
+	return mix64(s.source.Next()) % s.n
+}
+
+/******************************************************************************/
+/*                                  Hotspot                                   */
+/******************************************************************************/
+
+// hotspot sends a fixed fraction of requests to a small set of hot keys
+type hotspot struct {
+	rng         *rand.Rand
+	hot         uint64
+	cold        uint64
+	hotFraction float64
+}
+
+// NewHotspot returns a KeySource over [0, n) where the first hotSetFraction
+// of the keys receive hotOpFraction of the requests. Within the hot and cold
+// sets keys are drawn uniformly.
+func NewHotspot(seed int64, n uint64, hotSetFraction float64, hotOpFraction float64) KeySource {
+	hot := uint64(float64(n) * hotSetFraction)
+	if hot == 0 || hot >= n || hotOpFraction < 0 || hotOpFraction > 1 {
+		panic(fmt.Sprintf("workload: invalid hotspot parameters n=%d hotSetFraction=%v hotOpFraction=%v", n, hotSetFraction, hotOpFraction))
+	}
+	return &hotspot{rng: rand.New(rand.NewSource(seed)), hot: hot, cold: n - hot, hotFraction: hotOpFraction}
+}
+
+func (h *hotspot) Next() uint64 {
+	if h.rng.Float64() < h.hotFraction {
+		return uint64(h.rng.Int63n(int64(h.hot)))
+	}
+	return h.hot + uint64(h.rng.Int63n(int64(h.cold)))
+}
+
+/******************************************************************************/
+/*                               Scans and loops                              */
+/******************************************************************************/
+
+// sequential requests every key once, in order
+type sequential struct {
+	next uint64
+}
+
+// NewSequential returns a KeySource that scans 0, 1, 2, ... without ever
+// repeating a key.
+func NewSequential() KeySource {
+	return &sequential{}
+}
+
+func (s *sequential) Next() uint64 {
+	key := s.next
+	s.next++
+	return key
+}
+
+// loop requests the keys of [0, n) in order, over and over
+type loop struct {
+	next uint64
+	n    uint64
+}
+
+// NewLoop returns a KeySource that cycles through 0, 1, ..., n-1 repeatedly.
+// A loop slightly larger than the cache is the worst case for LRU.
+func NewLoop(n uint64) KeySource {
+	if n == 0 {
+		panic("workload: loop over no keys")
+	}
+	return &loop{n: n}
+}
+
+func (l *loop) Next() uint64 {
+	key := l.next
+	l.next = (l.next + 1) % l.n
+	return key
+}
+
+// scanMix interleaves a hot set with a one-off scan
+type scanMix struct {
+	rng          *rand.Rand
+	hot          KeySource
+	scan         sequential
+	scanFraction float64
+}
+
+// NewScanMix returns a KeySource that draws from hot, except that a fraction
+// scanFraction of requests instead continue a sequential scan of keys that are
+// never requested again. Hot keys are mapped to even indices and scan keys to
+// odd ones, so the two never collide. This is the pattern scan-resistant
+// policies such as ARC are designed for.
+func NewScanMix(seed int64, hot KeySource, scanFraction float64) KeySource {
+	if scanFraction < 0 || scanFraction > 1 {
+		panic(fmt.Sprintf("workload: invalid scan fraction %v", scanFraction))
+	}
+	return &scanMix{rng: rand.New(rand.NewSource(seed)), hot: hot, scanFraction: scanFraction}
+}
+
+func (m *scanMix) Next() uint64 {
+	if m.rng.Float64() < m.scanFraction {
+		return 2*m.scan.Next() + 1
+	}
+	return 2 * m.hot.Next()
+}
+
+// mix64 is a single step of SplitMix64, a cheap bijective hash
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,74 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Sizes assigns a value size to every key. The size of a key never changes,
+// as it would not in a real cache.
+type Sizes interface {
+	// Size returns the size in bytes of the value stored under key.
+	Size(key uint64) int
+}
+
+// unitFloat hashes key with seed to a float in [0, 1).
+func unitFloat(seed int64, key uint64) float64 {
+	return float64(mix64(key^uint64(seed))>>11) / (1 << 53)
+}
+
+// fixedSize gives every key the same size
+type fixedSize int
+
+// FixedSize returns a Sizes that gives every value the same size.
+func FixedSize(size int) Sizes {
+	return fixedSize(size)
+}
+
+func (f fixedSize) Size(key uint64) int {
+	return int(f)
+}
+
+// uniformSize draws sizes uniformly from [min, max]
+type uniformSize struct {
+	seed     int64
+	min, max int
+}
+
+// NewUniformSize returns a Sizes that draws each key's size uniformly from
+// [min, max].
+func NewUniformSize(seed int64, min int, max int) Sizes {
+	if min < 0 || max < min {
+		panic(fmt.Sprintf("workload: invalid size range [%d, %d]", min, max))
+	}
+	return &uniformSize{seed: seed, min: min, max: max}
+}
+
+func (u *uniformSize) Size(key uint64) int {
+	return u.min + int(unitFloat(u.seed, key)*float64(u.max-u.min+1))
+}
+
+// paretoSize draws heavy-tailed sizes from a bounded Pareto distribution
+type paretoSize struct {
+	seed     int64
+	min, max float64
+	alpha    float64
+}
+
+// NewParetoSize returns a Sizes that draws each key's size from a Pareto
+// distribution with shape alpha and minimum min, truncated at max. Most values
+// are close to min, with a long tail of large ones, as in most web caches.
+func NewParetoSize(seed int64, min int, max int, alpha float64) Sizes {
+	if min <= 0 || max < min || alpha <= 0 {
+		panic(fmt.Sprintf("workload: invalid Pareto parameters min=%d max=%d alpha=%v", min, max, alpha))
+	}
+	return &paretoSize{seed: seed, min: float64(min), max: float64(max), alpha: alpha}
+}
+
+func (p *paretoSize) Size(key uint64) int {
+	// Inverse CDF of the Pareto distribution truncated to [min, max]
+	u := unitFloat(p.seed, key)
+	ratio := math.Pow(p.min/p.max, p.alpha)
+	size := p.min / math.Pow(1-u*(1-ratio), 1/p.alpha)
+	return int(math.Min(size, p.max))
+}
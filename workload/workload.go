@@ -0,0 +1,86 @@
+// Package workload generates synthetic cache workloads.
+//
+// A workload combines a KeySource, which decides which keys are requested and
+// how often, with a Sizes, which gives each key a value size, and a read/write
+// mix. Every generator is seeded explicitly, so a workload can be replayed
+// exactly. Generators emit trace.Event values, so that synthetic and recorded
+// traces can be replayed the same way.
+package workload
+
+import (
+	"io"
+	"math/rand"
+	"strconv"
+
+	"cos316.princeton.edu/assignment3/trace"
+)
+
+// A Config describes a workload
+type Config struct {
+	Seed         int64     // Seeds the read/write mix
+	Keys         KeySource // The keys requested
+	Sizes        Sizes     // The size of each key's value; FixedSize(1) if nil
+	ReadFraction float64   // The fraction of requests that are reads; the rest are writes
+}
+
+// A Generator produces the events of a workload
+type Generator struct {
+	rng          *rand.Rand
+	keys         KeySource
+	sizes        Sizes
+	readFraction float64
+}
+
+// New returns a Generator for the workload described by config.
+func New(config Config) *Generator {
+	sizes := config.Sizes
+	if sizes == nil {
+		sizes = FixedSize(1)
+	}
+	return &Generator{
+		rng:          rand.New(rand.NewSource(config.Seed)),
+		keys:         config.Keys,
+		sizes:        sizes,
+		readFraction: config.ReadFraction,
+	}
+}
+
+// Next returns the next event of the workload. Reads are trace.OpGet and
+// writes are trace.OpSet.
+func (g *Generator) Next() trace.Event {
+	key := g.keys.Next()
+	event := trace.Event{Key: strconv.FormatUint(key, 10), Size: g.sizes.Size(key), Op: trace.OpSet}
+	if g.rng.Float64() < g.readFraction {
+		event.Op = trace.OpGet
+	}
+	return event
+}
+
+// Events returns the next n events of the workload.
+func (g *Generator) Events(n int) []trace.Event {
+	events := make([]trace.Event, n)
+	for i := range events {
+		events[i] = g.Next()
+	}
+	return events
+}
+
+// limited is a trace.Reader over a bounded number of a Generator's events
+type limited struct {
+	g    *Generator
+	left int
+}
+
+// Take returns a trace.Reader that returns the next n events of g and then
+// io.EOF.
+func Take(g *Generator, n int) trace.Reader {
+	return &limited{g: g, left: n}
+}
+
+func (l *limited) Next() (trace.Event, error) {
+	if l.left <= 0 {
+		return trace.Event{}, io.EOF
+	}
+	l.left--
+	return l.g.Next(), nil
+}
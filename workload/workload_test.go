@@ -0,0 +1,212 @@
+/******************************************************************************
+ * workload_test.go
+ * Author:
+ * Usage:    `go test`  or  `go test -v`
+ * Description:
+ *    A unit testing suite for the workload generators.
+ ******************************************************************************/
+
+package workload
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"cos316.princeton.edu/assignment3/trace"
+)
+
+/******************************************************************************/
+/*                                Constants                                   */
+/******************************************************************************/
+
+const samples = 100000
+
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// counts draws samples keys from source and counts how often each appears.
+func counts(source KeySource) map[uint64]int {
+	seen := make(map[uint64]int)
+	for i := 0; i < samples; i++ {
+		seen[source.Next()]++
+	}
+	return seen
+}
+
+// checkFraction fails t if got/samples is not within 0.01 of expected.
+func checkFraction(t *testing.T, name string, got int, expected float64) {
+	if math.Abs(float64(got)/samples-expected) > 0.01 {
+		t.Errorf("%s fraction wrong. Got %v, Expected %v", name, float64(got)/samples, expected)
+		t.FailNow()
+	}
+}
+
+/******************************************************************************/
+/*                                  Tests                                     */
+/******************************************************************************/
+
+// Check that every source replays exactly given the same seed
+func TestSeeded(t *testing.T) {
+	sources := map[string]func() KeySource{
+		"uniform":   func() KeySource { return NewUniform(7, 1000) },
+		"zipf":      func() KeySource { return NewZipf(7, 1000, 0.99) },
+		"zipf>1":    func() KeySource { return NewZipf(7, 1000, 1.2) },
+		"scrambled": func() KeySource { return NewScrambledZipf(7, 1000, 0.99) },
+		"hotspot":   func() KeySource { return NewHotspot(7, 1000, 0.1, 0.9) },
+		"scanmix":   func() KeySource { return NewScanMix(7, NewUniform(8, 10), 0.5) },
+	}
+	for name, newSource := range sources {
+		a, b := newSource(), newSource()
+		for i := 0; i < 1000; i++ {
+			if x, y := a.Next(), b.Next(); x != y {
+				t.Errorf("%s diverged at %d. Got %v, Expected %v", name, i, y, x)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+// Check that Zipfian keys stay in range and get less popular with rank
+func TestZipf(t *testing.T) {
+	for _, theta := range []float64{0.5, 0.99, 1.5} {
+		seen := counts(NewZipf(1, 1000, theta))
+		for key := range seen {
+			if key >= 1000 {
+				t.Errorf("Zipf(%v) key out of range. Got %v, Expected less than %v", theta, key, 1000)
+				t.FailNow()
+			}
+		}
+		if !(seen[0] > seen[1] && seen[1] > seen[10] && seen[10] > seen[500]) {
+			t.Errorf("Zipf(%v) not skewed. Got %v, %v, %v, %v for ranks 0, 1, 10, 500", theta, seen[0], seen[1], seen[10], seen[500])
+			t.FailNow()
+		}
+	}
+
+	// With theta = 0.99 over 1000 keys the top key gets 1/zeta(1000) of requests
+	seen := counts(NewZipf(1, 1000, 0.99))
+	checkFraction(t, "Zipf top key", seen[0], 1/zeta(1000, 0.99))
+}
+
+// Check that scrambling keeps the skew but moves the hottest key
+func TestScrambledZipf(t *testing.T) {
+	seen := counts(NewScrambledZipf(1, 1000, 0.99))
+	hottest, most := uint64(0), 0
+	for key, count := range seen {
+		if key >= 1000 {
+			t.Errorf("Scrambled key out of range. Got %v, Expected less than %v", key, 1000)
+			t.FailNow()
+		}
+		if count > most {
+			hottest, most = key, count
+		}
+	}
+	if hottest == 0 {
+		t.Errorf("Scrambling left the hottest key in place. Got %v", hottest)
+		t.FailNow()
+	}
+	checkFraction(t, "Scrambled top key", most, 1/zeta(1000, 0.99))
+}
+
+// Check that the hot set receives its share of requests
+func TestHotspot(t *testing.T) {
+	hot := 0
+	for key, count := range counts(NewHotspot(1, 1000, 0.2, 0.8)) {
+		if key < 200 {
+			hot += count
+		}
+	}
+	checkFraction(t, "Hot", hot, 0.8)
+}
+
+// Check scans, loops and the scan mix
+func TestScans(t *testing.T) {
+	sequential := NewSequential()
+	loop := NewLoop(3)
+	for i := uint64(0); i < 7; i++ {
+		if key := sequential.Next(); key != i {
+			t.Errorf("Sequential wrong. Got %v, Expected %v", key, i)
+			t.FailNow()
+		}
+		if key := loop.Next(); key != i%3 {
+			t.Errorf("Loop wrong. Got %v, Expected %v", key, i%3)
+			t.FailNow()
+		}
+	}
+
+	scans := 0
+	for key, count := range counts(NewScanMix(1, NewUniform(2, 10), 0.3)) {
+		if key%2 == 1 {
+			scans += count
+			if count != 1 {
+				t.Errorf("Scan key %v repeated. Got %v, Expected %v", key, count, 1)
+				t.FailNow()
+			}
+		} else if key/2 >= 10 {
+			t.Errorf("Hot key out of range. Got %v, Expected less than %v", key/2, 10)
+			t.FailNow()
+		}
+	}
+	checkFraction(t, "Scan", scans, 0.3)
+}
+
+// Check that sizes are stable per key and within their bounds
+func TestSizes(t *testing.T) {
+	sizes := map[string]Sizes{
+		"fixed":   FixedSize(100),
+		"uniform": NewUniformSize(1, 10, 20),
+		"pareto":  NewParetoSize(1, 10, 1000, 1.2),
+	}
+	bounds := map[string][2]int{"fixed": {100, 100}, "uniform": {10, 20}, "pareto": {10, 1000}}
+	for name, s := range sizes {
+		small := 0
+		for key := uint64(0); key < samples; key++ {
+			size := s.Size(key)
+			if size != s.Size(key) {
+				t.Errorf("%s size of key %v changed", name, key)
+				t.FailNow()
+			}
+			if size < bounds[name][0] || size > bounds[name][1] {
+				t.Errorf("%s size out of range. Got %v, Expected within %v", name, size, bounds[name])
+				t.FailNow()
+			}
+			if size < 20 {
+				small++
+			}
+		}
+		// Most Pareto sizes are close to the minimum
+		if name == "pareto" && small < samples/2 {
+			t.Errorf("Pareto sizes not heavy-tailed. Got %v below 20, Expected at least %v", small, samples/2)
+			t.FailNow()
+		}
+	}
+}
+
+// Check the read/write mix and that Take ends the workload
+func TestGenerator(t *testing.T) {
+	g := New(Config{Seed: 1, Keys: NewUniform(1, 100), ReadFraction: 0.9})
+	reads := 0
+	for _, event := range g.Events(samples) {
+		if event.Op == trace.OpGet {
+			reads++
+		}
+		if event.Size != 1 {
+			t.Errorf("Default size wrong. Got %v, Expected %v", event.Size, 1)
+			t.FailNow()
+		}
+	}
+	checkFraction(t, "Read", reads, 0.9)
+
+	reader := Take(New(Config{Keys: NewLoop(5), Sizes: FixedSize(8), ReadFraction: 1}), 2)
+	first, _ := reader.Next()
+	if first != (trace.Event{Key: "0", Size: 8, Op: trace.OpGet}) {
+		t.Errorf("Take returned wrong event. Got %v, Expected %v", first, trace.Event{Key: "0", Size: 8, Op: trace.OpGet})
+		t.FailNow()
+	}
+	reader.Next()
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Take did not end. Got %v, Expected %v", err, io.EOF)
+		t.FailNow()
+	}
+}